@@ -0,0 +1,92 @@
+package app
+
+import (
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/distribution"
+
+	"github.com/coinexchain/cet-sdk/modules/market"
+
+	"github.com/coinexchain/dex/app/overview"
+)
+
+func (app *CetChainApp) newOverviewQuerier() sdk.Querier {
+	return func(ctx sdk.Context, path []string, req abci.RequestQuery) ([]byte, sdk.Error) {
+		switch path[0] {
+		case overview.QueryAccount:
+			return app.queryAccountOverview(ctx, req)
+		default:
+			return nil, sdk.ErrUnknownRequest("query symbol : " + path[0])
+		}
+	}
+}
+
+func (app *CetChainApp) queryAccountOverview(ctx sdk.Context, req abci.RequestQuery) ([]byte, sdk.Error) {
+	var param overview.QueryAccountParam
+	if err := app.cdc.UnmarshalJSON(req.Data, &param); err != nil {
+		return nil, sdk.ErrUnknownRequest(sdk.AppendMsgToErr("failed to parse param", err.Error()))
+	}
+	addr := param.Address
+	if len(addr) == 0 {
+		return nil, sdk.ErrInvalidAddress("missing address")
+	}
+
+	res := overview.AccountOverview{
+		Height:               ctx.BlockHeight(),
+		Address:              addr,
+		Delegations:          app.stakingKeeper.GetAllDelegatorDelegations(ctx, addr),
+		UnbondingDelegations: app.stakingKeeper.GetAllUnbondingDelegations(ctx, addr),
+		Aliases:              app.aliasKeeper.GetAliasListOfAccount(ctx, addr),
+	}
+	if acc := app.accountKeeper.GetAccount(ctx, addr); acc != nil {
+		res.AccountNumber = acc.GetAccountNumber()
+		res.Sequence = acc.GetSequence()
+		res.Coins = acc.GetCoins()
+	}
+	if accx, found := app.accountXKeeper.GetAccountX(ctx, addr); found {
+		res.LockedCoins = accx.LockedCoins
+		res.FrozenCoins = accx.FrozenCoins
+		res.MemoRequired = accx.MemoRequired
+	}
+
+	var err sdk.Error
+	if res.Rewards, err = app.queryRewards(ctx, addr); err != nil {
+		return nil, err
+	}
+	res.OpenOrders = app.queryOpenOrders(ctx, addr)
+
+	bz, e := codec.MarshalJSONIndent(app.cdc, res)
+	if e != nil {
+		return nil, sdk.ErrInternal(sdk.AppendMsgToErr("could not marshal result to JSON", e.Error()))
+	}
+	return bz, nil
+}
+
+// queryRewards calculates the pending rewards with distribution's querier, which
+// works on a cache-wrapped context
+func (app *CetChainApp) queryRewards(ctx sdk.Context, addr sdk.AccAddress) (
+	rewards distribution.QueryDelegatorTotalRewardsResponse, err sdk.Error) {
+
+	querier := app.QueryRouter().Route(distribution.QuerierRoute)
+	bz, err := querier(ctx, []string{distribution.QueryDelegatorTotalRewards},
+		abci.RequestQuery{Data: app.cdc.MustMarshalJSON(distribution.NewQueryDelegatorParams(addr))})
+	if err != nil {
+		return
+	}
+	app.cdc.MustUnmarshalJSON(bz, &rewards)
+	return
+}
+
+// queryOpenOrders selects the orders of addr from the order book, as the market keeper has
+// no exported lookup of the orders of a user
+func (app *CetChainApp) queryOpenOrders(ctx sdk.Context, addr sdk.AccAddress) []market.Order {
+	orders := make([]market.Order, 0)
+	for _, order := range app.marketKeeper.GetAllOrders(ctx) {
+		if order.Sender.Equals(addr) {
+			orders = append(orders, *order)
+		}
+	}
+	return orders
+}
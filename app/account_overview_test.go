@@ -0,0 +1,144 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	"github.com/cosmos/cosmos-sdk/x/staking"
+
+	"github.com/coinexchain/cet-sdk/modules/bankx"
+	"github.com/coinexchain/cet-sdk/modules/market"
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+
+	"github.com/coinexchain/dex/app/overview"
+)
+
+func queryAccountOverview(t *testing.T, app *CetChainApp, addr sdk.AccAddress) (res overview.AccountOverview) {
+	ret := app.Query(abci.RequestQuery{
+		Path: "custom/" + overview.QuerierRoute + "/" + overview.QueryAccount,
+		Data: app.cdc.MustMarshalJSON(overview.NewQueryAccountParam(addr)),
+	})
+	require.True(t, ret.IsOK(), ret.Log)
+	app.cdc.MustUnmarshalJSON(ret.Value, &res)
+	return
+}
+
+func TestAccountOverview(t *testing.T) {
+	_, _, toAddr := testutil.KeyPubAddr()
+	key, _, fromAddr := testutil.KeyPubAddr()
+	acc0 := auth.BaseAccount{Address: fromAddr, Coins: dex.NewCetCoins(30e8)}
+
+	// app
+	app := initAppWithBaseAccounts(acc0)
+
+	// commit genesis state
+	now := time.Now()
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: 1, Time: now}})
+	app.EndBlock(abci.RequestEndBlock{Height: 1})
+	app.Commit()
+
+	// send some locked coins
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: 2, Time: now, ChainID: testChainID}})
+	msg := bankx.NewMsgSend(fromAddr, toAddr, dex.NewCetCoins(3e8), now.Unix()+10000)
+	tx := newStdTxBuilder().
+		Msgs(msg).GasAndFee(1000000, 100).AccNumSeqKey(0, 0, key).Build()
+	result := app.Deliver(tx)
+	require.Equal(t, sdk.CodeOK, result.Code)
+	app.EndBlock(abci.RequestEndBlock{Height: 2})
+	app.Commit()
+
+	from := queryAccountOverview(t, app, fromAddr)
+	require.Equal(t, int64(2), from.Height)
+	require.Equal(t, fromAddr, from.Address)
+	require.Equal(t, uint64(1), from.Sequence)
+	require.Equal(t, "2699999900cet", from.Coins.String())
+	require.Equal(t, 0, len(from.Delegations))
+	require.Equal(t, 0, len(from.UnbondingDelegations))
+	require.Equal(t, 0, len(from.OpenOrders))
+	require.True(t, from.Rewards.Total.IsZero())
+
+	to := queryAccountOverview(t, app, toAddr)
+	require.Equal(t, int64(2), to.Height)
+	require.True(t, to.Coins.IsZero())
+	require.Equal(t, 1, len(to.LockedCoins))
+	require.Equal(t, dex.NewCetCoin(2e8), to.LockedCoins[0].Coin)
+	require.False(t, to.MemoRequired)
+
+	ret := app.Query(abci.RequestQuery{Path: "custom/" + overview.QuerierRoute + "/unknown"})
+	require.False(t, ret.IsOK())
+}
+
+func TestAccountOverviewOfDelegator(t *testing.T) {
+	amountVal := cetToken().GetTotalSupply().Int64() - 2e10
+	valKey, valAcc := testutil.NewBaseAccount(amountVal, 0, 0)
+	valAddr := sdk.ValAddress(valAcc.Address)
+	consAddr := valAcc.PubKey.Address()
+	delKey, delAcc := testutil.NewBaseAccount(2e10, 1, 0)
+	newOrder := func(sender sdk.AccAddress, sequence uint64) *market.Order {
+		return &market.Order{Sender: sender, Sequence: sequence, TradingPair: "abc/cet", OrderType: market.LimitOrder,
+			Price: sdk.NewDec(1), Quantity: 100, Side: market.BUY, TimeInForce: market.GTE,
+			Height: 1, ExistBlocks: 10000, LeftStock: 100}
+	}
+	app := initApp(func(genState *GenesisState) {
+		addGenesisAccounts(genState, valAcc, delAcc)
+		genState.StakingXData.Params.MinSelfDelegation = 1
+		genState.MarketData.MarketInfos = []market.MarketInfo{{Stock: "abc", Money: dex.CET}}
+		genState.MarketData.Orders = []*market.Order{
+			newOrder(delAcc.Address, 1), newOrder(valAcc.Address, 1), newOrder(delAcc.Address, 2),
+		}
+	})
+
+	// the delegator delegates at height 1, undelegates a part at height 2 and gets rewards
+	votes := []abci.VoteInfo{{Validator: abci.Validator{Address: consAddr, Power: 1}, SignedLastBlock: true}}
+	for height := int64(1); height <= 3; height++ {
+		header := abci.Header{Height: height, ChainID: testChainID}
+		var commitInfo abci.LastCommitInfo
+		if height > 1 {
+			header.ProposerAddress = consAddr
+			commitInfo.Votes = votes
+		}
+		app.BeginBlock(abci.RequestBeginBlock{Header: header, LastCommitInfo: commitInfo})
+		switch height {
+		case 1:
+			createValMsg := testutil.NewMsgCreateValidatorBuilder(valAddr, valAcc.PubKey).
+				MinSelfDelegation(1).SelfDelegation(1e10).Commission("0.1", "0.1", "0.01").Build()
+			result := app.Deliver(newStdTxBuilder().
+				Msgs(createValMsg).GasAndFee(1000000, 100).AccNumSeqKey(0, 0, valKey).Build())
+			require.Equal(t, sdk.CodeOK, result.Code)
+			delMsg := staking.NewMsgDelegate(delAcc.Address, valAddr, dex.NewCetCoin(1e10))
+			result = app.Deliver(newStdTxBuilder().
+				Msgs(delMsg).GasAndFee(1000000, 100).AccNumSeqKey(1, 0, delKey).Build())
+			require.Equal(t, sdk.CodeOK, result.Code)
+		case 2:
+			undelMsg := staking.NewMsgUndelegate(delAcc.Address, valAddr, dex.NewCetCoin(1e9))
+			result := app.Deliver(newStdTxBuilder().
+				Msgs(undelMsg).GasAndFee(1000000, 100).AccNumSeqKey(1, 1, delKey).Build())
+			require.Equal(t, sdk.CodeOK, result.Code)
+		}
+		app.EndBlock(abci.RequestEndBlock{Height: height})
+		app.Commit()
+	}
+
+	res := queryAccountOverview(t, app, delAcc.Address)
+	require.Equal(t, int64(3), res.Height)
+	require.Equal(t, uint64(2), res.Sequence)
+	require.Equal(t, 1, len(res.Delegations))
+	require.Equal(t, valAddr, res.Delegations[0].ValidatorAddress)
+	require.Equal(t, sdk.NewDec(9e9), res.Delegations[0].Shares)
+	require.Equal(t, 1, len(res.UnbondingDelegations))
+	require.Equal(t, 1, len(res.UnbondingDelegations[0].Entries))
+	require.Equal(t, sdk.NewInt(1e9), res.UnbondingDelegations[0].Entries[0].Balance)
+	require.Equal(t, 1, len(res.Rewards.Rewards))
+	require.Equal(t, valAddr, res.Rewards.Rewards[0].ValidatorAddress)
+	require.True(t, res.Rewards.Total.IsAllPositive())
+	require.Equal(t, 2, len(res.OpenOrders))
+	require.Equal(t, newOrder(delAcc.Address, 1).OrderID(), res.OpenOrders[0].OrderID())
+	require.Equal(t, newOrder(delAcc.Address, 2).OrderID(), res.OpenOrders[1].OrderID())
+}
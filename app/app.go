@@ -1,7 +1,6 @@
 package app
 
 import (
-	"encoding/json"
 	"io"
 	"os"
 	"strconv"
@@ -17,6 +16,7 @@ import (
 
 	bam "github.com/cosmos/cosmos-sdk/baseapp"
 	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/store"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/cosmos/cosmos-sdk/types/module"
 	"github.com/cosmos/cosmos-sdk/version"
@@ -47,7 +47,12 @@ import (
 	"github.com/coinexchain/cet-sdk/modules/supplyx"
 	"github.com/coinexchain/cet-sdk/msgqueue"
 	dex "github.com/coinexchain/cet-sdk/types"
+	"github.com/coinexchain/dex/app/overview"
 	"github.com/coinexchain/dex/app/plugin"
+	"github.com/coinexchain/dex/app/snapshot"
+	"github.com/coinexchain/dex/app/txindex"
+	"github.com/coinexchain/dex/app/upgrade"
+	upgradeclient "github.com/coinexchain/dex/app/upgrade/client"
 )
 
 const (
@@ -90,6 +95,7 @@ func init() {
 		comment.AppModuleBasic{},
 		incentive.AppModuleBasic{},
 		market.AppModuleBasic{},
+		upgrade.AppModuleBasic{},
 
 		//modules wraps those of cosmos
 		authx.AppModuleBasic{}, //before `bank` to override `/bank/balances/{address}`
@@ -100,7 +106,8 @@ func init() {
 		//modules of cosmos
 		AuthModuleBasic{},
 		CrisisModuleBasic{},
-		GovModuleBasic{gov.NewAppModuleBasic(paramsclient.ProposalHandler, distrclient.ProposalHandler)},
+		GovModuleBasic{gov.NewAppModuleBasic(paramsclient.ProposalHandler, distrclient.ProposalHandler,
+			upgradeclient.ProposalHandler, upgradeclient.CancelProposalHandler)},
 		SlashingModuleBasic{},
 		StakingModuleBasic{},
 		bank.AppModuleBasic{},
@@ -153,6 +160,14 @@ type CetChainApp struct {
 	keyIncentive *sdk.KVStoreKey
 	keyAlias     *sdk.KVStoreKey
 	keyComment   *sdk.KVStoreKey
+	keyUpgrade   *sdk.KVStoreKey
+
+	// the multistore of BaseApp and its db, kept to mount the upgrade store at its height
+	db  dbm.DB
+	cms sdk.CommitMultiStore
+
+	upgradeStoreHeight  int64
+	upgradeStoreMounted bool
 
 	// Manage getting and setting accounts
 	accountKeeper   auth.AccountKeeper
@@ -176,6 +191,7 @@ type CetChainApp struct {
 	msgQueProducer  msgqueue.MsgSender
 	aliasKeeper     alias.Keeper
 	commentKeeper   comment.Keeper
+	upgradeKeeper   upgrade.Keeper
 
 	enableUnconfirmedLimit bool
 	currBlockTime          int64
@@ -184,7 +200,13 @@ type CetChainApp struct {
 	// the module manager
 	mm *module.Manager
 
-	pubMsgs []PubMsg
+	pubMsgs           []PubMsg
+	blockSummary      NotificationBlockSummary
+	feeCollectorCoins sdk.Coins
+	touchedAccounts   *TouchedAccounts
+	livenessTracker   *LivenessTracker
+	txIndexer         *txindex.Indexer
+	txIndexInBlock    int
 	plugin.Holder
 }
 
@@ -195,13 +217,24 @@ func NewCetChainApp(logger log.Logger, db dbm.DB, traceStore io.Writer, loadLate
 	cdc := MakeCodec()
 
 	txDecoder := auth.DefaultTxDecoder(cdc)
+	cms := store.NewCommitMultiStore(db)
+	baseAppOptions = append([]func(*bam.BaseApp){func(bApp *bam.BaseApp) { bApp.SetCMS(cms) }}, baseAppOptions...)
 	bApp := bam.NewBaseApp(appName, logger, db, txDecoder, baseAppOptions...)
 	bApp.SetCommitMultiStoreTracer(traceStore)
 	bApp.SetAppVersion(version.Version)
 	bam.SetHaltHeight(viper.GetUint64(server.FlagHaltHeight))(bApp)
 
 	app := newCetChainApp(bApp, cdc, invCheckPeriod, txDecoder)
+	app.db, app.cms = db, cms
+	app.upgradeStoreHeight = viper.GetInt64(FlagUpgradeStoreHeight)
 	app.initPubMsgBuf()
+	app.touchedAccounts = NewTouchedAccounts(viper.GetStringSlice(FlagBalanceChangeAddrs))
+	livenessTracker, err := NewLivenessTracker(viper.GetString(FlagLivenessWarningFractions))
+	if err != nil {
+		cmn.Exit(err.Error())
+	}
+	app.livenessTracker = livenessTracker
+	app.txIndexer = newTxIndexer()
 	app.initKeepers(invCheckPeriod)
 	app.initModules()
 	app.mountStores()
@@ -221,11 +254,13 @@ func NewCetChainApp(logger log.Logger, db dbm.DB, traceStore io.Writer, loadLate
 		if err != nil {
 			cmn.Exit(err.Error())
 		}
+		if err = app.checkUpgrades(); err != nil {
+			cmn.Exit(err.Error())
+		}
 	}
 
 	unconfirmedTxLimitTime, ok := os.LookupEnv("COINEX_UNCONFIRMED_TX_LIMIT_TIME")
 	var limitTime int64
-	var err error
 	if ok {
 		limitTime, err = strconv.ParseInt(unconfirmedTxLimitTime, 10, 64)
 		if err != nil {
@@ -267,6 +302,7 @@ func newCetChainApp(bApp *bam.BaseApp, cdc *codec.Codec, invCheckPeriod uint, tx
 		keyIncentive:   sdk.NewKVStoreKey(incentive.StoreKey),
 		keyAlias:       sdk.NewKVStoreKey(alias.StoreKey),
 		keyComment:     sdk.NewKVStoreKey(comment.StoreKey),
+		keyUpgrade:     sdk.NewKVStoreKey(upgrade.StoreKey),
 	}
 }
 
@@ -313,11 +349,16 @@ func (app *CetChainApp) initKeepers(invCheckPeriod uint) {
 		staking.DefaultCodespace,
 	)
 
+	app.upgradeKeeper = upgrade.NewKeeper(app.keyUpgrade, app.cdc)
+	app.upgradeKeeper.SetActiveChecker(func() bool { return app.upgradeStoreMounted })
+	app.registerUpgradeHandlers()
+
 	// register the proposal types
 	govRouter := gov.NewRouter()
 	govRouter.AddRoute(gov.RouterKey, gov.ProposalHandler).
 		AddRoute(params.RouterKey, params.NewParamChangeProposalHandler(app.paramsKeeper)).
-		AddRoute(distr.RouterKey, distr.NewCommunityPoolSpendProposalHandler(app.distrKeeper))
+		AddRoute(distr.RouterKey, distr.NewCommunityPoolSpendProposalHandler(app.distrKeeper)).
+		AddRoute(upgrade.RouterKey, upgrade.NewSoftwareUpgradeProposalHandler(app.upgradeKeeper))
 
 	app.govKeeper = gov.NewKeeper(
 		app.cdc,
@@ -452,7 +493,8 @@ func (app *CetChainApp) initModules() {
 	// During begin block slashing happens after distr.BeginBlocker so that
 	// there is nothing left over in the validator fee pool, so as to keep the
 	// CanWithdrawInvariant invariant.
-	app.mm.SetOrderBeginBlockers(market.ModuleName, incentive.ModuleName, distr.ModuleName, slashing.ModuleName)
+	// upgrade must be the first one, so the stores are migrated before being used.
+	app.mm.SetOrderBeginBlockers(upgrade.ModuleName, market.ModuleName, incentive.ModuleName, distr.ModuleName, slashing.ModuleName)
 
 	app.mm.SetOrderEndBlockers(gov.ModuleName, staking.ModuleName, authx.ModuleName, market.ModuleName, crisis.ModuleName)
 
@@ -492,6 +534,7 @@ func (app *CetChainApp) createAppModules() []module.AppModule {
 		genutil.NewAppModule(app.accountKeeper, app.stakingKeeper, app.BaseApp.DeliverTx),
 		alias.NewAppModule(app.aliasKeeper),
 		comment.NewAppModule(app.commentKeeper),
+		upgrade.NewAppModule(app.upgradeKeeper),
 	}
 }
 
@@ -516,6 +559,7 @@ func getAppModuleInitOrder() []string {
 		genutil.ModuleName, //call DeliverGenTxs in genutil at last
 		alias.ModuleName,
 		comment.ModuleName,
+		upgrade.ModuleName,
 	}
 }
 
@@ -531,30 +575,51 @@ func (app *CetChainApp) registerRoutesWithOrder(modules []module.AppModule) {
 			queryRouter.AddRoute(module.QuerierRoute(), module.NewQuerierHandler())
 		}
 	}
+	queryRouter.AddRoute(txindex.QuerierRoute, txindex.NewQuerier(app.cdc, app.txIndexer))
+	queryRouter.AddRoute(overview.QuerierRoute, app.newOverviewQuerier())
 }
 
-// initialize BaseApp
+// initialize BaseApp, the upgrade store is mounted when a version is loaded
 func (app *CetChainApp) mountStores() {
-	app.MountStores(app.keyMain, app.keyAccount, app.keySupply, app.keyStaking, app.keyDistr,
+	for _, key := range app.kvStoreKeys() {
+		if key != app.keyUpgrade {
+			app.MountStores(key)
+		}
+	}
+	app.MountStores(app.tkeyParams, app.tkeyStaking)
+}
+
+// the stores persisted in the db
+func (app *CetChainApp) kvStoreKeys() []*sdk.KVStoreKey {
+	return []*sdk.KVStoreKey{app.keyMain, app.keyAccount, app.keySupply, app.keyStaking, app.keyDistr,
 		app.keySlashing, app.keyGov, app.keyParams,
-		app.tkeyParams, app.tkeyStaking,
 		app.keyAccountX, app.keyAsset, app.keyMarket, app.keyIncentive,
 		app.keyBancor, app.keyAlias, app.keyComment, app.keyStakingX,
-	)
+		app.keyUpgrade,
+	}
 }
 
 // application updates every begin block
 func (app *CetChainApp) beginBlocker(ctx sdk.Context, req abci.RequestBeginBlock) abci.ResponseBeginBlock {
 	app.height = ctx.BlockHeight()
+	app.txIndexInBlock = 0
 	app.resetPubMsgBuf()
 	if app.msgQueProducer.IsOpenToggle() {
 		app.txCount = req.Header.TotalTxs - req.Header.NumTxs
 		app.pushNewHeightInfo(ctx)
 	}
 	ret := app.mm.BeginBlock(ctx, req)
+	if app.isBalanceChangeEnabled() {
+		app.touchedAccounts.Reset()
+		app.touchedAccounts.AddFromEvents(ret.Events)
+	}
+	if app.isLivenessNotificationEnabled() {
+		app.livenessTracker.BeginBlock(req, ret.Events)
+	}
 	if app.msgQueProducer.IsOpenToggle() {
 		ret.Events = collectKafkaEvents(ret.Events, app)
 		app.notifyBeginBlock(ret.Events)
+		app.resetBlockSummary(ctx, req)
 	}
 	if app.enableUnconfirmedLimit {
 		app.currBlockTime = req.Header.Time.Unix()
@@ -567,22 +632,44 @@ func (app *CetChainApp) beginBlocker(ctx sdk.Context, req abci.RequestBeginBlock
 // nolint: unparam
 func (app *CetChainApp) endBlocker(ctx sdk.Context, req abci.RequestEndBlock) abci.ResponseEndBlock {
 	ret := app.mm.EndBlock(ctx, req)
+	if app.isBalanceChangeEnabled() {
+		app.touchedAccounts.AddFromEvents(ret.Events)
+	}
 	if app.msgQueProducer.IsOpenToggle() {
 		ret.Events = collectKafkaEvents(ret.Events, app)
 		app.notifyEndBlock(ret.Events)
+		if app.isBalanceChangeEnabled() {
+			app.notifyBalanceChanges(ctx)
+		}
+		if app.isLivenessNotificationEnabled() {
+			app.notifyValidatorLiveness(ctx)
+		}
+		app.setBlockSummaryFees(ctx)
 	}
 	return ret
 }
 
 // custom logic for coindex initialization
 func (app *CetChainApp) initChainer(ctx sdk.Context, req abci.RequestInitChain) abci.ResponseInitChain {
-	var genesisState map[string]json.RawMessage
-	app.cdc.MustUnmarshalJSON(req.AppStateBytes, &genesisState)
+	// the sections of modules are slices of req.AppStateBytes, to save memory for a large state
+	genesisState, err := splitAppState(req.AppStateBytes)
+	if err != nil {
+		panic(err)
+	}
 
+	// the accounts and the orders are validated and imported one at a time
+	lists, err := splitStreamedLists(genesisState)
+	if err != nil {
+		panic(err)
+	}
 	if err := ModuleBasics.ValidateGenesis(genesisState); err != nil {
 		panic(err)
 	}
-	return app.mm.InitGenesis(ctx, genesisState)
+	ret := app.initGenesis(ctx, genesisState, lists)
+	if app.isBalanceChangeEnabled() {
+		writeGenesisState(ctx)
+	}
+	return ret
 }
 
 // load a particular height
@@ -590,6 +677,20 @@ func (app *CetChainApp) LoadHeight(height int64) error {
 	return app.LoadVersion(height, app.keyMain)
 }
 
+func (app *CetChainApp) LoadLatestVersion(baseKey *sdk.KVStoreKey) error {
+	if err := app.mountUpgradeStore(snapshot.LatestVersion(app.db)); err != nil {
+		return err
+	}
+	return app.BaseApp.LoadLatestVersion(baseKey)
+}
+
+func (app *CetChainApp) LoadVersion(version int64, baseKey *sdk.KVStoreKey) error {
+	if err := app.mountUpgradeStore(version); err != nil {
+		return err
+	}
+	return app.BaseApp.LoadVersion(version, baseKey)
+}
+
 // ModuleAccountAddrs returns all the app's module account addresses.
 func (app *CetChainApp) ModuleAccountAddrs() map[string]bool {
 	modAccAddrs := make(map[string]bool)
@@ -620,6 +721,15 @@ func (app *CetChainApp) appendPubMsgKV(key string, val []byte) {
 
 /* "override" ABCI methods */
 
+func (app *CetChainApp) BeginBlock(req abci.RequestBeginBlock) abci.ResponseBeginBlock {
+	if !app.upgradeStoreMounted && req.Header.Height == app.upgradeStoreHeight {
+		if err := app.loadUpgradeStore(req.Header.Height - 1); err != nil {
+			panic(err)
+		}
+	}
+	return app.BaseApp.BeginBlock(req)
+}
+
 func (app *CetChainApp) CheckTx(req abci.RequestCheckTx) abci.ResponseCheckTx {
 	if p := app.GetPlugin(); p != nil {
 		if err := p.PreCheckTx(req, app.txDecoder, app.Logger()); err != nil {
@@ -687,10 +797,29 @@ func (app *CetChainApp) DeliverTx(req abci.RequestDeliverTx) abci.ResponseDelive
 
 	ret := app.BaseApp.DeliverTx(req)
 
+	if formatOK && app.isBalanceChangeEnabled() {
+		for _, signer := range stdTx.GetSigners() {
+			app.touchedAccounts.Add(signer)
+		}
+		if ret.Code == uint32(sdk.CodeOK) {
+			app.touchedAccounts.AddFromEvents(ret.Events)
+		}
+	}
+	if formatOK && ret.Code == uint32(sdk.CodeOK) && app.isLivenessNotificationEnabled() {
+		app.livenessTracker.AddUnjailed(stdTx.Msgs)
+	}
+	if app.txIndexer != nil {
+		if formatOK {
+			app.indexTx(req, stdTx, ret)
+		}
+		app.txIndexInBlock++
+	}
+
 	if app.msgQueProducer.IsOpenToggle() {
 		if formatOK {
 			app.notifyTx(req, stdTx, ret)
 		}
+		app.addTxToBlockSummary(ret)
 		if ret.Code == uint32(sdk.CodeOK) {
 			ret.Events = collectKafkaEvents(ret.Events, app)
 		} else {
@@ -707,6 +836,7 @@ func (app *CetChainApp) DeliverTx(req abci.RequestDeliverTx) abci.ResponseDelive
 
 func (app *CetChainApp) Commit() abci.ResponseCommit {
 	if app.msgQueProducer.IsOpenToggle() {
+		app.notifyBlockSummary()
 		for _, msg := range app.pubMsgs {
 			app.msgQueProducer.SendMsg(msg.Key, msg.Value)
 		}
@@ -715,5 +845,9 @@ func (app *CetChainApp) Commit() abci.ResponseCommit {
 	if app.enableUnconfirmedLimit {
 		app.account2UnconfirmedTx.CommitRemove(app.currBlockTime)
 	}
+	// the index is committed first, so the block is indexed again if the app is not committed
+	if app.txIndexer != nil {
+		app.txIndexer.Commit()
+	}
 	return app.BaseApp.Commit()
 }
@@ -361,3 +361,56 @@ func getDelegatorRewardsMsg(event abci.Event) []byte {
 	}
 	return dex.SafeJSONMarshal(res)
 }
+
+type NotificationBlockSummary struct {
+	Height        int64  `json:"height"`
+	TimeStamp     int64  `json:"timestamp"`
+	Proposer      string `json:"proposer"`
+	TotalTxCount  int    `json:"total_tx_count"`
+	FailedTxCount int    `json:"failed_tx_count"`
+	TotalGasUsed  int64  `json:"total_gas_used"`
+	CollectedFees string `json:"collected_fees"`
+	PubMsgCount   int    `json:"pub_msg_count"`
+}
+
+func (app *CetChainApp) resetBlockSummary(ctx sdk.Context, req abci.RequestBeginBlock) {
+	app.blockSummary = NotificationBlockSummary{
+		Height:    req.Header.Height,
+		TimeStamp: req.Header.Time.Unix(),
+		Proposer:  sdk.ConsAddress(req.Header.ProposerAddress).String(),
+	}
+	// the fee collector has been drained by distribution in BeginBlock, so
+	// whatever it holds at the end of this block was collected in this block
+	app.feeCollectorCoins = app.getFeeCollectorCoins(ctx)
+}
+
+func (app *CetChainApp) addTxToBlockSummary(ret abci.ResponseDeliverTx) {
+	app.blockSummary.TotalTxCount++
+	if ret.Code != uint32(sdk.CodeOK) {
+		app.blockSummary.FailedTxCount++
+	}
+	app.blockSummary.TotalGasUsed += ret.GasUsed
+}
+
+func (app *CetChainApp) setBlockSummaryFees(ctx sdk.Context) {
+	fees, negative := app.getFeeCollectorCoins(ctx).SafeSub(app.feeCollectorCoins)
+	if negative {
+		fees = sdk.Coins{}
+	}
+	app.blockSummary.CollectedFees = fees.String()
+}
+
+// getFeeCollectorCoins reads the fee collector's balance without creating the module
+// account, so that enabling notifications never changes the state
+func (app *CetChainApp) getFeeCollectorCoins(ctx sdk.Context) sdk.Coins {
+	acc := app.accountKeeper.GetAccount(ctx, app.supplyKeeper.GetModuleAddress(auth.FeeCollectorName))
+	if acc == nil {
+		return sdk.Coins{}
+	}
+	return acc.GetCoins()
+}
+
+func (app *CetChainApp) notifyBlockSummary() {
+	app.blockSummary.PubMsgCount = len(app.pubMsgs)
+	app.appendPubMsgKV("block_summary", dex.SafeJSONMarshal(app.blockSummary))
+}
@@ -0,0 +1,27 @@
+package app
+
+import (
+	tmtypes "github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/coinexchain/dex/app/snapshot"
+	"github.com/coinexchain/dex/app/upgrade"
+)
+
+// CreateSnapshot writes a snapshot of all the stores at height to dir, db must be the one of app
+func (app *CetChainApp) CreateSnapshot(db dbm.DB, height int64, header *tmtypes.Header,
+	dir string, chunkSize int64) (*snapshot.Manifest, error) {
+
+	// the upgrade store is not committed before its height on a chain started by an older binary
+	upgradeCommitted, err := app.isStoreCommitted(upgrade.StoreKey, height)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, key := range app.kvStoreKeys() {
+		if key != app.keyUpgrade || upgradeCommitted {
+			names = append(names, key.Name())
+		}
+	}
+	return snapshot.Create(db, height, names, header, dir, chunkSize)
+}
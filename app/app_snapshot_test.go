@@ -0,0 +1,67 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+	tmtypes "github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+
+	bam "github.com/cosmos/cosmos-sdk/baseapp"
+	"github.com/cosmos/cosmos-sdk/store"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+
+	"github.com/coinexchain/dex/app/snapshot"
+)
+
+func TestSnapshot(t *testing.T) {
+	_, _, addr := testutil.KeyPubAddr()
+	acc := auth.BaseAccount{Address: addr, Coins: dex.NewCetCoins(1000)}
+
+	db := dbm.NewMemDB()
+	app1 := NewCetChainApp(log.NewNopLogger(), db, nil, true, 10000, bam.SetPruning(store.PruneNothing))
+	initChain(app1, func(genState *GenesisState) {
+		addGenesisAccounts(genState, acc)
+	})
+	var header *tmtypes.Header
+	for height := int64(1); height <= 3; height++ {
+		app1.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: height, Time: time.Now(), ChainID: testChainID}})
+		app1.EndBlock(abci.RequestEndBlock{Height: height})
+		commit := app1.Commit()
+		if height == 2 {
+			header = &tmtypes.Header{Height: 3, AppHash: commit.Data}
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "snapshot")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	m, err := app1.CreateSnapshot(db, 2, header, dir, snapshot.DefaultChunkSize)
+	require.Nil(t, err)
+	require.Equal(t, len(app1.kvStoreKeys()), len(m.Stores))
+
+	db2 := dbm.NewMemDB()
+	_, err = snapshot.Restore(db2, dir)
+	require.Nil(t, err)
+	app2 := NewCetChainApp(log.NewNopLogger(), db2, nil, true, 10000)
+	require.Equal(t, int64(2), app2.LastBlockHeight())
+	require.Equal(t, []byte(header.AppHash), app2.LastCommitID().Hash)
+
+	ctx := app2.NewContext(true, abci.Header{Height: app2.LastBlockHeight()})
+	require.Equal(t, acc.Coins, app2.accountKeeper.GetAccount(ctx, addr).GetCoins())
+
+	// the restored app goes on from the next block
+	app2.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: 3, Time: time.Now(), ChainID: testChainID}})
+	app2.EndBlock(abci.RequestEndBlock{Height: 3})
+	app2.Commit()
+	require.Equal(t, int64(3), app2.LastBlockHeight())
+}
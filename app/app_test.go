@@ -1,6 +1,7 @@
 package app
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"testing"
@@ -126,8 +127,17 @@ func addAccountForDanglingCET(amount int64, genState *GenesisState) {
 
 func initApp(cb genesisStateCallback, baseAppOptions ...func(*bam.BaseApp)) *CetChainApp {
 	app := newApp(baseAppOptions...)
+	initChain(app, cb)
+	return app
+}
 
-	// genesis state
+func initChain(app *CetChainApp, cb genesisStateCallback) {
+	// init chain
+	genStateBytes := newGenesisStateBytes(app, cb)
+	app.InitChain(abci.RequestInitChain{ChainId: testChainID, AppStateBytes: genStateBytes})
+}
+
+func newGenesisStateBytes(app *CetChainApp, cb genesisStateCallback) []byte {
 	genState := NewDefaultGenesisState()
 
 	cetToken := cetToken()
@@ -139,11 +149,8 @@ func initApp(cb genesisStateCallback, baseAppOptions ...func(*bam.BaseApp)) *Cet
 		cb(&genState)
 	}
 
-	// init chain
 	genStateBytes, _ := app.cdc.MarshalJSON(genState)
-	app.InitChain(abci.RequestInitChain{ChainId: testChainID, AppStateBytes: genStateBytes})
-
-	return app
+	return genStateBytes
 }
 
 func initAppWithAccounts(accs ...auth.BaseAccount) *CetChainApp {
@@ -838,3 +845,49 @@ func TestMsgSetRefereeHandle(t *testing.T) {
 	require.Equal(t, authx.CodeRefereeChangeTooFast, res.Code)
 
 }
+
+func TestBlockSummary(t *testing.T) {
+	toAddr := sdk.AccAddress([]byte("addr"))
+	key, _, fromAddr := testutil.KeyPubAddr()
+	acc0 := auth.BaseAccount{Address: fromAddr, Coins: dex.NewCetCoins(30e8)}
+
+	// app
+	app := initAppWithBaseAccounts(acc0)
+
+	// begin block
+	now := time.Now()
+	proposer := sdk.ConsAddress([]byte("proposer"))
+	header := abci.Header{Height: 1, Time: now, ProposerAddress: proposer}
+	app.BeginBlock(abci.RequestBeginBlock{Header: header})
+
+	// deliver txs, the second one has a bad sequence
+	msg := bankx.NewMsgSend(fromAddr, toAddr, dex.NewCetCoins(1e8), 0)
+	tx := newStdTxBuilder().
+		Msgs(msg).GasAndFee(1000000, 100).AccNumSeqKey(0, 0, key).Build()
+	result := app.Deliver(tx)
+	require.Equal(t, sdk.CodeOK, result.Code)
+	gasUsed := int64(result.GasUsed)
+
+	tx = newStdTxBuilder().
+		Msgs(msg).GasAndFee(1000000, 100).AccNumSeqKey(0, 5, key).Build()
+	result = app.Deliver(tx)
+	require.NotEqual(t, sdk.CodeOK, result.Code)
+	gasUsed += int64(result.GasUsed)
+
+	app.EndBlock(abci.RequestEndBlock{Height: 1})
+	app.Commit()
+
+	last := app.pubMsgs[len(app.pubMsgs)-1]
+	require.Equal(t, "block_summary", string(last.Key))
+	var summary NotificationBlockSummary
+	require.NoError(t, json.Unmarshal(last.Value, &summary))
+	require.Equal(t, int64(1), summary.Height)
+	require.Equal(t, now.Unix(), summary.TimeStamp)
+	require.Equal(t, proposer.String(), summary.Proposer)
+	require.Equal(t, 2, summary.TotalTxCount)
+	require.Equal(t, 1, summary.FailedTxCount)
+	require.Equal(t, gasUsed, summary.TotalGasUsed)
+	// tx fee plus the activation fee of toAddr
+	require.Equal(t, "100000100cet", summary.CollectedFees)
+	require.Equal(t, len(app.pubMsgs)-1, summary.PubMsgCount)
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/viper"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/cli"
+	tmtypes "github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+
+	"github.com/coinexchain/dex/app/txindex"
+)
+
+// FlagTxIndex enables the local transaction index, which is kept in data/txindex.db
+const FlagTxIndex = "app-tx-index"
+
+const txIndexDBName = "txindex"
+
+func newTxIndexer() *txindex.Indexer {
+	if !viper.GetBool(FlagTxIndex) {
+		return nil
+	}
+	dataDir := filepath.Join(viper.GetString(cli.HomeFlag), "data")
+	db := dbm.NewDB(txIndexDBName, dbm.GoLevelDBBackend, dataDir)
+	return txindex.NewIndexer(db)
+}
+
+// OpenTxIndexDB opens the db of the local transaction index in the data directory of home,
+// it returns nil if the index has never been enabled
+func OpenTxIndexDB(home string) (dbm.DB, error) {
+	dataDir := filepath.Join(home, "data")
+	if _, err := os.Stat(filepath.Join(dataDir, txIndexDBName+".db")); os.IsNotExist(err) {
+		return nil, nil
+	}
+	return dbm.NewGoLevelDB(txIndexDBName, dataDir)
+}
+
+// Close closes the local transaction index, the db of BaseApp is closed by its owner. The
+// start command exits without closing the app after the node is stopped by a signal, which
+// keeps the index, as the records of every block are written with sync.
+func (app *CetChainApp) Close() {
+	if app.txIndexer != nil {
+		app.txIndexer.Close()
+	}
+}
+
+func (app *CetChainApp) indexTx(req abci.RequestDeliverTx, stdTx auth.StdTx, ret abci.ResponseDeliverTx) {
+	msgTypes := make([]string, len(stdTx.Msgs))
+	for i, msg := range stdTx.Msgs {
+		msgTypes[i] = getType(msg)
+	}
+
+	var recipients []sdk.AccAddress
+	if ret.Code == uint32(sdk.CodeOK) {
+		for _, event := range ret.Events {
+			if event.Type != "transfer" {
+				continue
+			}
+			for _, attr := range event.Attributes {
+				if string(attr.Key) != "recipient" {
+					continue
+				}
+				if addr, err := sdk.AccAddressFromBech32(string(attr.Value)); err == nil {
+					recipients = append(recipients, addr)
+				}
+			}
+		}
+	}
+
+	app.txIndexer.IndexTx(txindex.IndexedTx{
+		Hash:       tmtypes.Tx(req.Tx).Hash(),
+		Height:     app.height,
+		Index:      app.txIndexInBlock,
+		Code:       ret.Code,
+		Signers:    stdTx.GetSigners(),
+		Recipients: recipients,
+		MsgTypes:   msgTypes,
+	})
+}
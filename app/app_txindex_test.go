@@ -0,0 +1,61 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	dbm "github.com/tendermint/tm-db"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+
+	"github.com/coinexchain/cet-sdk/modules/bankx"
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+
+	"github.com/coinexchain/dex/app/txindex"
+)
+
+func TestTxIndex(t *testing.T) {
+	_, _, toAddr := testutil.KeyPubAddr()
+	key, _, fromAddr := testutil.KeyPubAddr()
+	acc0 := auth.BaseAccount{Address: fromAddr, Coins: dex.NewCetCoins(30e8)}
+
+	// app
+	app := initAppWithBaseAccounts(acc0)
+	app.txIndexer = txindex.NewIndexer(dbm.NewMemDB())
+
+	// begin block
+	header := abci.Header{Height: 1, Time: time.Now()}
+	app.BeginBlock(abci.RequestBeginBlock{Header: header})
+
+	// deliver tx
+	msg := bankx.NewMsgSend(fromAddr, toAddr, dex.NewCetCoins(1e8), 0)
+	tx := newStdTxBuilder().
+		Msgs(msg).GasAndFee(1000000, 100).AccNumSeqKey(0, 0, key).Build()
+	result := app.Deliver(tx)
+	require.Equal(t, sdk.CodeOK, result.Code)
+	app.EndBlock(abci.RequestEndBlock{Height: 1})
+
+	// not visible before commit
+	txs, err := app.txIndexer.QueryTxs(txindex.NewQueryAccountTxsParam(toAddr, "", 1, 10))
+	require.NoError(t, err)
+	require.Equal(t, 0, len(txs))
+	app.Commit()
+
+	txs, err = app.txIndexer.QueryTxs(txindex.NewQueryAccountTxsParam(toAddr, "", 1, 10))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(txs))
+	require.Equal(t, int64(1), txs[0].Height)
+	require.Equal(t, 0, txs[0].Index)
+	require.Equal(t, []sdk.AccAddress{fromAddr}, txs[0].Signers)
+	require.Contains(t, txs[0].Recipients, toAddr)
+	require.Equal(t, []string{"MsgSend"}, txs[0].MsgTypes)
+
+	txs, err = app.txIndexer.QueryTxs(txindex.NewQueryAccountTxsParam(fromAddr, "MsgSend", 1, 10))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(txs))
+}
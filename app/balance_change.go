@@ -0,0 +1,169 @@
+package app
+
+import (
+	"encoding/json"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+
+	"github.com/coinexchain/cet-sdk/modules/authx"
+	"github.com/coinexchain/cet-sdk/msgqueue"
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+// FlagBalanceChangeAddrs limits balance_change notifications to the listed addresses,
+// all the touched accounts are reported when it is empty. The list is set for the node and
+// the notifications are sent to all of its brokers, a subscriber which watches only some
+// of the addresses filters the notifications by their address field.
+const FlagBalanceChangeAddrs = "balance-change-addrs"
+
+type AccountBalance struct {
+	Coins       string `json:"coins"`
+	LockedCoins string `json:"locked_coins"`
+	FrozenCoins string `json:"frozen_coins"`
+}
+
+type NotificationBalanceChange struct {
+	Address string         `json:"address"`
+	Height  int64          `json:"height"`
+	Before  AccountBalance `json:"before"`
+	After   AccountBalance `json:"after"`
+}
+
+// TouchedAccounts records the accounts which may have been changed in a block, in the
+// order they were seen
+type TouchedAccounts struct {
+	addrs    []sdk.AccAddress
+	seen     map[string]struct{}
+	watching map[string]struct{}
+}
+
+func NewTouchedAccounts(watchList []string) *TouchedAccounts {
+	ta := &TouchedAccounts{
+		addrs: make([]sdk.AccAddress, 0, 1000),
+		seen:  make(map[string]struct{}),
+	}
+	if len(watchList) != 0 {
+		ta.watching = make(map[string]struct{}, len(watchList))
+		for _, addr := range watchList {
+			ta.watching[addr] = struct{}{}
+		}
+	}
+	return ta
+}
+
+func (ta *TouchedAccounts) Add(addr sdk.AccAddress) {
+	s := addr.String()
+	if _, ok := ta.seen[s]; ok {
+		return
+	}
+	if ta.watching != nil {
+		if _, ok := ta.watching[s]; !ok {
+			return
+		}
+	}
+	ta.seen[s] = struct{}{}
+	ta.addrs = append(ta.addrs, addr)
+}
+
+// AddFromEvents collects the addresses found in the attributes of events, the
+// attributes of msgqueue events are JSON documents which are searched recursively
+func (ta *TouchedAccounts) AddFromEvents(events []abci.Event) {
+	for _, event := range events {
+		for _, attr := range event.Attributes {
+			if event.Type != msgqueue.EventTypeMsgQueue {
+				ta.addFromString(string(attr.Value))
+				continue
+			}
+			var doc interface{}
+			if err := json.Unmarshal(attr.Value, &doc); err == nil {
+				ta.addFromJSON(doc)
+			}
+		}
+	}
+}
+
+func (ta *TouchedAccounts) addFromJSON(doc interface{}) {
+	switch v := doc.(type) {
+	case string:
+		ta.addFromString(v)
+	case []interface{}:
+		for _, e := range v {
+			ta.addFromJSON(e)
+		}
+	case map[string]interface{}:
+		for _, e := range v {
+			ta.addFromJSON(e)
+		}
+	}
+}
+
+func (ta *TouchedAccounts) addFromString(s string) {
+	if addr, err := sdk.AccAddressFromBech32(s); err == nil {
+		ta.Add(addr)
+	}
+}
+
+func (ta *TouchedAccounts) List() []sdk.AccAddress {
+	return ta.addrs
+}
+
+func (ta *TouchedAccounts) Reset() {
+	ta.addrs = ta.addrs[:0]
+	ta.seen = make(map[string]struct{})
+}
+
+func (app *CetChainApp) isBalanceChangeEnabled() bool {
+	return app.touchedAccounts != nil && app.msgQueProducer.IsSubscribed(auth.ModuleName)
+}
+
+// writeGenesisState writes the state of InitChain to the underlying stores, where the
+// balances before the first block are read from, as the genesis state is committed with
+// the first block. The app hash of the first block is not changed, it is calculated after
+// all the writes of the block.
+func writeGenesisState(ctx sdk.Context) {
+	ctx.MultiStore().(sdk.CacheMultiStore).Write()
+}
+
+func (app *CetChainApp) notifyBalanceChanges(ctx sdk.Context) {
+	// the state of the last block, which is not changed until Commit
+	committedCtx := sdk.NewContext(app.cms.CacheMultiStore(), ctx.BlockHeader(), false, app.Logger())
+	for _, addr := range app.touchedAccounts.List() {
+		before := app.getBalance(committedCtx, addr)
+		after := app.getBalance(ctx, addr)
+		if before == after {
+			continue
+		}
+		msg := NotificationBalanceChange{
+			Address: addr.String(),
+			Height:  ctx.BlockHeight(),
+			Before:  before,
+			After:   after,
+		}
+		app.appendPubMsgKV("balance_change", dex.SafeJSONMarshal(msg))
+	}
+	app.touchedAccounts.Reset()
+}
+
+func (app *CetChainApp) getBalance(ctx sdk.Context, addr sdk.AccAddress) AccountBalance {
+	var coins sdk.Coins
+	if acc := app.accountKeeper.GetAccount(ctx, addr); acc != nil {
+		coins = acc.GetCoins()
+	}
+	accx, _ := app.accountXKeeper.GetAccountX(ctx, addr)
+	return newAccountBalance(coins, accx)
+}
+
+func newAccountBalance(coins sdk.Coins, accx authx.AccountX) AccountBalance {
+	locked := sdk.Coins{}
+	for _, lc := range accx.LockedCoins {
+		locked = locked.Add(sdk.Coins{lc.Coin})
+	}
+	return AccountBalance{
+		Coins:       coins.String(),
+		LockedCoins: locked.String(),
+		FrozenCoins: accx.FrozenCoins.String(),
+	}
+}
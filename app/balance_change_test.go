@@ -0,0 +1,131 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/common"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+
+	"github.com/coinexchain/cet-sdk/modules/bankx"
+	"github.com/coinexchain/cet-sdk/msgqueue"
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+func TestTouchedAccounts(t *testing.T) {
+	_, _, addr1 := testutil.KeyPubAddr()
+	_, _, addr2 := testutil.KeyPubAddr()
+	_, _, addr3 := testutil.KeyPubAddr()
+
+	ta := NewTouchedAccounts(nil)
+	ta.Add(addr1)
+	ta.AddFromEvents([]abci.Event{
+		{
+			Type: "transfer",
+			Attributes: []common.KVPair{
+				{Key: []byte("recipient"), Value: []byte(addr2.String())},
+				{Key: []byte("amount"), Value: []byte("100cet")},
+			},
+		},
+		{
+			Type: msgqueue.EventTypeMsgQueue,
+			Attributes: []common.KVPair{
+				{Key: []byte("fill_order_info"), Value: []byte(fmt.Sprintf(`{"sender":"%s","deals":["%s"]}`, addr1, addr3))},
+			},
+		},
+	})
+	require.Equal(t, []sdk.AccAddress{addr1, addr2, addr3}, ta.List())
+
+	ta.Reset()
+	require.Equal(t, 0, len(ta.List()))
+
+	ta = NewTouchedAccounts([]string{addr2.String()})
+	ta.Add(addr1)
+	ta.Add(addr2)
+	require.Equal(t, []sdk.AccAddress{addr2}, ta.List())
+}
+
+func TestBalanceChangeNotification(t *testing.T) {
+	_, _, toAddr := testutil.KeyPubAddr()
+	key, _, fromAddr := testutil.KeyPubAddr()
+	acc0 := auth.BaseAccount{Address: fromAddr, Coins: dex.NewCetCoins(30e8)}
+
+	// app
+	app := initAppWithBaseAccounts(acc0)
+
+	// commit genesis state
+	now := time.Now()
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: 1, Time: now}})
+	app.EndBlock(abci.RequestEndBlock{Height: 1})
+	app.Commit()
+
+	// send some locked coins
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: 2, Time: now, ChainID: testChainID}})
+	msg := bankx.NewMsgSend(fromAddr, toAddr, dex.NewCetCoins(3e8), now.Unix()+10000)
+	tx := newStdTxBuilder().
+		Msgs(msg).GasAndFee(1000000, 100).AccNumSeqKey(0, 0, key).Build()
+	result := app.Deliver(tx)
+	require.Equal(t, sdk.CodeOK, result.Code)
+	app.EndBlock(abci.RequestEndBlock{Height: 2})
+	app.Commit()
+
+	changes := make(map[string]NotificationBalanceChange)
+	for _, msg := range app.pubMsgs {
+		if string(msg.Key) == "balance_change" {
+			var change NotificationBalanceChange
+			require.NoError(t, json.Unmarshal(msg.Value, &change))
+			changes[change.Address] = change
+		}
+	}
+
+	from := changes[fromAddr.String()]
+	require.Equal(t, int64(2), from.Height)
+	require.Equal(t, "3000000000cet", from.Before.Coins)
+	require.Equal(t, "2699999900cet", from.After.Coins)
+
+	to := changes[toAddr.String()]
+	require.Equal(t, "", to.Before.Coins)
+	require.Equal(t, "", to.Before.LockedCoins)
+	require.Equal(t, "", to.After.Coins)
+	require.Equal(t, "200000000cet", to.After.LockedCoins)
+}
+
+func TestBalanceChangeOfFirstBlock(t *testing.T) {
+	_, _, toAddr := testutil.KeyPubAddr()
+	key, _, fromAddr := testutil.KeyPubAddr()
+	acc0 := auth.BaseAccount{Address: fromAddr, Coins: dex.NewCetCoins(30e8)}
+	app := initAppWithBaseAccounts(acc0)
+
+	// the genesis state is committed with the first block
+	now := time.Now()
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: 1, Time: now, ChainID: testChainID}})
+	msg := bankx.NewMsgSend(fromAddr, toAddr, dex.NewCetCoins(3e8), 0)
+	tx := newStdTxBuilder().
+		Msgs(msg).GasAndFee(1000000, 100).AccNumSeqKey(0, 0, key).Build()
+	result := app.Deliver(tx)
+	require.Equal(t, sdk.CodeOK, result.Code)
+	app.EndBlock(abci.RequestEndBlock{Height: 1})
+	app.Commit()
+
+	var changes []NotificationBalanceChange
+	for _, msg := range app.pubMsgs {
+		if string(msg.Key) == "balance_change" {
+			var change NotificationBalanceChange
+			require.NoError(t, json.Unmarshal(msg.Value, &change))
+			if change.Address == fromAddr.String() {
+				changes = append(changes, change)
+			}
+		}
+	}
+	require.Equal(t, 1, len(changes))
+	require.Equal(t, "3000000000cet", changes[0].Before.Coins)
+	require.Equal(t, "2699999900cet", changes[0].After.Coins)
+}
@@ -33,10 +33,7 @@ func (app *CetChainApp) ExportAppStateAndValidators(forZeroHeight bool, jailWhit
 
 	genState := app.mm.ExportGenesis(ctx)
 	if forZeroHeight {
-		var ig incentive.GenesisState
-		incentive.ModuleCdc.MustUnmarshalJSON(genState[incentive.ModuleName], &ig)
-		ig.State.HeightAdjustment = ig.State.HeightAdjustment + ctx.BlockHeader().Height
-		genState[incentive.ModuleName] = incentive.ModuleCdc.MustMarshalJSON(ig)
+		genState[incentive.ModuleName] = adjustIncentiveHeight(genState[incentive.ModuleName], ctx.BlockHeader().Height)
 	}
 
 	appState, err = codec.MarshalJSONIndent(app.cdc, genState)
@@ -47,10 +44,10 @@ func (app *CetChainApp) ExportAppStateAndValidators(forZeroHeight bool, jailWhit
 	return appState, validators, nil
 }
 
-// prepare for fresh start at zero height
+// prepare for fresh start at zero height, and report what is changed
 // NOTE zero height genesis is a temporary feature which will be deprecated
 //      in favour of export at a block height
-func (app *CetChainApp) prepForZeroHeightGenesis(ctx sdk.Context, jailWhiteList []string) {
+func (app *CetChainApp) prepForZeroHeightGenesis(ctx sdk.Context, jailWhiteList []string) *ZeroHeightReport {
 	applyWhiteList := false
 
 	//Check if there is a whitelist
@@ -71,18 +68,27 @@ func (app *CetChainApp) prepForZeroHeightGenesis(ctx sdk.Context, jailWhiteList
 	/* Just to be safe, assert the invariants on current state. */
 	app.crisisKeeper.AssertInvariants(ctx)
 
+	report := &ZeroHeightReport{Height: ctx.BlockHeight()}
+
 	/* Handle fee distribution state. */
 
 	// withdraw all validator commission
 	app.stakingKeeper.IterateValidators(ctx, func(_ int64, val staking.ValidatorI) (stop bool) {
-		_, _ = app.distrKeeper.WithdrawValidatorCommission(ctx, val.GetOperator())
+		commission, _ := app.distrKeeper.WithdrawValidatorCommission(ctx, val.GetOperator())
+		if !commission.IsZero() {
+			report.Commissions = append(report.Commissions, WithdrawnCommission{val.GetOperator(), commission})
+		}
 		return false
 	})
 
 	// withdraw all delegator rewards
 	dels := app.stakingKeeper.GetAllDelegations(ctx)
 	for _, delegation := range dels {
-		_, _ = app.distrKeeper.WithdrawDelegationRewards(ctx, delegation.DelegatorAddress, delegation.ValidatorAddress)
+		rewards, _ := app.distrKeeper.WithdrawDelegationRewards(ctx, delegation.DelegatorAddress, delegation.ValidatorAddress)
+		if !rewards.IsZero() {
+			report.Rewards = append(report.Rewards,
+				WithdrawnRewards{delegation.DelegatorAddress, delegation.ValidatorAddress, rewards})
+		}
 	}
 
 	// clear validator slash events
@@ -103,6 +109,10 @@ func (app *CetChainApp) prepForZeroHeightGenesis(ctx sdk.Context, jailWhiteList
 		feePool := app.distrKeeper.GetFeePool(ctx)
 		feePool.CommunityPool = feePool.CommunityPool.Add(scraps)
 		app.distrKeeper.SetFeePool(ctx, feePool)
+		if !scraps.IsZero() {
+			report.Scraps = append(report.Scraps, DonatedScraps{val.GetOperator(), scraps})
+			report.TotalScraps = report.TotalScraps.Add(scraps)
+		}
 
 		app.distrKeeper.Hooks().AfterValidatorCreated(ctx, val.GetOperator())
 		return false
@@ -121,18 +131,32 @@ func (app *CetChainApp) prepForZeroHeightGenesis(ctx sdk.Context, jailWhiteList
 
 	// iterate through redelegations, reset creation height
 	app.stakingKeeper.IterateRedelegations(ctx, func(_ int64, red staking.Redelegation) (stop bool) {
+		reset := ResetRedelegation{red.DelegatorAddress, red.ValidatorSrcAddress, red.ValidatorDstAddress, nil}
+		changed := false
 		for i := range red.Entries {
+			reset.CreationHeights = append(reset.CreationHeights, red.Entries[i].CreationHeight)
+			changed = changed || red.Entries[i].CreationHeight != 0
 			red.Entries[i].CreationHeight = 0
 		}
+		if changed {
+			report.Redelegations = append(report.Redelegations, reset)
+		}
 		app.stakingKeeper.SetRedelegation(ctx, red)
 		return false
 	})
 
 	// iterate through unbonding delegations, reset creation height
 	app.stakingKeeper.IterateUnbondingDelegations(ctx, func(_ int64, ubd staking.UnbondingDelegation) (stop bool) {
+		reset := ResetUnbondingDelegation{ubd.DelegatorAddress, ubd.ValidatorAddress, nil}
+		changed := false
 		for i := range ubd.Entries {
+			reset.CreationHeights = append(reset.CreationHeights, ubd.Entries[i].CreationHeight)
+			changed = changed || ubd.Entries[i].CreationHeight != 0
 			ubd.Entries[i].CreationHeight = 0
 		}
+		if changed {
+			report.UnbondingDelegations = append(report.UnbondingDelegations, reset)
+		}
 		app.stakingKeeper.SetUnbondingDelegation(ctx, ubd)
 		return false
 	})
@@ -150,9 +174,15 @@ func (app *CetChainApp) prepForZeroHeightGenesis(ctx sdk.Context, jailWhiteList
 			panic("expected validator, not found")
 		}
 
+		if validator.UnbondingHeight != 0 {
+			report.UnbondingHeights = append(report.UnbondingHeights, ResetUnbondingHeight{addr, validator.UnbondingHeight})
+		}
 		validator.UnbondingHeight = 0
-		if applyWhiteList && !whiteListMap[addr.String()] {
+		if applyWhiteList && !whiteListMap[addr.String()] && !validator.Jailed {
+			// a jailed validator must not be in the power index
+			app.stakingKeeper.DeleteValidatorByPowerIndex(ctx, validator)
 			validator.Jailed = true
+			report.Jailed = append(report.Jailed, addr)
 		}
 
 		app.stakingKeeper.SetValidator(ctx, validator)
@@ -169,9 +199,13 @@ func (app *CetChainApp) prepForZeroHeightGenesis(ctx sdk.Context, jailWhiteList
 	app.slashingKeeper.IterateValidatorSigningInfos(
 		ctx,
 		func(addr sdk.ConsAddress, info slashing.ValidatorSigningInfo) (stop bool) {
+			if info.StartHeight != 0 {
+				report.SigningInfos = append(report.SigningInfos, ResetStartHeight{addr, info.StartHeight})
+			}
 			info.StartHeight = 0
 			app.slashingKeeper.SetValidatorSigningInfo(ctx, addr, info)
 			return false
 		},
 	)
+	return report
 }
@@ -0,0 +1,64 @@
+package app
+
+import (
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// ZeroHeightReport lists what is changed by an export for zero height at Height, for
+// the review of the new genesis file. The amounts are withdrawn to the accounts of the
+// delegators, and the scraps are donated to the community pool. Jailed are the validators
+// jailed as they are not in the whitelist. The other lists are the heights reset to zero,
+// the ones which are already zero are not listed.
+type ZeroHeightReport struct {
+	Height               int64                      `json:"height"`
+	Commissions          []WithdrawnCommission      `json:"commissions"`
+	Rewards              []WithdrawnRewards         `json:"rewards"`
+	Scraps               []DonatedScraps            `json:"scraps"`
+	TotalScraps          sdk.DecCoins               `json:"total_scraps"`
+	Jailed               []sdk.ValAddress           `json:"jailed"`
+	Redelegations        []ResetRedelegation        `json:"redelegations"`
+	UnbondingDelegations []ResetUnbondingDelegation `json:"unbonding_delegations"`
+	UnbondingHeights     []ResetUnbondingHeight     `json:"unbonding_heights"`
+	SigningInfos         []ResetStartHeight         `json:"signing_infos"`
+}
+
+type WithdrawnCommission struct {
+	Validator sdk.ValAddress `json:"validator"`
+	Amount    sdk.Coins      `json:"amount"`
+}
+
+type WithdrawnRewards struct {
+	Delegator sdk.AccAddress `json:"delegator"`
+	Validator sdk.ValAddress `json:"validator"`
+	Amount    sdk.Coins      `json:"amount"`
+}
+
+type DonatedScraps struct {
+	Validator sdk.ValAddress `json:"validator"`
+	Amount    sdk.DecCoins   `json:"amount"`
+}
+
+// ResetRedelegation has the creation heights of the entries before being reset
+type ResetRedelegation struct {
+	Delegator       sdk.AccAddress `json:"delegator"`
+	ValidatorSrc    sdk.ValAddress `json:"validator_src"`
+	ValidatorDst    sdk.ValAddress `json:"validator_dst"`
+	CreationHeights []int64        `json:"creation_heights"`
+}
+
+// ResetUnbondingDelegation has the creation heights of the entries before being reset
+type ResetUnbondingDelegation struct {
+	Delegator       sdk.AccAddress `json:"delegator"`
+	Validator       sdk.ValAddress `json:"validator"`
+	CreationHeights []int64        `json:"creation_heights"`
+}
+
+type ResetUnbondingHeight struct {
+	Validator       sdk.ValAddress `json:"validator"`
+	UnbondingHeight int64          `json:"unbonding_height"`
+}
+
+type ResetStartHeight struct {
+	Address     sdk.ConsAddress `json:"address"`
+	StartHeight int64           `json:"start_height"`
+}
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"io/ioutil"
 	"sort"
 	"testing"
 
@@ -8,6 +9,7 @@ import (
 
 	abci "github.com/tendermint/tendermint/abci/types"
 	"github.com/tendermint/tendermint/crypto"
+	tmtypes "github.com/tendermint/tendermint/types"
 
 	"github.com/cosmos/cosmos-sdk/codec"
 	sdk "github.com/cosmos/cosmos-sdk/types"
@@ -253,3 +255,109 @@ func initAppWithValidators(gs GenesisState) *CetChainApp {
 
 	return app
 }
+
+func TestZeroHeightReport(t *testing.T) {
+	amountVal := cetToken().GetTotalSupply().Int64() - 2e10
+	valKey, valAcc := testutil.NewBaseAccount(amountVal, 0, 0)
+	valAddr := sdk.ValAddress(valAcc.Address)
+	consAddr := valAcc.PubKey.Address()
+	delKey, delAcc := testutil.NewBaseAccount(2e10, 1, 0)
+	app := initApp(func(genState *GenesisState) {
+		addGenesisAccounts(genState, valAcc, delAcc)
+		genState.StakingXData.Params.MinSelfDelegation = 1
+	})
+
+	// a validator with a delegator, who undelegates at height 2
+	votes := []abci.VoteInfo{{Validator: abci.Validator{Address: consAddr, Power: 1}, SignedLastBlock: true}}
+	for height := int64(1); height <= 3; height++ {
+		header := abci.Header{Height: height, ChainID: testChainID}
+		var commitInfo abci.LastCommitInfo
+		if height > 1 {
+			header.ProposerAddress = consAddr
+			commitInfo.Votes = votes
+		}
+		app.BeginBlock(abci.RequestBeginBlock{Header: header, LastCommitInfo: commitInfo})
+		switch height {
+		case 1:
+			createValMsg := testutil.NewMsgCreateValidatorBuilder(valAddr, valAcc.PubKey).
+				MinSelfDelegation(1).SelfDelegation(1e10).Commission("0.1", "0.1", "0.01").Build()
+			result := app.Deliver(newStdTxBuilder().
+				Msgs(createValMsg).GasAndFee(1000000, 100).AccNumSeqKey(0, 0, valKey).Build())
+			require.Equal(t, sdk.CodeOK, result.Code)
+			delMsg := staking.NewMsgDelegate(delAcc.Address, valAddr, dex.NewCetCoin(1e10))
+			result = app.Deliver(newStdTxBuilder().
+				Msgs(delMsg).GasAndFee(1000000, 100).AccNumSeqKey(1, 0, delKey).Build())
+			require.Equal(t, sdk.CodeOK, result.Code)
+		case 2:
+			undelMsg := staking.NewMsgUndelegate(delAcc.Address, valAddr, dex.NewCetCoin(1e9))
+			result := app.Deliver(newStdTxBuilder().
+				Msgs(undelMsg).GasAndFee(1000000, 100).AccNumSeqKey(1, 1, delKey).Build())
+			require.Equal(t, sdk.CodeOK, result.Code)
+		}
+		app.EndBlock(abci.RequestEndBlock{Height: height})
+		app.Commit()
+	}
+
+	// the validator is in the whitelist
+	_, report, err := app.WriteAppState(ioutil.Discard, true, []string{valAddr.String()})
+	require.Nil(t, err)
+	require.Equal(t, int64(3), report.Height)
+	require.Equal(t, 1, len(report.Commissions))
+	require.Equal(t, valAddr, report.Commissions[0].Validator)
+	require.True(t, report.Commissions[0].Amount.IsAllPositive())
+	require.Equal(t, 2, len(report.Rewards))
+	require.Empty(t, report.Jailed)
+	require.Equal(t, []ResetUnbondingDelegation{{delAcc.Address, valAddr, []int64{2}}}, report.UnbondingDelegations)
+	require.Empty(t, report.Redelegations)
+
+	// the export is made on the check state, where the heights have been reset
+	_, report, err = app.WriteAppState(ioutil.Discard, true, []string{valAddr.String()})
+	require.Nil(t, err)
+	require.Empty(t, report.UnbondingDelegations)
+	require.Empty(t, report.UnbondingHeights)
+	require.Empty(t, report.SigningInfos)
+
+	_, report, err = app.WriteAppState(ioutil.Discard, false, nil)
+	require.Nil(t, err)
+	require.Nil(t, report)
+}
+
+// jailing a validator which is still in the power index made the export panic
+func TestZeroHeightExportJailsValidatorsNotInWhitelist(t *testing.T) {
+	amountVal := cetToken().GetTotalSupply().Int64() - 2e10
+	valKey, valAcc := testutil.NewBaseAccount(amountVal, 0, 0)
+	valAddr := sdk.ValAddress(valAcc.Address)
+	_, otherAcc := testutil.NewBaseAccount(2e10, 1, 0)
+	app := initApp(func(genState *GenesisState) {
+		addGenesisAccounts(genState, valAcc, otherAcc)
+		genState.StakingXData.Params.MinSelfDelegation = 1
+	})
+
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: 1, ChainID: testChainID}})
+	createValMsg := testutil.NewMsgCreateValidatorBuilder(valAddr, valAcc.PubKey).
+		MinSelfDelegation(1).SelfDelegation(1e10).Commission("0.1", "0.1", "0.01").Build()
+	result := app.Deliver(newStdTxBuilder().
+		Msgs(createValMsg).GasAndFee(1000000, 100).AccNumSeqKey(0, 0, valKey).Build())
+	require.Equal(t, sdk.CodeOK, result.Code)
+	app.EndBlock(abci.RequestEndBlock{Height: 1})
+	app.Commit()
+
+	otherVal := sdk.ValAddress(otherAcc.Address).String()
+	var report *ZeroHeightReport
+	var validators []tmtypes.GenesisValidator
+	var err error
+	require.NotPanics(t, func() {
+		validators, report, err = app.WriteAppState(ioutil.Discard, true, []string{otherVal})
+	})
+	require.Nil(t, err)
+	require.Equal(t, []sdk.ValAddress{valAddr}, report.Jailed)
+	require.Empty(t, validators)
+
+	// the export is made on the check state, where the validator has been jailed
+	require.NotPanics(t, func() {
+		validators, report, err = app.WriteAppState(ioutil.Discard, true, []string{otherVal})
+	})
+	require.Nil(t, err)
+	require.Empty(t, report.Jailed)
+	require.Empty(t, validators)
+}
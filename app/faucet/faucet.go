@@ -0,0 +1,224 @@
+// Package faucet dispenses the coins of a testnet over HTTP. A claim sends the coins of one
+// denom from the key of the faucet to an address, and the claims are limited per address
+// and per IP.
+//
+//	POST /claim   {"address": "coinex1...", "denom": "cet"}
+//	GET  /status
+package faucet
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/rest"
+
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+// Config is what the faucet dispenses and how often
+type Config struct {
+	// the coins of every denom which can be claimed, one denom per claim
+	Amounts      sdk.Coins
+	AddressLimit Limit
+	IPLimit      Limit
+	// the number of proxies in front of the faucet, each of which appends the address it
+	// is connected from to X-Forwarded-For, zero if the clients connect to the faucet directly
+	TrustedProxies int
+}
+
+// Sender sends the coins of the faucet
+type Sender interface {
+	Address() sdk.AccAddress
+	Send(to sdk.AccAddress, amount sdk.Coins) (txHash string, err error)
+	Balance() (sdk.Coins, error)
+}
+
+type Faucet struct {
+	config Config
+	sender Sender
+	now    func() time.Time
+
+	// the claims are sent one by one, as the txs of a key are ordered by their sequences
+	sendMtx sync.Mutex
+	// mtx guards the limiters and the count, it is not held while a claim is sent, so the
+	// other requests are not blocked by a slow broadcast
+	mtx          sync.Mutex
+	addrLimiter  *limiter
+	ipLimiter    *limiter
+	claimedTimes int
+}
+
+// ClaimRequest is the body of POST /claim, the denom is cet if empty
+type ClaimRequest struct {
+	Address string `json:"address"`
+	Denom   string `json:"denom"`
+}
+
+type ClaimResponse struct {
+	TxHash string    `json:"tx_hash"`
+	Amount sdk.Coins `json:"amount"`
+}
+
+// RateLimitedResponse is returned with 429 Too Many Requests
+type RateLimitedResponse struct {
+	Error      string `json:"error"`
+	RetryAfter int64  `json:"retry_after"` // in seconds
+}
+
+type StatusResponse struct {
+	Address sdk.AccAddress `json:"address"`
+	Balance sdk.Coins      `json:"balance"`
+	Amounts sdk.Coins      `json:"amounts"`
+	Claims  int            `json:"claims"`
+}
+
+func New(config Config, sender Sender) *Faucet {
+	return &Faucet{
+		config:      config,
+		sender:      sender,
+		now:         time.Now,
+		addrLimiter: newLimiter(config.AddressLimit),
+		ipLimiter:   newLimiter(config.IPLimit),
+	}
+}
+
+// Handler serves the JSON API of the faucet
+func (f *Faucet) Handler() http.Handler {
+	r := mux.NewRouter()
+	r.HandleFunc("/claim", f.claimHandler).Methods("POST")
+	r.HandleFunc("/status", f.statusHandler).Methods("GET")
+	return r
+}
+
+func (f *Faucet) claimHandler(w http.ResponseWriter, r *http.Request) {
+	var req ClaimRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	addr, err := sdk.AccAddressFromBech32(strings.TrimSpace(req.Address))
+	if err != nil {
+		rest.WriteErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("invalid address: %s", err.Error()))
+		return
+	}
+	denom := req.Denom
+	if denom == "" {
+		denom = dex.CET
+	}
+	amount := f.config.Amounts.AmountOf(denom)
+	if !amount.IsPositive() {
+		rest.WriteErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("%s is not dispensed", denom))
+		return
+	}
+	coins := sdk.NewCoins(sdk.NewCoin(denom, amount))
+
+	res, wait, err := f.claim(addr, f.clientIP(r), coins)
+	if wait > 0 {
+		w.Header().Set("Retry-After", fmt.Sprintf("%d", retryAfterSeconds(wait)))
+		writeJSON(w, http.StatusTooManyRequests, RateLimitedResponse{Error: err.Error(), RetryAfter: retryAfterSeconds(wait)})
+		return
+	}
+	if err != nil {
+		rest.WriteErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// claim sends coins to addr if neither addr nor ip is limited. The claim is reserved in the
+// limiters before the tx is sent, so the concurrent claims of addr or ip are limited too, and
+// it is released if the tx is not accepted.
+func (f *Faucet) claim(addr sdk.AccAddress, ip string, coins sdk.Coins) (*ClaimResponse, time.Duration, error) {
+	now, wait, err := f.reserve(addr.String(), ip)
+	if wait > 0 {
+		return nil, wait, err
+	}
+
+	f.sendMtx.Lock()
+	txHash, err := f.sender.Send(addr, coins)
+	f.sendMtx.Unlock()
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if err != nil {
+		f.addrLimiter.remove(addr.String(), now)
+		f.ipLimiter.remove(ip, now)
+		return nil, 0, err
+	}
+	f.claimedTimes++
+	return &ClaimResponse{TxHash: txHash, Amount: coins}, 0, nil
+}
+
+// reserve adds a claim of addr and ip at now to the limiters, unless one of them is limited
+func (f *Faucet) reserve(addr, ip string) (now time.Time, wait time.Duration, err error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	now = f.now()
+	if wait := f.addrLimiter.check(addr, now); wait > 0 {
+		return now, wait, fmt.Errorf("%s has claimed too often", addr)
+	}
+	if wait := f.ipLimiter.check(ip, now); wait > 0 {
+		return now, wait, fmt.Errorf("%s has claimed too often", ip)
+	}
+	f.addrLimiter.add(addr, now)
+	f.ipLimiter.add(ip, now)
+	return now, 0, nil
+}
+
+func (f *Faucet) statusHandler(w http.ResponseWriter, r *http.Request) {
+	balance, err := f.sender.Balance()
+	if err != nil {
+		rest.WriteErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	f.mtx.Lock()
+	claims := f.claimedTimes
+	f.mtx.Unlock()
+	writeJSON(w, http.StatusOK, StatusResponse{
+		Address: f.sender.Address(),
+		Balance: balance,
+		Amounts: f.config.Amounts,
+		Claims:  claims,
+	})
+}
+
+// clientIP is the IP of the remote address, or the one appended to X-Forwarded-For by the
+// outermost trusted proxy. The entries before it are sent by the client, and can be spoofed.
+// If there are fewer entries than the trusted proxies, the request has not passed all of them,
+// and all the entries may be sent by the client, so the remote address is used.
+func (f *Faucet) clientIP(r *http.Request) string {
+	if n := f.config.TrustedProxies; n > 0 {
+		var ips []string
+		for _, h := range r.Header["X-Forwarded-For"] {
+			ips = append(ips, strings.Split(h, ",")...)
+		}
+		if len(ips) >= n {
+			return strings.TrimSpace(ips[len(ips)-n])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func retryAfterSeconds(wait time.Duration) int64 {
+	return int64(math.Ceil(wait.Seconds()))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,233 @@
+package faucet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cosmos/cosmos-sdk/client/keys"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+	"github.com/coinexchain/dex/app"
+	"github.com/coinexchain/dex/app/testnet/network"
+)
+
+func TestMain(m *testing.M) {
+	dex.InitSdkConfig()
+	os.Exit(m.Run())
+}
+
+type fakeSender struct {
+	addr sdk.AccAddress
+	sent map[string]sdk.Coins
+	fail bool
+}
+
+func (s *fakeSender) Address() sdk.AccAddress { return s.addr }
+
+func (s *fakeSender) Send(to sdk.AccAddress, amount sdk.Coins) (string, error) {
+	if s.fail {
+		return "", errors.New("insufficient coins")
+	}
+	s.sent[to.String()] = s.sent[to.String()].Add(amount)
+	return fmt.Sprintf("TX%d", len(s.sent)), nil
+}
+
+func (s *fakeSender) Balance() (sdk.Coins, error) {
+	return dex.NewCetCoins(1e18), nil
+}
+
+func claim(h http.Handler, ip, body string) (int, []byte) {
+	req := httptest.NewRequest("POST", "/claim", bytes.NewBufferString(body))
+	req.RemoteAddr = ip + ":1234"
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w.Code, w.Body.Bytes()
+}
+
+func claimBody(addr sdk.AccAddress, denom string) string {
+	return fmt.Sprintf(`{"address":"%s","denom":"%s"}`, addr, denom)
+}
+
+func TestClaim(t *testing.T) {
+	_, _, faucetAddr := testutil.KeyPubAddr()
+	_, _, alice := testutil.KeyPubAddr()
+	_, _, bob := testutil.KeyPubAddr()
+	_, _, carol := testutil.KeyPubAddr()
+	sender := &fakeSender{addr: faucetAddr, sent: make(map[string]sdk.Coins)}
+	amounts := sdk.NewCoins(sdk.NewInt64Coin("abc", 500), dex.NewCetCoin(100))
+	f := New(Config{
+		Amounts:      amounts,
+		AddressLimit: Limit{Max: 1, Window: time.Hour},
+		IPLimit:      Limit{Max: 2, Window: time.Hour},
+	}, sender)
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	f.now = func() time.Time { return now }
+	h := f.Handler()
+
+	code, bz := claim(h, "1.1.1.1", claimBody(alice, ""))
+	require.Equal(t, http.StatusOK, code, string(bz))
+	var res ClaimResponse
+	require.Nil(t, json.Unmarshal(bz, &res))
+	require.Equal(t, "TX1", res.TxHash)
+	require.Equal(t, dex.NewCetCoins(100), res.Amount)
+
+	// one claim per address, but each denom can be claimed
+	code, bz = claim(h, "2.2.2.2", claimBody(alice, "cet"))
+	require.Equal(t, http.StatusTooManyRequests, code)
+	var limited RateLimitedResponse
+	require.Nil(t, json.Unmarshal(bz, &limited))
+	require.Equal(t, int64(3600), limited.RetryAfter)
+	code, _ = claim(h, "2.2.2.2", claimBody(alice, "xyz"))
+	require.Equal(t, http.StatusBadRequest, code)
+	code, _ = claim(h, "2.2.2.2", `{"address":"coinex1xxx"}`)
+	require.Equal(t, http.StatusBadRequest, code)
+
+	// two claims per IP
+	code, _ = claim(h, "1.1.1.1", claimBody(bob, "abc"))
+	require.Equal(t, http.StatusOK, code)
+	code, _ = claim(h, "1.1.1.1", claimBody(carol, "cet"))
+	require.Equal(t, http.StatusTooManyRequests, code)
+	require.Equal(t, sdk.NewCoins(sdk.NewInt64Coin("abc", 500)), sender.sent[bob.String()])
+
+	// a failed send is not counted
+	sender.fail = true
+	code, _ = claim(h, "3.3.3.3", claimBody(carol, "cet"))
+	require.Equal(t, http.StatusInternalServerError, code)
+	sender.fail = false
+	code, _ = claim(h, "3.3.3.3", claimBody(carol, "cet"))
+	require.Equal(t, http.StatusOK, code)
+
+	now = now.Add(time.Hour)
+	code, _ = claim(h, "1.1.1.1", claimBody(alice, "cet"))
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, dex.NewCetCoins(200), sender.sent[alice.String()])
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/status", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var status StatusResponse
+	require.Nil(t, json.Unmarshal(w.Body.Bytes(), &status))
+	require.Equal(t, faucetAddr, status.Address)
+	require.Equal(t, amounts, status.Amounts)
+	require.Equal(t, 4, status.Claims)
+}
+
+func TestClientIP(t *testing.T) {
+	req := httptest.NewRequest("POST", "/claim", nil)
+	req.RemoteAddr = "1.1.1.1:1234"
+	require.Equal(t, "1.1.1.1", New(Config{TrustedProxies: 1}, nil).clientIP(req))
+
+	// the client sends a spoofed X-Forwarded-For, and the proxies append 3.3.3.3 and 4.4.4.4
+	req.Header.Set("X-Forwarded-For", "2.2.2.2, 3.3.3.3")
+	req.Header.Add("X-Forwarded-For", "4.4.4.4")
+	require.Equal(t, "1.1.1.1", New(Config{}, nil).clientIP(req))
+	require.Equal(t, "4.4.4.4", New(Config{TrustedProxies: 1}, nil).clientIP(req))
+	require.Equal(t, "3.3.3.3", New(Config{TrustedProxies: 2}, nil).clientIP(req))
+
+	// the request has not passed the proxies, the header is sent by the client
+	req.Header.Set("X-Forwarded-For", "3.3.3.3")
+	require.Equal(t, "1.1.1.1", New(Config{TrustedProxies: 2}, nil).clientIP(req))
+}
+
+// blockingSender sends when it is released
+type blockingSender struct {
+	fakeSender
+	sending chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingSender) Send(to sdk.AccAddress, amount sdk.Coins) (string, error) {
+	s.sending <- struct{}{}
+	<-s.release
+	return s.fakeSender.Send(to, amount)
+}
+
+func TestClaimWhileSending(t *testing.T) {
+	_, _, faucetAddr := testutil.KeyPubAddr()
+	_, _, alice := testutil.KeyPubAddr()
+	sender := &blockingSender{
+		fakeSender: fakeSender{addr: faucetAddr, sent: make(map[string]sdk.Coins)},
+		sending:    make(chan struct{}),
+		release:    make(chan struct{}),
+	}
+	f := New(Config{
+		Amounts:      dex.NewCetCoins(100),
+		AddressLimit: Limit{Max: 1, Window: time.Hour},
+	}, sender)
+	h := f.Handler()
+
+	done := make(chan int)
+	go func() {
+		code, _ := claim(h, "1.1.1.1", claimBody(alice, ""))
+		done <- code
+	}()
+	<-sender.sending
+
+	// the claim is reserved, and the other requests are not blocked by the broadcast
+	code, _ := claim(h, "2.2.2.2", claimBody(alice, ""))
+	require.Equal(t, http.StatusTooManyRequests, code)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/status", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	close(sender.release)
+	require.Equal(t, http.StatusOK, <-done)
+	require.Equal(t, dex.NewCetCoins(100), sender.sent[alice.String()])
+}
+
+func TestTxSender(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the in-process network in short mode")
+	}
+	dir, err := ioutil.TempDir("", "faucet")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	n, err := network.NewNetwork(dir, network.DefaultNetworkConfig())
+	defer n.Cleanup()
+	require.Nil(t, err)
+	require.Nil(t, n.WaitForHeight(2, 30*time.Second))
+
+	val := n.Nodes[0]
+	kb, err := keys.NewKeyBaseFromDir(val.CLIHome)
+	require.Nil(t, err)
+	gasPrices, err := sdk.ParseDecCoins(network.DefaultNetworkConfig().MinGasPrices)
+	require.Nil(t, err)
+	txBldr := auth.NewTxBuilder(auth.DefaultTxEncoder(n.Cdc), 0, 0, 200000, 0,
+		false, n.ChainID, "", nil, gasPrices).WithKeybase(kb)
+	sender, err := NewTxSender(n.CLIContext, txBldr, val.KeyName, app.DefaultKeyPass)
+	require.Nil(t, err)
+	require.Equal(t, val.Address, sender.Address())
+
+	srv := httptest.NewServer(New(Config{Amounts: dex.NewCetCoins(1e9)}, sender).Handler())
+	defer srv.Close()
+	_, _, alice := testutil.KeyPubAddr()
+	_, _, bob := testutil.KeyPubAddr()
+	for _, addr := range []sdk.AccAddress{alice, bob} {
+		resp, err := http.Post(srv.URL+"/claim", "application/json", bytes.NewBufferString(claimBody(addr, "")))
+		require.Nil(t, err)
+		bz, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.Nil(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode, string(bz))
+	}
+
+	// the activation fee of a new account is 1 CET
+	for _, addr := range []sdk.AccAddress{alice, bob} {
+		acc, err := auth.NewAccountRetriever(n.CLIContext).GetAccount(addr)
+		require.Nil(t, err)
+		require.Equal(t, dex.NewCetCoins(9e8), acc.GetCoins())
+	}
+}
@@ -0,0 +1,86 @@
+package faucet
+
+import (
+	"time"
+)
+
+// Limit allows at most Max claims in any Window, there is no limit if Max is zero
+type Limit struct {
+	Max    int
+	Window time.Duration
+}
+
+// limiter keeps the times of the recent claims of every key, such as an address or an IP
+type limiter struct {
+	limit     Limit
+	hits      map[string][]time.Time
+	lastSweep time.Time
+}
+
+func newLimiter(limit Limit) *limiter {
+	return &limiter{limit: limit, hits: make(map[string][]time.Time)}
+}
+
+// check returns how long the key has to wait for the next claim, zero if it can claim now
+func (l *limiter) check(key string, now time.Time) time.Duration {
+	if l.limit.Max <= 0 {
+		return 0
+	}
+	hits := l.recent(key, now)
+	if len(hits) < l.limit.Max {
+		return 0
+	}
+	return hits[len(hits)-l.limit.Max].Add(l.limit.Window).Sub(now)
+}
+
+// add records a claim of key at now
+func (l *limiter) add(key string, now time.Time) {
+	if l.limit.Max <= 0 {
+		return
+	}
+	l.hits[key] = append(l.recent(key, now), now)
+	if now.Sub(l.lastSweep) >= l.limit.Window {
+		l.sweep(now)
+	}
+}
+
+// remove drops the claim of key added at t, which is not accepted
+func (l *limiter) remove(key string, t time.Time) {
+	hits := l.hits[key]
+	for i := len(hits) - 1; i >= 0; i-- {
+		if hits[i].Equal(t) {
+			hits = append(hits[:i], hits[i+1:]...)
+			break
+		}
+	}
+	if len(hits) == 0 {
+		delete(l.hits, key)
+	} else {
+		l.hits[key] = hits
+	}
+}
+
+// sweep drops the keys which have no claims in the window before now, so that the keys which
+// never claim again are not kept forever
+func (l *limiter) sweep(now time.Time) {
+	for key := range l.hits {
+		l.recent(key, now)
+	}
+	l.lastSweep = now
+}
+
+// recent drops the claims of key out of the window before now
+func (l *limiter) recent(key string, now time.Time) []time.Time {
+	hits := l.hits[key]
+	i := 0
+	for i < len(hits) && !hits[i].Add(l.limit.Window).After(now) {
+		i++
+	}
+	if i == len(hits) {
+		delete(l.hits, key)
+		return nil
+	}
+	hits = hits[i:]
+	l.hits[key] = hits
+	return hits
+}
@@ -0,0 +1,60 @@
+package faucet
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter(t *testing.T) {
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := newLimiter(Limit{Max: 2, Window: time.Hour})
+	require.Equal(t, time.Duration(0), l.check("a", t0))
+	l.add("a", t0)
+	l.add("a", t0.Add(10*time.Minute))
+	require.Equal(t, time.Hour-time.Minute, l.check("a", t0.Add(time.Minute)))
+	require.Equal(t, time.Duration(0), l.check("b", t0.Add(time.Minute)))
+
+	// the first claim is out of the window
+	require.Equal(t, time.Duration(0), l.check("a", t0.Add(time.Hour)))
+	l.add("a", t0.Add(time.Hour))
+	require.Equal(t, 10*time.Minute, l.check("a", t0.Add(time.Hour)))
+	require.Nil(t, l.recent("a", t0.Add(3*time.Hour)))
+	require.Empty(t, l.hits)
+
+	// the keys out of the window are swept once a window
+	l.add("b", t0.Add(4*time.Hour))
+	l.add("c", t0.Add(4*time.Hour+time.Minute))
+	require.Equal(t, 2, len(l.hits))
+	l.add("d", t0.Add(5*time.Hour))
+	require.Equal(t, []string{"c", "d"}, hitKeys(l))
+	l.add("e", t0.Add(5*time.Hour+2*time.Minute))
+	require.Equal(t, []string{"c", "d", "e"}, hitKeys(l))
+	l.add("e", t0.Add(6*time.Hour))
+	require.Equal(t, []string{"e"}, hitKeys(l))
+
+	// a claim which is not accepted is removed
+	l.add("e", t0.Add(6*time.Hour+time.Minute))
+	l.remove("e", t0.Add(6*time.Hour+time.Minute))
+	require.Equal(t, []time.Time{t0.Add(5*time.Hour + 2*time.Minute), t0.Add(6 * time.Hour)}, l.hits["e"])
+	l.remove("e", t0.Add(5*time.Hour+2*time.Minute))
+	l.remove("e", t0.Add(6*time.Hour))
+	require.Empty(t, l.hits)
+
+	unlimited := newLimiter(Limit{})
+	for i := 0; i < 10; i++ {
+		unlimited.add("a", t0)
+	}
+	require.Equal(t, time.Duration(0), unlimited.check("a", t0))
+}
+
+func hitKeys(l *limiter) []string {
+	var keys []string
+	for key := range l.hits {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
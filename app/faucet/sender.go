@@ -0,0 +1,81 @@
+package faucet
+
+import (
+	"fmt"
+
+	"github.com/cosmos/cosmos-sdk/client/context"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+
+	"github.com/coinexchain/cet-sdk/modules/bankx"
+)
+
+// TxSender signs a bankx.MsgSend for every claim with a key in the keybase of txBldr,
+// and broadcasts it with the mode of cliCtx
+type TxSender struct {
+	cliCtx     context.CLIContext
+	txBldr     auth.TxBuilder
+	name       string
+	passphrase string
+
+	// the sequence is counted locally, so the claims in the same block do not conflict,
+	// it is queried again after a failed tx
+	synced bool
+	accNum uint64
+	seq    uint64
+}
+
+var _ Sender = (*TxSender)(nil)
+
+// NewTxSender returns a sender of the key name, txBldr must have the keybase of the key
+func NewTxSender(cliCtx context.CLIContext, txBldr auth.TxBuilder, name, passphrase string) (*TxSender, error) {
+	info, err := txBldr.Keybase().Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return &TxSender{
+		cliCtx:     cliCtx.WithFromAddress(info.GetAddress()).WithFromName(name),
+		txBldr:     txBldr,
+		name:       name,
+		passphrase: passphrase,
+	}, nil
+}
+
+func (s *TxSender) Address() sdk.AccAddress {
+	return s.cliCtx.GetFromAddress()
+}
+
+func (s *TxSender) Send(to sdk.AccAddress, amount sdk.Coins) (string, error) {
+	if !s.synced {
+		accNum, seq, err := auth.NewAccountRetriever(s.cliCtx).GetAccountNumberSequence(s.Address())
+		if err != nil {
+			return "", err
+		}
+		s.accNum, s.seq, s.synced = accNum, seq, true
+	}
+
+	msg := bankx.NewMsgSend(s.Address(), to, amount, 0)
+	txBytes, err := s.txBldr.WithAccountNumber(s.accNum).WithSequence(s.seq).
+		BuildAndSign(s.name, s.passphrase, []sdk.Msg{msg})
+	if err != nil {
+		return "", err
+	}
+	res, err := s.cliCtx.BroadcastTx(txBytes)
+	if err == nil && res.Code != uint32(sdk.CodeOK) {
+		err = fmt.Errorf("tx %s failed with code %d: %s", res.TxHash, res.Code, res.RawLog)
+	}
+	if err != nil {
+		s.synced = false
+		return "", err
+	}
+	s.seq++
+	return res.TxHash, nil
+}
+
+func (s *TxSender) Balance() (sdk.Coins, error) {
+	acc, err := auth.NewAccountRetriever(s.cliCtx).GetAccount(s.Address())
+	if err != nil {
+		return nil, err
+	}
+	return acc.GetCoins(), nil
+}
@@ -0,0 +1,142 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/libs/log"
+	tmtypes "github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/staking"
+
+	"github.com/coinexchain/cet-sdk/modules/asset"
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+// ForkParams is how a chain is forked from an exported state. The validator set of the
+// fork is a single validator with ConsPubKey, which is operated by Operator.
+type ForkParams struct {
+	ChainID     string
+	GenesisTime time.Time
+	ConsPubKey  crypto.PubKey
+	Moniker     string
+	Operator    sdk.AccAddress
+	// the self delegation of the validator, the min self delegation of stakingx if zero
+	Stake int64
+	// the cet in the account of the operator to pay the fees
+	Coins int64
+}
+
+// ForkReport lists what is changed by a fork. The cet of the operator is minted, and the
+// validators of the exported state are jailed and begin unbonding at the genesis of the fork.
+type ForkReport struct {
+	ChainID   string           `json:"chain_id"`
+	Validator sdk.ValAddress   `json:"validator"`
+	Power     int64            `json:"power"`
+	Minted    sdk.Coins        `json:"minted"`
+	Jailed    []sdk.ValAddress `json:"jailed"`
+}
+
+// ForkAppState loads appState, which is exported by 'cetd export', into an app on a memDB,
+// replaces its validators by the one of params, and returns the state of the fork with its
+// validator. The staking power, the distribution records and the signing info of the new
+// validator are derived by the keepers as if it were created by a tx.
+func ForkAppState(appState json.RawMessage, params ForkParams) (
+	forkState json.RawMessage, validators []tmtypes.GenesisValidator, report *ForkReport, err error) {
+
+	app := NewCetChainApp(log.NewNopLogger(), dbm.NewMemDB(), nil, true, 0)
+	header := abci.Header{ChainID: params.ChainID, Time: params.GenesisTime}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	app.InitChain(abci.RequestInitChain{ChainId: params.ChainID, Time: params.GenesisTime, AppStateBytes: appState})
+
+	ctx := app.NewContext(false, header)
+	if report, err = app.prepForFork(ctx, params); err != nil {
+		return nil, nil, nil, err
+	}
+	forkState, err = codec.MarshalJSONIndent(app.cdc, app.mm.ExportGenesis(ctx))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return forkState, staking.WriteValidators(ctx, app.stakingKeeper), report, nil
+}
+
+func (app *CetChainApp) prepForFork(ctx sdk.Context, params ForkParams) (*ForkReport, error) {
+	report := &ForkReport{ChainID: params.ChainID, Validator: sdk.ValAddress(params.Operator)}
+
+	// jail all the validators, they begin unbonding when the validator set is updated
+	for _, val := range app.stakingKeeper.GetAllValidators(ctx) {
+		if val.Jailed {
+			continue
+		}
+		app.stakingKeeper.Jail(ctx, val.GetConsAddr())
+		report.Jailed = append(report.Jailed, val.OperatorAddress)
+	}
+
+	minSelfDelegation := sdk.NewInt(app.stakingXKeeper.GetParams(ctx).MinSelfDelegation)
+	stake := sdk.NewInt(params.Stake)
+	if params.Stake == 0 {
+		stake = minSelfDelegation
+	}
+	minted := stake.Add(sdk.NewInt(params.Coins))
+	report.Minted = sdk.NewCoins(sdk.NewCoin(dex.CET, minted))
+	if err := app.mintCET(ctx, params.Operator, minted); err != nil {
+		return nil, err
+	}
+
+	msg := staking.NewMsgCreateValidator(sdk.ValAddress(params.Operator), params.ConsPubKey,
+		sdk.NewCoin(dex.DefaultBondDenom, stake), staking.NewDescription(params.Moniker, "", "", ""),
+		staking.NewCommissionRates(sdk.NewDecWithPrec(1, 1), sdk.NewDecWithPrec(2, 1), sdk.NewDecWithPrec(1, 2)),
+		minSelfDelegation)
+	if err := msg.ValidateBasic(); err != nil {
+		return nil, err
+	}
+	if res := staking.NewHandler(app.stakingKeeper)(ctx, msg); !res.IsOK() {
+		return nil, fmt.Errorf("create validator: %s", res.Log)
+	}
+
+	updates := app.stakingKeeper.ApplyAndReturnValidatorSetUpdates(ctx)
+	for _, update := range updates {
+		if update.Power > 0 {
+			report.Power = update.Power
+		}
+	}
+	if report.Power == 0 {
+		return nil, fmt.Errorf("the stake %s is too small to have power", stake)
+	}
+
+	app.crisisKeeper.AssertInvariants(ctx)
+	return report, nil
+}
+
+// mintCET adds amount to the supply of cet and sends it to addr, the total mint of cet,
+// which is not mintable, is kept zero for the validation of the genesis
+func (app *CetChainApp) mintCET(ctx sdk.Context, addr sdk.AccAddress, amount sdk.Int) error {
+	token := app.assetKeeper.GetToken(ctx, dex.CET)
+	if token == nil {
+		return fmt.Errorf("no cet token in the state")
+	}
+	if err := token.SetTotalSupply(token.GetTotalSupply().Add(amount)); err != nil {
+		return err
+	}
+	if err := app.assetKeeper.SetToken(ctx, token); err != nil {
+		return err
+	}
+	coins := sdk.NewCoins(sdk.NewCoin(dex.CET, amount))
+	if err := app.supplyKeeper.MintCoins(ctx, asset.ModuleName, coins); err != nil {
+		return err
+	}
+	if err := app.supplyKeeper.SendCoinsFromModuleToAccount(ctx, asset.ModuleName, addr, coins); err != nil {
+		return err
+	}
+	return nil
+}
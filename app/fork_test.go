@@ -0,0 +1,113 @@
+package app
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/staking"
+
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+func TestForkAppState(t *testing.T) {
+	amountVal := cetToken().GetTotalSupply().Int64() - 2e10
+	valKey, valAcc := testutil.NewBaseAccount(amountVal, 0, 0)
+	valAddr := sdk.ValAddress(valAcc.Address)
+	consAddr := valAcc.PubKey.Address()
+	delKey, delAcc := testutil.NewBaseAccount(2e10, 1, 0)
+	app := initApp(func(genState *GenesisState) {
+		addGenesisAccounts(genState, valAcc, delAcc)
+		genState.StakingXData.Params.MinSelfDelegation = 1
+	})
+
+	// a validator with a delegator
+	votes := []abci.VoteInfo{{Validator: abci.Validator{Address: consAddr, Power: 1}, SignedLastBlock: true}}
+	for height := int64(1); height <= 2; height++ {
+		header := abci.Header{Height: height, ChainID: testChainID}
+		var commitInfo abci.LastCommitInfo
+		if height > 1 {
+			header.ProposerAddress = consAddr
+			commitInfo.Votes = votes
+		}
+		app.BeginBlock(abci.RequestBeginBlock{Header: header, LastCommitInfo: commitInfo})
+		if height == 1 {
+			createValMsg := testutil.NewMsgCreateValidatorBuilder(valAddr, valAcc.PubKey).
+				MinSelfDelegation(1).SelfDelegation(1e10).Commission("0.1", "0.1", "0.01").Build()
+			result := app.Deliver(newStdTxBuilder().
+				Msgs(createValMsg).GasAndFee(1000000, 100).AccNumSeqKey(0, 0, valKey).Build())
+			require.Equal(t, sdk.CodeOK, result.Code)
+			delMsg := staking.NewMsgDelegate(delAcc.Address, valAddr, dex.NewCetCoin(1e10))
+			result = app.Deliver(newStdTxBuilder().
+				Msgs(delMsg).GasAndFee(1000000, 100).AccNumSeqKey(1, 0, delKey).Build())
+			require.Equal(t, sdk.CodeOK, result.Code)
+		}
+		app.EndBlock(abci.RequestEndBlock{Height: height})
+		app.Commit()
+	}
+	var exported bytes.Buffer
+	_, _, err := app.WriteAppState(&exported, true, nil)
+	require.Nil(t, err)
+
+	// the fork is run by a local validator
+	consKey := ed25519.GenPrivKey()
+	_, _, operator := testutil.KeyPubAddr()
+	genesisTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	forkState, validators, report, err := ForkAppState(exported.Bytes(), ForkParams{
+		ChainID:     "fork",
+		GenesisTime: genesisTime,
+		ConsPubKey:  consKey.PubKey(),
+		Moniker:     "local",
+		Operator:    operator,
+		Stake:       3e10,
+		Coins:       1e9,
+	})
+	require.Nil(t, err)
+	require.Equal(t, []sdk.ValAddress{valAddr}, report.Jailed)
+	require.Equal(t, sdk.ValAddress(operator), report.Validator)
+	require.Equal(t, sdk.TokensToConsensusPower(sdk.NewInt(3e10)), report.Power)
+	require.Equal(t, dex.NewCetCoins(31e9), report.Minted)
+	require.Equal(t, 1, len(validators))
+	require.Equal(t, consKey.PubKey(), validators[0].PubKey)
+
+	// too little stake to have power
+	_, _, _, err = ForkAppState(exported.Bytes(), ForkParams{ChainID: "fork", ConsPubKey: consKey.PubKey(),
+		Operator: operator, Stake: 1})
+	require.NotNil(t, err)
+
+	fork := newApp()
+	res := fork.InitChain(abci.RequestInitChain{ChainId: "fork", Time: genesisTime, AppStateBytes: forkState})
+	require.Equal(t, 1, len(res.Validators))
+	require.Equal(t, report.Power, res.Validators[0].Power)
+
+	newConsAddr := consKey.PubKey().Address()
+	power := sdk.TokensToConsensusPower(sdk.NewInt(3e10))
+	votes = []abci.VoteInfo{{Validator: abci.Validator{Address: newConsAddr, Power: power}, SignedLastBlock: true}}
+	for height := int64(1); height <= 3; height++ {
+		header := abci.Header{Height: height, ChainID: "fork", Time: genesisTime.Add(time.Duration(height) * time.Second),
+			ProposerAddress: newConsAddr}
+		var commitInfo abci.LastCommitInfo
+		if height > 1 {
+			commitInfo.Votes = votes
+		}
+		fork.BeginBlock(abci.RequestBeginBlock{Header: header, LastCommitInfo: commitInfo})
+		require.Empty(t, fork.EndBlock(abci.RequestEndBlock{Height: height}).ValidatorUpdates)
+		fork.Commit()
+	}
+
+	ctx := fork.NewContext(true, abci.Header{Height: fork.LastBlockHeight()})
+	fork.crisisKeeper.AssertInvariants(ctx)
+	val, found := fork.stakingKeeper.GetValidator(ctx, valAddr)
+	require.True(t, found)
+	require.True(t, val.Jailed)
+	require.Equal(t, sdk.Unbonding, val.Status)
+	require.Equal(t, int64(1e9), fork.accountKeeper.GetAccount(ctx, operator).GetCoins().AmountOf(dex.CET).Int64())
+	require.Equal(t, power, fork.stakingKeeper.GetLastTotalPower(ctx).Int64())
+}
@@ -0,0 +1,212 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/staking"
+
+	"github.com/coinexchain/cet-sdk/modules/authx"
+	"github.com/coinexchain/cet-sdk/modules/bancorlite"
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+// CheckConsistency checks the facts across modules which are not covered by the
+// ValidateGenesis of each module, all the problems found are returned
+func (gs GenesisState) CheckConsistency() []error {
+	var errs []error
+	errs = append(errs, gs.checkSupply()...)
+	errs = append(errs, gs.checkStakingPools()...)
+	errs = append(errs, gs.checkDenoms()...)
+	errs = append(errs, gs.checkOrders()...)
+	errs = append(errs, gs.checkFrozenCoins()...)
+	return errs
+}
+
+// checkSupply checks the coins of all the accounts (including the module accounts
+// and the staking pools) sum up to the total supply, and the authx module account
+// holds the locked and frozen coins of AccountX
+func (gs GenesisState) checkSupply() []error {
+	var errs []error
+	total := sdk.Coins{}
+	var authxCoins sdk.Coins
+	for _, acc := range gs.Accounts {
+		total = total.Add(acc.Coins)
+		if acc.ModuleName == authx.ModuleName {
+			authxCoins = acc.Coins
+		}
+	}
+	if !gs.Supply.Supply.Empty() && !total.IsEqual(gs.Supply.Supply) {
+		errs = append(errs, fmt.Errorf("total coins of accounts %s is not equal to supply %s",
+			total, gs.Supply.Supply))
+	}
+
+	accxCoins := sdk.Coins{}
+	for _, accx := range gs.AuthXData.AccountXs {
+		accxCoins = accxCoins.Add(accx.GetAllCoins())
+	}
+	if !accxCoins.IsEqual(authxCoins) {
+		errs = append(errs, fmt.Errorf("locked and frozen coins of accountxs %s is not equal to coins of %s module account %s",
+			accxCoins, authx.ModuleName, authxCoins))
+	}
+	return errs
+}
+
+// checkStakingPools checks the staking pools hold the tokens of the validators and
+// the unbonding delegations, as staking's InitGenesis requires
+func (gs GenesisState) checkStakingPools() []error {
+	bondDenom := gs.StakingData.Params.BondDenom
+	bondedTokens, notBondedTokens := sdk.ZeroInt(), sdk.ZeroInt()
+	for _, val := range gs.StakingData.Validators {
+		if val.IsBonded() {
+			bondedTokens = bondedTokens.Add(val.Tokens)
+		} else {
+			notBondedTokens = notBondedTokens.Add(val.Tokens)
+		}
+	}
+	for _, ubd := range gs.StakingData.UnbondingDelegations {
+		for _, entry := range ubd.Entries {
+			notBondedTokens = notBondedTokens.Add(entry.Balance)
+		}
+	}
+
+	var errs []error
+	pools := []struct {
+		name   string
+		tokens sdk.Int
+	}{
+		{staking.BondedPoolName, bondedTokens},
+		{staking.NotBondedPoolName, notBondedTokens},
+	}
+	for _, pool := range pools {
+		poolTokens := sdk.ZeroInt()
+		for _, acc := range gs.Accounts {
+			if acc.ModuleName == pool.name {
+				poolTokens = acc.Coins.AmountOf(bondDenom)
+			}
+		}
+		if !poolTokens.Equal(pool.tokens) {
+			errs = append(errs, fmt.Errorf("%s has %s%s, but the validators and unbonding delegations need %s%s",
+				pool.name, poolTokens, bondDenom, pool.tokens, bondDenom))
+		}
+	}
+	return errs
+}
+
+// checkDenoms checks every denom held by the accounts is an issued token
+func (gs GenesisState) checkDenoms() []error {
+	tokens := make(map[string]bool, len(gs.AssetData.Tokens))
+	for _, token := range gs.AssetData.Tokens {
+		tokens[token.GetSymbol()] = true
+	}
+
+	unknown := make(map[string]sdk.AccAddress)
+	for _, acc := range gs.Accounts {
+		for _, coin := range acc.Coins {
+			if !tokens[coin.Denom] {
+				unknown[coin.Denom] = acc.Address
+			}
+		}
+	}
+	for _, accx := range gs.AuthXData.AccountXs {
+		for _, coin := range accx.GetAllCoins() {
+			if !tokens[coin.Denom] {
+				unknown[coin.Denom] = accx.Address
+			}
+		}
+	}
+
+	errs := make([]error, 0, len(unknown))
+	for _, denom := range sortedDenoms(unknown) {
+		errs = append(errs, fmt.Errorf("denom %s held by %s is not an issued token", denom, unknown[denom]))
+	}
+	return errs
+}
+
+// checkOrders checks every order belongs to an existing market
+func (gs GenesisState) checkOrders() []error {
+	markets := make(map[string]bool, len(gs.MarketData.MarketInfos))
+	for _, info := range gs.MarketData.MarketInfos {
+		markets[dex.GetSymbol(info.Stock, info.Money)] = true
+	}
+
+	var errs []error
+	for _, order := range gs.MarketData.Orders {
+		if !markets[order.TradingPair] {
+			errs = append(errs, fmt.Errorf("order %s references a non-existent market %s",
+				order.OrderID(), order.TradingPair))
+		}
+	}
+	return errs
+}
+
+// checkFrozenCoins checks the frozen coins of each AccountX are exactly the coins frozen
+// by the orders and the bancor pools of the account
+func (gs GenesisState) checkFrozenCoins() []error {
+	expected := make(map[string]sdk.Coins)
+	for _, order := range gs.MarketData.Orders {
+		frozen := sdk.NewCoins(sdk.NewCoin(order.GetOrderUsedDenom(), sdk.NewInt(order.Freeze))).
+			Add(sdk.NewCoins(sdk.NewCoin(dex.CET, sdk.NewInt(order.FrozenCommission+order.FrozenFeatureFee))))
+		addr := order.Sender.String()
+		expected[addr] = expected[addr].Add(frozen)
+	}
+
+	var errs []error
+	pools := make(map[string]sdk.Coins)
+	for _, symbol := range sortedBancorSymbols(gs.BancorData.BancorInfoMap) {
+		bi := gs.BancorData.BancorInfoMap[symbol]
+		reserves := sdk.NewCoins(sdk.NewCoin(bi.Stock, bi.StockInPool), sdk.NewCoin(bi.Money, bi.MoneyInPool))
+		owner := bi.Owner.String()
+		pools[owner] = pools[owner].Add(reserves)
+		expected[owner] = expected[owner].Add(reserves)
+	}
+
+	actual := make(map[string]sdk.Coins)
+	for _, accx := range gs.AuthXData.AccountXs {
+		actual[accx.Address.String()] = accx.FrozenCoins
+	}
+	for _, addr := range sortedAddrs(expected, actual) {
+		if !pools[addr].Empty() && !actual[addr].IsAllGTE(pools[addr]) {
+			errs = append(errs, fmt.Errorf("bancor reserves %s of %s are not backed by its frozen coins %s",
+				pools[addr], addr, actual[addr]))
+		}
+		if !expected[addr].IsEqual(actual[addr]) {
+			errs = append(errs, fmt.Errorf("frozen coins of %s is %s, but its orders and bancor pools freeze %s",
+				addr, actual[addr], expected[addr]))
+		}
+	}
+	return errs
+}
+
+func sortedDenoms(m map[string]sdk.AccAddress) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedBancorSymbols(m map[string]bancorlite.BancorInfo) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedAddrs(a, b map[string]sdk.Coins) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/genaccounts"
+	"github.com/cosmos/cosmos-sdk/x/staking"
+	"github.com/cosmos/cosmos-sdk/x/supply"
+
+	"github.com/coinexchain/cet-sdk/modules/authx"
+	"github.com/coinexchain/cet-sdk/modules/bancorlite"
+	"github.com/coinexchain/cet-sdk/modules/market"
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+func newConsistentGenesisState(addr sdk.AccAddress) GenesisState {
+	state := NewDefaultGenesisState()
+	state.AssetData.Tokens = append(state.AssetData.Tokens, cetToken())
+	state.Accounts = genaccounts.GenesisState{
+		{Address: addr, Coins: dex.NewCetCoins(100)},
+		{Address: supply.NewModuleAddress(authx.ModuleName), Coins: dex.NewCetCoins(40), ModuleName: authx.ModuleName},
+	}
+	state.AuthXData.AccountXs = []authx.AccountX{
+		{Address: addr, FrozenCoins: dex.NewCetCoins(30), LockedCoins: authx.LockedCoins{authx.NewLockedCoin(dex.CET, sdk.NewInt(10), 1000)}},
+	}
+	state.MarketData.MarketInfos = []market.MarketInfo{{Stock: "abc", Money: dex.CET}}
+	state.MarketData.Orders = []*market.Order{
+		{Sender: addr, TradingPair: "abc/cet", Side: market.BUY, Freeze: 20, FrozenCommission: 10},
+	}
+	state.Supply.Supply = dex.NewCetCoins(140)
+	return state
+}
+
+func TestCheckConsistency(t *testing.T) {
+	_, _, addr := testutil.KeyPubAddr()
+	state := newConsistentGenesisState(addr)
+	require.Equal(t, 0, len(state.CheckConsistency()))
+
+	// supply
+	state.Supply.Supply = dex.NewCetCoins(120)
+	require.Equal(t, 1, len(state.CheckConsistency()))
+	state.Supply.Supply = sdk.Coins{}
+	require.Equal(t, 0, len(state.CheckConsistency()))
+
+	// authx module account
+	state = newConsistentGenesisState(addr)
+	state.Accounts[1].Coins = dex.NewCetCoins(30)
+	errs := state.CheckConsistency()
+	require.Equal(t, 2, len(errs))
+	require.Contains(t, errs[1].Error(), "module account")
+
+	// staking pools
+	state = newConsistentGenesisState(addr)
+	state.StakingData.Validators = staking.Validators{{Tokens: sdk.NewInt(50), Status: sdk.Bonded}}
+	errs = state.CheckConsistency()
+	require.Equal(t, 1, len(errs))
+	require.Contains(t, errs[0].Error(), staking.BondedPoolName)
+
+	// unknown denoms
+	state = newConsistentGenesisState(addr)
+	state.Accounts[0].Coins = state.Accounts[0].Coins.Add(dex.NewCoins("xyz", 10))
+	state.Supply.Supply = state.Supply.Supply.Add(dex.NewCoins("xyz", 10))
+	errs = state.CheckConsistency()
+	require.Equal(t, 1, len(errs))
+	require.Contains(t, errs[0].Error(), "denom xyz")
+
+	// orders
+	state = newConsistentGenesisState(addr)
+	state.MarketData.MarketInfos = nil
+	errs = state.CheckConsistency()
+	require.Equal(t, 1, len(errs))
+	require.Contains(t, errs[0].Error(), "non-existent market abc/cet")
+
+	state = newConsistentGenesisState(addr)
+	state.MarketData.Orders[0].Freeze = 10
+	errs = state.CheckConsistency()
+	require.Equal(t, 1, len(errs))
+	require.Contains(t, errs[0].Error(), "frozen coins of "+addr.String())
+
+	// bancor
+	state = newConsistentGenesisState(addr)
+	state.BancorData.BancorInfoMap["abc/cet"] = bancorlite.BancorInfo{
+		Owner: addr, Stock: "abc", Money: dex.CET, StockInPool: sdk.NewInt(5), MoneyInPool: sdk.ZeroInt(),
+	}
+	errs = state.CheckConsistency()
+	require.Equal(t, 2, len(errs))
+	require.Contains(t, errs[0].Error(), "bancor reserves")
+}
@@ -0,0 +1,205 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/staking"
+
+	"github.com/coinexchain/cet-sdk/modules/incentive"
+)
+
+// WriteAppState exports the app state to w one module at a time, the section of a module is
+// built in memory and written before the next one, except the accounts and the orders, which
+// are written one at a time, so the whole app state is never kept in memory. The output is the same as the app_state in the sorted JSON written by 'cetd export'.
+// Only the given modules are exported if any.
+// The report is nil unless forZeroHeight is true.
+func (app *CetChainApp) WriteAppState(w io.Writer, forZeroHeight bool, jailWhiteList []string, modules ...string) (
+	validators []tmtypes.GenesisValidator, report *ZeroHeightReport, err error) {
+
+	modules, err = app.exportedModules(modules)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx := app.NewContext(true, abci.Header{Height: app.LastBlockHeight()})
+	if forZeroHeight {
+		report = app.prepForZeroHeightGenesis(ctx, jailWhiteList)
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString("{")
+	for i, name := range modules {
+		if sl, ok := streamedLists[name]; ok {
+			if err = writeStreamedSection(bw, name, i == 0, func(w io.Writer) error {
+				return sl.write(app, ctx, w)
+			}); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		section := app.mm.Modules[name].ExportGenesis(ctx)
+		if forZeroHeight && name == incentive.ModuleName {
+			section = adjustIncentiveHeight(section, ctx.BlockHeight())
+		}
+		if err = writeSortedSection(bw, name, section, i == 0); err != nil {
+			return nil, nil, err
+		}
+	}
+	bw.WriteString("}")
+	if err = bw.Flush(); err != nil {
+		return nil, nil, err
+	}
+	return staking.WriteValidators(ctx, app.stakingKeeper), report, nil
+}
+
+// exportedModules checks the names of modules and returns them sorted,
+// or all the modules if none is given
+func (app *CetChainApp) exportedModules(names []string) ([]string, error) {
+	if len(names) == 0 {
+		names = app.mm.OrderExportGenesis
+	}
+	modules := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if _, ok := app.mm.Modules[name]; !ok {
+			return nil, fmt.Errorf("unknown module: %s", name)
+		}
+		if !seen[name] {
+			seen[name] = true
+			modules = append(modules, name)
+		}
+	}
+	sort.Strings(modules)
+	return modules, nil
+}
+
+func adjustIncentiveHeight(section json.RawMessage, height int64) json.RawMessage {
+	var ig incentive.GenesisState
+	incentive.ModuleCdc.MustUnmarshalJSON(section, &ig)
+	ig.State.HeightAdjustment = ig.State.HeightAdjustment + height
+	return incentive.ModuleCdc.MustMarshalJSON(ig)
+}
+
+func writeSortedSection(w io.Writer, key string, section json.RawMessage, first bool) error {
+	if !first {
+		if _, err := io.WriteString(w, ","); err != nil {
+			return err
+		}
+	}
+	keyJSON, _ := json.Marshal(key)
+	if _, err := w.Write(append(keyJSON, ':')); err != nil {
+		return err
+	}
+	if len(section) == 0 {
+		_, err := io.WriteString(w, "null")
+		return err
+	}
+	sorted, err := sdk.SortJSON(section)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(sorted)
+	return err
+}
+
+func writeStreamedSection(w io.Writer, key string, first bool, writeSection func(w io.Writer) error) error {
+	if !first {
+		if _, err := io.WriteString(w, ","); err != nil {
+			return err
+		}
+	}
+	keyJSON, _ := json.Marshal(key)
+	if _, err := w.Write(append(keyJSON, ':')); err != nil {
+		return err
+	}
+	return writeSection(w)
+}
+
+// WriteGenesisDoc writes genDoc to w as the sorted JSON written by 'cetd export', with its
+// app_state written by writeAppState, which returns the validators of genDoc
+func WriteGenesisDoc(cdc *codec.Codec, w io.Writer, genDoc *tmtypes.GenesisDoc,
+	writeAppState func(w io.Writer) ([]tmtypes.GenesisValidator, error)) error {
+
+	bw := bufio.NewWriter(w)
+	doc := *genDoc
+	doc.AppState = nil
+	doc.Validators = nil
+	// app_hash is the only field sorted before app_state
+	fmt.Fprintf(bw, `{"app_hash":"%s","app_state":`, doc.AppHash)
+	validators, err := writeAppState(bw)
+	if err != nil {
+		return err
+	}
+	doc.Validators = validators
+
+	bz, err := cdc.MarshalJSON(doc)
+	if err != nil {
+		return err
+	}
+	fields := make(map[string]json.RawMessage)
+	if err = json.Unmarshal(bz, &fields); err != nil {
+		return err
+	}
+	delete(fields, "app_hash")
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		bw.WriteString(",")
+		if err = writeSortedSection(bw, k, fields[k], true); err != nil {
+			return err
+		}
+	}
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// splitAppState splits the app state into the sections of modules, which are slices
+// of bz instead of copies. Tendermint passes the whole genesis to InitChain in memory,
+// so the app state can not be read as a stream, but it is not copied again, and the
+// sections are skipped token by token instead of being decoded.
+func splitAppState(bz []byte) (map[string]json.RawMessage, error) {
+	sections := make(map[string]json.RawMessage)
+	dec := json.NewDecoder(bytes.NewReader(bz))
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		start := dec.InputOffset()
+		if err = skipValue(dec); err != nil {
+			return nil, err
+		}
+		section := bytes.TrimLeft(bz[start:dec.InputOffset()], " \t\r\n:")
+		if bytes.Equal(section, []byte("null")) {
+			section = nil // the same as amino
+		}
+		sections[key.(string)] = section
+	}
+	return sections, expectDelim(dec, '}')
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("expected %s in app state, got %v", delim, tok)
+	}
+	return nil
+}
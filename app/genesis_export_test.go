@@ -0,0 +1,93 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+func TestWriteGenesisDoc(t *testing.T) {
+	_, _, addr := testutil.KeyPubAddr()
+	acc := auth.BaseAccount{Address: addr, Coins: dex.NewCetCoins(cetToken().GetTotalSupply().Int64())}
+	app := initAppWithBaseAccounts(acc)
+	commitFirstBlock(app)
+
+	// the same as server.ExportCmd
+	appState, validators, err := app.ExportAppStateAndValidators(false, nil)
+	require.Nil(t, err)
+	doc := &tmtypes.GenesisDoc{ChainID: testChainID, AppState: appState, Validators: validators}
+	encoded, err := codec.MarshalJSONIndent(app.cdc, doc)
+	require.Nil(t, err)
+	expected := string(sdk.MustSortJSON(encoded)) + "\n"
+
+	var buf bytes.Buffer
+	err = WriteGenesisDoc(app.cdc, &buf, &tmtypes.GenesisDoc{ChainID: testChainID},
+		func(w io.Writer) ([]tmtypes.GenesisValidator, error) {
+			validators, _, err := app.WriteAppState(w, false, nil)
+			return validators, err
+		})
+	require.Nil(t, err)
+	require.Equal(t, expected, buf.String())
+
+	genDoc, err := tmtypes.GenesisDocFromJSON(buf.Bytes())
+	require.Nil(t, err)
+	var genState GenesisState
+	require.Nil(t, app.cdc.UnmarshalJSON(genDoc.AppState, &genState))
+	require.Empty(t, genState.CheckConsistency())
+}
+
+func TestSplitAppState(t *testing.T) {
+	genState := NewDefaultGenesisState()
+	bz, err := codec.MarshalJSONIndent(MakeCodec(), genState)
+	require.Nil(t, err)
+	bz = append(bytes.Replace(bz[:len(bz)-1], []byte(`"supply"`), []byte(`"empty": null, "supply"`), 1), '}')
+
+	var expected map[string]json.RawMessage
+	require.Nil(t, MakeCodec().UnmarshalJSON(bz, &expected))
+	sections, err := splitAppState(bz)
+	require.Nil(t, err)
+	require.Equal(t, len(expected), len(sections))
+	require.Nil(t, sections["empty"])
+	for module, section := range expected {
+		require.Equal(t, string(section), string(sections[module]), module)
+	}
+
+	_, err = splitAppState([]byte(`{"a":`))
+	require.NotNil(t, err)
+}
+
+func TestWriteAppStateOfModules(t *testing.T) {
+	app := initAppWithBaseAccounts()
+	commitFirstBlock(app)
+
+	var buf bytes.Buffer
+	_, _, err := app.WriteAppState(&buf, false, nil, "market", "asset", "market")
+	require.Nil(t, err)
+	var sections map[string]json.RawMessage
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &sections))
+	require.Equal(t, 2, len(sections))
+	require.NotNil(t, sections["market"])
+	require.NotNil(t, sections["asset"])
+
+	_, _, err = app.WriteAppState(&buf, false, nil, "unknown")
+	require.EqualError(t, err, "unknown module: unknown")
+}
+
+func commitFirstBlock(app *CetChainApp) {
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: 1, ChainID: testChainID}})
+	app.EndBlock(abci.RequestEndBlock{Height: 1})
+	app.Commit()
+}
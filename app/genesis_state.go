@@ -24,6 +24,7 @@ import (
 	"github.com/coinexchain/cet-sdk/modules/incentive"
 	"github.com/coinexchain/cet-sdk/modules/market"
 	"github.com/coinexchain/cet-sdk/modules/stakingx"
+	"github.com/coinexchain/dex/app/upgrade"
 )
 
 // State to Unmarshal
@@ -47,6 +48,7 @@ type GenesisState struct {
 	Incentive    incentive.GenesisState    `json:"incentive"`
 	Supply       supply.GenesisState       `json:"supply"`
 	GenUtil      genutil.GenesisState      `json:"genutil"`
+	UpgradeData  upgrade.GenesisState      `json:"upgrade"`
 }
 
 func NewDefaultGenesisState() GenesisState {
@@ -70,6 +72,7 @@ func NewDefaultGenesisState() GenesisState {
 		Incentive:    incentive.DefaultGenesisState(),
 		Supply:       supply.DefaultGenesisState(),
 		GenUtil:      genutil.GenesisState{},
+		UpgradeData:  upgrade.DefaultGenesisState(),
 	}
 }
 
@@ -95,6 +98,7 @@ func FromMap(cdc *codec.Codec, g map[string]json.RawMessage) GenesisState {
 	unmarshalField(cdc, g[incentive.ModuleName], &gs.Incentive)
 	unmarshalField(cdc, g[supply.ModuleName], &gs.Supply)
 	unmarshalField(cdc, g[genutil.ModuleName], &gs.GenUtil)
+	unmarshalField(cdc, g[upgrade.ModuleName], &gs.UpgradeData)
 
 	return gs
 }
@@ -126,5 +130,6 @@ func (gs GenesisState) toMap(cdc *codec.Codec) map[string]json.RawMessage {
 	m[incentive.ModuleName] = cdc.MustMarshalJSON(gs.Incentive)
 	m[supply.ModuleName] = cdc.MustMarshalJSON(gs.Supply)
 	m[genutil.ModuleName] = cdc.MustMarshalJSON(gs.GenUtil)
+	m[upgrade.ModuleName] = cdc.MustMarshalJSON(gs.UpgradeData)
 	return m
 }
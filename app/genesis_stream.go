@@ -0,0 +1,375 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	"github.com/cosmos/cosmos-sdk/x/genaccounts"
+
+	"github.com/coinexchain/cet-sdk/modules/market"
+)
+
+// streamedList is a large list in the genesis section of a module, the accounts or the orders,
+// which is exported, validated and imported one element at a time instead of being kept in
+// memory as a whole. The other fields of the section are handled by the module.
+type streamedList struct {
+	// the field of the list in the section, or "" if the section is the list
+	field string
+	// write writes the section with the list written one element at a time
+	write func(app *CetChainApp, ctx sdk.Context, w io.Writer) error
+	// newValidator returns a function which validates the elements one by one
+	newValidator func() func(elem json.RawMessage) error
+	// init imports the elements, after the module has imported the other fields
+	init func(app *CetChainApp, ctx sdk.Context, elems []json.RawMessage)
+}
+
+var streamedLists = map[string]streamedList{
+	genaccounts.ModuleName: {
+		write:        (*CetChainApp).writeGenesisAccounts,
+		newValidator: newGenesisAccountValidator,
+		init:         (*CetChainApp).initGenesisAccounts,
+	},
+	market.ModuleName: {
+		field:        "orders",
+		write:        (*CetChainApp).writeMarketGenesis,
+		newValidator: newOrderValidator,
+		init:         (*CetChainApp).initOrders,
+	},
+}
+
+// writeGenesisAccounts writes the section of genaccounts, which is the list of accounts
+func (app *CetChainApp) writeGenesisAccounts(ctx sdk.Context, w io.Writer) error {
+	lw := &listWriter{w: w}
+	app.accountKeeper.IterateAccounts(ctx, func(acc auth.Account) bool {
+		gacc, err := genaccounts.NewGenesisAccountI(acc)
+		if err != nil {
+			panic(err) // the same as genaccounts.ExportGenesis
+		}
+		return lw.write(genaccounts.ModuleCdc.MustMarshalJSON(gacc)) != nil
+	})
+	return lw.close("[]")
+}
+
+// writeMarketGenesis writes the section of market as market.ExportGenesis does, except that
+// the orders, which are loaded by the keeper, are marshaled one at a time
+func (app *CetChainApp) writeMarketGenesis(ctx sdk.Context, w io.Writer) error {
+	mk := app.marketKeeper
+	rest := market.NewGenesisState(mk.GetParams(ctx), nil, mk.GetAllMarketInfos(ctx), mk.GetOrderCleanTime(ctx))
+	return writeSortedObject(w, market.ModuleCdc.MustMarshalJSON(rest), "orders", func(w io.Writer) error {
+		lw := &listWriter{w: w}
+		for _, order := range mk.GetAllOrders(ctx) {
+			if err := lw.write(marshalOrder(order)); err != nil {
+				return err
+			}
+		}
+		return lw.close("null")
+	})
+}
+
+func newGenesisAccountValidator() func(elem json.RawMessage) error {
+	seen := make(map[string]struct{})
+	return func(elem json.RawMessage) error {
+		var gacc genaccounts.GenesisAccount
+		if err := genaccounts.ModuleCdc.UnmarshalJSON(elem, &gacc); err != nil {
+			return err
+		}
+		addr := gacc.Address.String()
+		if _, ok := seen[addr]; ok {
+			return fmt.Errorf("duplicate account found in genesis state; address: %s", addr)
+		}
+		seen[addr] = struct{}{}
+		// the vesting fields
+		return genaccounts.ValidateGenesis(genaccounts.GenesisState{gacc})
+	}
+}
+
+// initGenesisAccounts imports the accounts as genaccounts.InitGenesis does, which sorts them
+// by their account numbers before they are numbered again. The numbers are sorted by the
+// same sort.Slice, so the accounts are imported in the same order.
+func (app *CetChainApp) initGenesisAccounts(ctx sdk.Context, elems []json.RawMessage) {
+	type numberedElem struct {
+		AccountNumber uint64 `json:"account_number,string"`
+		elem          json.RawMessage
+	}
+	sorted := make([]numberedElem, len(elems))
+	for i, elem := range elems {
+		if err := json.Unmarshal(elem, &sorted[i]); err != nil {
+			panic(err)
+		}
+		sorted[i].elem = elem
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].AccountNumber < sorted[j].AccountNumber
+	})
+
+	for _, e := range sorted {
+		var gacc genaccounts.GenesisAccount
+		genaccounts.ModuleCdc.MustUnmarshalJSON(e.elem, &gacc)
+		gacc.Coins = gacc.Coins.Sort()
+		acc := app.accountKeeper.NewAccount(ctx, gacc.ToAccount()) // set account number
+		app.accountKeeper.SetAccount(ctx, acc)
+	}
+}
+
+func newOrderValidator() func(elem json.RawMessage) error {
+	seen := make(map[string]struct{})
+	return func(elem json.RawMessage) error {
+		order, err := unmarshalOrder(elem)
+		if err != nil {
+			return err
+		}
+		id := order.OrderID()
+		if _, ok := seen[id]; ok {
+			return fmt.Errorf("duplicate order found during market ValidateGenesis")
+		}
+		seen[id] = struct{}{}
+		return nil
+	}
+}
+
+// initOrders imports the orders as market.InitGenesis does
+func (app *CetChainApp) initOrders(ctx sdk.Context, elems []json.RawMessage) {
+	for _, elem := range elems {
+		order, err := unmarshalOrder(elem)
+		if err != nil {
+			panic(err)
+		}
+		app.marketKeeper.SetOrder(ctx, order)
+	}
+}
+
+// marshalOrder marshals order as an element of the orders in the genesis state, which is not
+// wrapped with its amino type as a registered type marshaled alone is
+func marshalOrder(order *market.Order) []byte {
+	bz := market.ModuleCdc.MustMarshalJSON([]*market.Order{order})
+	return bz[1 : len(bz)-1]
+}
+
+func unmarshalOrder(elem json.RawMessage) (*market.Order, error) {
+	var orders []*market.Order
+	bz := make([]byte, 0, len(elem)+2)
+	bz = append(append(append(bz, '['), elem...), ']')
+	if err := market.ModuleCdc.UnmarshalJSON(bz, &orders); err != nil {
+		return nil, err
+	}
+	if len(orders) != 1 || orders[0] == nil {
+		return nil, fmt.Errorf("invalid order: %s", elem)
+	}
+	return orders[0], nil
+}
+
+// splitStreamedLists takes the streamed lists out of the sections in genesisState, each
+// section is replaced by a copy in which the list is empty. The elements are slices of the
+// sections, which are validated one by one.
+func splitStreamedLists(genesisState map[string]json.RawMessage) (map[string][]json.RawMessage, error) {
+	lists := make(map[string][]json.RawMessage, len(streamedLists))
+	for name, sl := range streamedLists {
+		section := genesisState[name]
+		if section == nil {
+			continue
+		}
+		elems, rest, err := splitList(section, sl.field)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err.Error())
+		}
+		validate := sl.newValidator()
+		for _, elem := range elems {
+			if err := validate(elem); err != nil {
+				return nil, fmt.Errorf("%s: %s", name, err.Error())
+			}
+		}
+		genesisState[name] = rest
+		lists[name] = elems
+	}
+	return lists, nil
+}
+
+// splitList returns the elements of the list in field of section, or of section if field is
+// empty, and a copy of section with an empty list
+func splitList(section json.RawMessage, field string) (elems []json.RawMessage, rest json.RawMessage, err error) {
+	dec := json.NewDecoder(bytes.NewReader(section))
+	if field == "" {
+		elems, _, _, err = readList(dec, section)
+		return elems, json.RawMessage("[]"), err
+	}
+
+	if err = expectDelim(dec, '{'); err != nil {
+		return nil, nil, err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		if key != field {
+			if err = skipValue(dec); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		elems, start, end, err := readList(dec, section)
+		if err != nil {
+			return nil, nil, err
+		}
+		rest = make(json.RawMessage, 0, len(section)-(end-start)+2)
+		rest = append(append(append(rest, section[:start]...), "[]"...), section[end:]...)
+		return elems, rest, nil
+	}
+	return nil, section, expectDelim(dec, '}')
+}
+
+// readList reads the next value of dec, which is a list or null, and returns its elements and
+// its offsets in bz, which is read by dec
+func readList(dec *json.Decoder, bz []byte) (elems []json.RawMessage, start, end int, err error) {
+	start = int(dec.InputOffset())
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	start += bytes.IndexAny(bz[start:], "[n")
+	if tok == nil {
+		return nil, start, int(dec.InputOffset()), nil
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '[' {
+		return nil, 0, 0, fmt.Errorf("expected [ or null, got %v", tok)
+	}
+	for dec.More() {
+		elemStart := int(dec.InputOffset())
+		if err = skipValue(dec); err != nil {
+			return nil, 0, 0, err
+		}
+		elemEnd := int(dec.InputOffset())
+		elems = append(elems, bytes.TrimLeft(bz[elemStart:elemEnd], " \t\r\n,"))
+	}
+	if err = expectDelim(dec, ']'); err != nil {
+		return nil, 0, 0, err
+	}
+	return elems, start, int(dec.InputOffset()), nil
+}
+
+// skipValue reads the next value of dec token by token, so a large value is not buffered by
+// dec as a whole
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// initGenesis is module.Manager.InitGenesis, except that the streamed lists are imported
+// after the other fields of the sections of their modules
+func (app *CetChainApp) initGenesis(ctx sdk.Context, genesisState map[string]json.RawMessage,
+	lists map[string][]json.RawMessage) abci.ResponseInitChain {
+
+	var validatorUpdates []abci.ValidatorUpdate
+	for _, moduleName := range app.mm.OrderInitGenesis {
+		if genesisState[moduleName] == nil {
+			continue
+		}
+		moduleValUpdates := app.mm.Modules[moduleName].InitGenesis(ctx, genesisState[moduleName])
+		if elems, ok := lists[moduleName]; ok {
+			streamedLists[moduleName].init(app, ctx, elems)
+		}
+
+		// use these validator updates if provided, the module manager assumes
+		// only one module will update the validator set
+		if len(moduleValUpdates) > 0 {
+			if len(validatorUpdates) > 0 {
+				panic("validator InitGenesis updates already set by a previous module")
+			}
+			validatorUpdates = moduleValUpdates
+		}
+	}
+	return abci.ResponseInitChain{
+		Validators: validatorUpdates,
+	}
+}
+
+// listWriter writes a JSON list one sorted element at a time
+type listWriter struct {
+	w   io.Writer
+	n   int
+	err error
+}
+
+func (lw *listWriter) write(elem []byte) error {
+	if lw.err != nil {
+		return lw.err
+	}
+	sorted, err := sdk.SortJSON(elem)
+	if err != nil {
+		lw.err = err
+		return err
+	}
+	sep := ","
+	if lw.n == 0 {
+		sep = "["
+	}
+	if _, lw.err = io.WriteString(lw.w, sep); lw.err == nil {
+		_, lw.err = lw.w.Write(sorted)
+	}
+	lw.n++
+	return lw.err
+}
+
+// close ends the list, an empty list is written as empty, which is [] or null
+func (lw *listWriter) close(empty string) error {
+	if lw.err != nil {
+		return lw.err
+	}
+	if lw.n == 0 {
+		_, err := io.WriteString(lw.w, empty)
+		return err
+	}
+	_, err := io.WriteString(lw.w, "]")
+	return err
+}
+
+// writeSortedObject writes the object in bz as sdk.SortJSON does, with the value of field
+// written by writeField
+func writeSortedObject(w io.Writer, bz []byte, field string, writeField func(w io.Writer) error) error {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(bz, &fields); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if _, err := io.WriteString(w, "{"); err != nil {
+		return err
+	}
+	for i, k := range keys {
+		if k != field {
+			if err := writeSortedSection(w, k, fields[k], i == 0); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := writeStreamedSection(w, k, i == 0, writeField); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "}")
+	return err
+}
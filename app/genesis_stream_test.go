@@ -0,0 +1,132 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+
+	"github.com/coinexchain/cet-sdk/modules/market"
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+func initAppWithOrders() *CetChainApp {
+	return initApp(addAccountsAndOrders())
+}
+
+// the account numbers are not sorted and repeated, the orders are not sorted
+func addAccountsAndOrders() genesisStateCallback {
+	var accs []auth.BaseAccount
+	for _, number := range []uint64{5, 2, 9, 2, 0, 7, 2} {
+		_, acc := testutil.NewBaseAccount(1e10, number, 0)
+		accs = append(accs, acc)
+	}
+	return func(genState *GenesisState) {
+		addGenesisAccounts(genState, accs...)
+		genState.MarketData.MarketInfos = []market.MarketInfo{{Stock: "abc", Money: dex.CET}}
+		for i := len(accs) - 1; i >= 0; i-- {
+			genState.MarketData.Orders = append(genState.MarketData.Orders, &market.Order{
+				Sender: accs[i].Address, Sequence: uint64(i), TradingPair: "abc/cet", OrderType: market.LimitOrder,
+				Price: sdk.NewDec(1), Quantity: 100, Side: market.BUY, TimeInForce: market.GTE,
+				Height: 1, ExistBlocks: 10000, LeftStock: 100})
+		}
+	}
+}
+
+func TestWriteAppStateWithOrders(t *testing.T) {
+	app := initAppWithOrders()
+	commitFirstBlock(app)
+	require.Equal(t, 7, len(app.marketKeeper.GetAllOrders(app.NewContext(true, abci.Header{}))))
+
+	appState, _, err := app.ExportAppStateAndValidators(false, nil)
+	require.Nil(t, err)
+	var buf bytes.Buffer
+	_, _, err = app.WriteAppState(&buf, false, nil)
+	require.Nil(t, err)
+	require.Equal(t, string(sdk.MustSortJSON(appState)), buf.String())
+}
+
+func TestStreamedInitGenesis(t *testing.T) {
+	// the exported state is checked too, in which the accounts are sorted by addresses
+	exported := initAppWithOrders()
+	commitFirstBlock(exported)
+	var buf bytes.Buffer
+	_, _, err := exported.WriteAppState(&buf, false, nil)
+	require.Nil(t, err)
+
+	for _, appState := range [][]byte{newGenesisStateBytes(newApp(), addAccountsAndOrders()), buf.Bytes()} {
+		testStreamedInitGenesis(t, abci.RequestInitChain{ChainId: testChainID, AppStateBytes: appState})
+	}
+}
+
+func testStreamedInitGenesis(t *testing.T, req abci.RequestInitChain) {
+	// the genesis state is imported by module.Manager
+	expected := newApp()
+	ctx := sdk.NewContext(expected.cms.CacheMultiStore(), abci.Header{ChainID: testChainID}, false, expected.Logger())
+	var genesisState map[string]json.RawMessage
+	require.Nil(t, expected.cdc.UnmarshalJSON(req.AppStateBytes, &genesisState))
+	require.Nil(t, ModuleBasics.ValidateGenesis(genesisState))
+	expectedRes := expected.mm.InitGenesis(ctx, genesisState)
+	ctx.MultiStore().(sdk.CacheMultiStore).Write()
+
+	app := newApp()
+	ctx = sdk.NewContext(app.cms.CacheMultiStore(), abci.Header{ChainID: testChainID}, false, app.Logger())
+	res := app.initChainer(ctx, req)
+	ctx.MultiStore().(sdk.CacheMultiStore).Write()
+
+	require.Equal(t, expectedRes, res)
+	require.Equal(t, expected.cms.Commit().Hash, app.cms.Commit().Hash)
+}
+
+func TestSplitList(t *testing.T) {
+	elems, rest, err := splitList([]byte(` [ {"a":[1,2]} , 3,"x" ] `), "")
+	require.Nil(t, err)
+	require.Equal(t, []json.RawMessage{[]byte(`{"a":[1,2]}`), []byte(`3`), []byte(`"x"`)}, elems)
+	require.Equal(t, "[]", string(rest))
+
+	elems, rest, err = splitList([]byte(`{"a": {"orders":[1]}, "orders" : [ 1, {"b":2} ], "c":3}`), "orders")
+	require.Nil(t, err)
+	require.Equal(t, []json.RawMessage{[]byte(`1`), []byte(`{"b":2}`)}, elems)
+	require.Equal(t, `{"a": {"orders":[1]}, "orders" : [], "c":3}`, string(rest))
+
+	elems, rest, err = splitList([]byte(`{"orders":null,"c":3}`), "orders")
+	require.Nil(t, err)
+	require.Nil(t, elems)
+	require.Equal(t, `{"orders":[],"c":3}`, string(rest))
+
+	elems, rest, err = splitList([]byte(`{"c":3}`), "orders")
+	require.Nil(t, err)
+	require.Nil(t, elems)
+	require.Equal(t, `{"c":3}`, string(rest))
+
+	_, _, err = splitList([]byte(`{"orders":{}}`), "orders")
+	require.NotNil(t, err)
+}
+
+func TestSplitStreamedListsRejectsDuplicates(t *testing.T) {
+	app := initAppWithOrders()
+	commitFirstBlock(app)
+	var buf bytes.Buffer
+	_, _, err := app.WriteAppState(&buf, false, nil, "accounts", "market")
+	require.Nil(t, err)
+	genesisState, err := splitAppState(buf.Bytes())
+	require.Nil(t, err)
+
+	accounts, _, err := splitList(genesisState["accounts"], "")
+	require.Nil(t, err)
+	orders, _, err := splitList(genesisState["market"], "orders")
+	require.Nil(t, err)
+	dupAccounts := "[" + string(accounts[0]) + "," + string(accounts[0]) + "]"
+	_, err = splitStreamedLists(map[string]json.RawMessage{"accounts": []byte(dupAccounts)})
+	require.Contains(t, err.Error(), "duplicate account")
+	dupOrders := `{"orders":[` + string(orders[0]) + "," + string(orders[0]) + "]}"
+	_, err = splitStreamedLists(map[string]json.RawMessage{"market": []byte(dupOrders)})
+	require.Contains(t, err.Error(), "duplicate order")
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/client/context"
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/coinexchain/dex/app/overview"
+)
+
+func GetAccountOverviewCmd(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "account-overview [address]",
+		Short: "Query the balances, delegations, rewards, open orders and aliases of an account",
+		Long: `Query everything about an account in one request, all the fields are read at the same height.
+
+Example:
+	cetcli query account-overview coinex1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			addr, err := sdk.AccAddressFromBech32(args[0])
+			if err != nil {
+				return err
+			}
+			route := fmt.Sprintf("custom/%s/%s", overview.QuerierRoute, overview.QueryAccount)
+			bz, err := cdc.MarshalJSON(overview.NewQueryAccountParam(addr))
+			if err != nil {
+				return err
+			}
+
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+			res, _, err := cliCtx.QueryWithData(route, bz)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
+	return client.GetCommands(cmd)[0]
+}
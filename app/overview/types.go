@@ -0,0 +1,43 @@
+package overview
+
+import (
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/distribution"
+	"github.com/cosmos/cosmos-sdk/x/staking"
+
+	"github.com/coinexchain/cet-sdk/modules/authx"
+	"github.com/coinexchain/cet-sdk/modules/market"
+)
+
+const (
+	// QuerierRoute is the querier route of the app-level aggregated queries
+	QuerierRoute = "overview"
+
+	QueryAccount = "account"
+)
+
+// AccountOverview puts together what a wallet needs to render an account,
+// all the fields are read at the same height
+type AccountOverview struct {
+	Height               int64                                           `json:"height"`
+	Address              sdk.AccAddress                                  `json:"address"`
+	AccountNumber        uint64                                          `json:"account_number"`
+	Sequence             uint64                                          `json:"sequence"`
+	Coins                sdk.Coins                                       `json:"coins"`
+	LockedCoins          authx.LockedCoins                               `json:"locked_coins"`
+	FrozenCoins          sdk.Coins                                       `json:"frozen_coins"`
+	MemoRequired         bool                                            `json:"memo_required"`
+	Delegations          []staking.Delegation                            `json:"delegations"`
+	UnbondingDelegations []staking.UnbondingDelegation                   `json:"unbonding_delegations"`
+	Rewards              distribution.QueryDelegatorTotalRewardsResponse `json:"rewards"`
+	OpenOrders           []market.Order                                  `json:"open_orders"`
+	Aliases              []string                                        `json:"aliases"`
+}
+
+type QueryAccountParam struct {
+	Address sdk.AccAddress `json:"address"`
+}
+
+func NewQueryAccountParam(addr sdk.AccAddress) QueryAccountParam {
+	return QueryAccountParam{Address: addr}
+}
@@ -35,18 +35,31 @@ func (loader *Holder) WaitPluginToggleSignal(logger log.Logger) {
 }
 
 type Holder struct {
-	isEnabled      int32
-	pluginInstance AppPlugin
+	isEnabled int32
+	// the instance is set by the goroutine of the signal while the app reads it, so it is
+	// kept in an atomic.Value as a pluginBox, which can hold a nil instance
+	pluginInstance atomic.Value
 	logger         log.Logger
 }
 
+type pluginBox struct {
+	instance AppPlugin
+}
+
+func (loader *Holder) loadPluginInstance() AppPlugin {
+	if box, ok := loader.pluginInstance.Load().(pluginBox); ok {
+		return box.instance
+	}
+	return nil
+}
+
 func (loader *Holder) isPluginLoaded() bool {
-	return loader.pluginInstance != nil
+	return loader.loadPluginInstance() != nil
 }
 
 func (loader *Holder) GetPlugin() AppPlugin {
 	if loader.isPluginEnabled() {
-		return loader.pluginInstance
+		return loader.loadPluginInstance()
 	}
 
 	return nil
@@ -78,16 +91,16 @@ func (loader *Holder) isPluginEnabled() bool {
 func (loader *Holder) enablePlugin() {
 	atomic.StoreInt32(&loader.isEnabled, 1)
 
-	if loader.pluginInstance != nil {
-		loader.logger.Info(fmt.Sprintf("plugin %s is enabled", loader.pluginInstance.Name()))
+	if instance := loader.loadPluginInstance(); instance != nil {
+		loader.logger.Info(fmt.Sprintf("plugin %s is enabled", instance.Name()))
 	}
 }
 
 func (loader *Holder) disablePlugin() {
 	atomic.StoreInt32(&loader.isEnabled, 0)
 
-	if loader.pluginInstance != nil {
-		loader.logger.Info(fmt.Sprintf("plugin %s is disabled", loader.pluginInstance.Name()))
+	if instance := loader.loadPluginInstance(); instance != nil {
+		loader.logger.Info(fmt.Sprintf("plugin %s is disabled", instance.Name()))
 	}
 }
 
@@ -124,6 +137,17 @@ func (loader *Holder) loadAndEnablePlugin() {
 		return
 	}
 
-	loader.pluginInstance = instance
+	loader.pluginInstance.Store(pluginBox{instance})
 	loader.enablePlugin()
 }
+
+// SetPlugin enables p without loading it from a file, or disables the plugin if p is nil
+func (loader *Holder) SetPlugin(p AppPlugin) {
+	if p == nil {
+		atomic.StoreInt32(&loader.isEnabled, 0)
+		loader.pluginInstance.Store(pluginBox{})
+	} else {
+		loader.pluginInstance.Store(pluginBox{p})
+		atomic.StoreInt32(&loader.isEnabled, 1)
+	}
+}
@@ -2,11 +2,14 @@ package plugin
 
 import (
 	"os/exec"
+	"runtime"
 	"testing"
 
 	"github.com/cosmos/cosmos-sdk/client/flags"
+	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
 	"github.com/tendermint/tendermint/libs/log"
 )
 
@@ -44,3 +47,42 @@ func TestTogglePlugin(t *testing.T) {
 	require.Equal(t, int32(1), holder.isEnabled)
 	require.NotNil(t, holder.GetPlugin())
 }
+
+type nopPlugin struct{}
+
+func (nopPlugin) PreCheckTx(abci.RequestCheckTx, sdk.TxDecoder, log.Logger) sdk.Error { return nil }
+func (nopPlugin) Name() string                                                        { return "nop" }
+
+func TestSetPlugin(t *testing.T) {
+	holder := Holder{}
+	holder.SetPlugin(nopPlugin{})
+	require.Equal(t, nopPlugin{}, holder.GetPlugin())
+	holder.SetPlugin(nil)
+	require.Nil(t, holder.GetPlugin())
+}
+
+// the plugin is set by the goroutine of the toggle signal while CheckTx reads it,
+// run with -race to check the instance is not read while it is written
+func TestSetPluginConcurrently(t *testing.T) {
+	holder := Holder{logger: log.NewNopLogger()}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			if i%2 == 0 {
+				holder.SetPlugin(nopPlugin{})
+			} else {
+				holder.SetPlugin(nil)
+			}
+			runtime.Gosched()
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		if p := holder.GetPlugin(); p != nil {
+			require.Equal(t, "nop", p.Name())
+		}
+		runtime.Gosched()
+	}
+	<-done
+	require.Nil(t, holder.GetPlugin())
+}
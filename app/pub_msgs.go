@@ -1,6 +1,7 @@
 package app
 
 import (
+	"github.com/spf13/viper"
 	abci "github.com/tendermint/tendermint/abci/types"
 
 	"github.com/coinexchain/cet-sdk/msgqueue"
@@ -32,3 +33,23 @@ func discardKafkaEvents(events []abci.Event) []abci.Event {
 	}
 	return nonKafkaEvents
 }
+
+// WithPubMsgConfig calls create while the msg queue is configured by viper to publish the
+// topics to the brokers, as the producer reads them when the app is created. The viper
+// keys are restored after, it is for the apps of the tests.
+func WithPubMsgConfig(brokers []string, topics string, create func() *CetChainApp) *CetChainApp {
+	keys := []string{msgqueue.FlagBrokers, msgqueue.FlagTopics, msgqueue.FlagFeatureToggle}
+	saved := make([]interface{}, len(keys))
+	for i, key := range keys {
+		saved[i] = viper.Get(key)
+	}
+	defer func() {
+		for i, key := range keys {
+			viper.Set(key, saved[i])
+		}
+	}()
+	viper.Set(msgqueue.FlagBrokers, brokers)
+	viper.Set(msgqueue.FlagTopics, topics)
+	viper.Set(msgqueue.FlagFeatureToggle, true)
+	return create()
+}
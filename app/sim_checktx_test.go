@@ -0,0 +1,329 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+	"github.com/tendermint/tendermint/libs/log"
+	tmtypes "github.com/tendermint/tendermint/types"
+
+	bam "github.com/cosmos/cosmos-sdk/baseapp"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+
+	"github.com/coinexchain/cet-sdk/modules/bankx"
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+// The CheckTx simulation sends the txs of random clients through CetChainApp.CheckTx into a
+// FIFO mempool, from which blocks take the oldest txs, and the rest of the mempool is rechecked
+// after every commit as Tendermint does. The node's min gas price is above the one of the
+// chain, and a plugin rejects the txs with a memo, so PreCheckTx, the unconfirmed tx limit and
+// the mempool fees are all exercised.
+const (
+	checkTxSimGas          = 1000000
+	checkTxSimFee          = 100
+	checkTxSimLowFee       = 5 // below the node's min gas price, above the chain's
+	checkTxSimMinGasPrices = "0.00001cet"
+	checkTxSimRejectMemo   = "reject"
+	checkTxSimBlockTime    = 5 * time.Second
+	checkTxSimMinLock      = 10 * 60 // long enough for a locked send to wait in the mempool
+	checkTxSimMaxLock      = 30 * 60 // within the free time of bankx
+)
+
+var errCheckTxSimRejected = sdk.NewError("plugin", 2000, "rejected by the simulation plugin")
+
+// checkTxSimPlugin rejects the txs with checkTxSimRejectMemo
+type checkTxSimPlugin struct{}
+
+func (checkTxSimPlugin) PreCheckTx(req abci.RequestCheckTx, txDecoder sdk.TxDecoder, _ log.Logger) sdk.Error {
+	tx, err := txDecoder(req.Tx)
+	if err != nil {
+		return err
+	}
+	if stdTx, ok := tx.(auth.StdTx); ok && stdTx.Memo == checkTxSimRejectMemo {
+		return errCheckTxSimRejected
+	}
+	return nil
+}
+
+func (checkTxSimPlugin) Name() string {
+	return "CheckTxSimPlugin"
+}
+
+type checkTxSimClient struct {
+	key    crypto.PrivKey
+	addr   sdk.AccAddress
+	accNum uint64
+	seq    uint64 // the sequence of the next tx
+
+	pending *mempoolTx  // the tx in the mempool
+	waiting []*txIntent // the valid sends not in the mempool yet
+}
+
+// txIntent is a valid send of a client, which must get into a block eventually
+type txIntent struct {
+	to     sdk.AccAddress
+	amount int64
+	unlock int64 // the unlock time of a locked send, 0 for a normal one
+	since  int64 // the height when the client wants to send it
+}
+
+type mempoolTx struct {
+	bz     []byte
+	client *checkTxSimClient
+	intent *txIntent
+}
+
+type checkTxSim struct {
+	t       *testing.T
+	r       *rand.Rand
+	app     *CetChainApp
+	encode  sdk.TxEncoder
+	clients []*checkTxSimClient
+	mempool []*mempoolTx
+	header  abci.Header
+
+	maxBlockTxs int
+	maxWait     int64
+	stats       map[string]int
+
+	// onEndBlock is called after every EndBlock, when the pub msgs of the block are complete
+	onEndBlock func()
+}
+
+func newCheckTxSim(t *testing.T, r *rand.Rand, numClients, maxBlockTxs int) *checkTxSim {
+	sim := &checkTxSim{t: t, r: r, maxBlockTxs: maxBlockTxs, stats: make(map[string]int)}
+	accs := make([]auth.BaseAccount, numClients)
+	for i := 0; i < numClients; i++ {
+		seed := make([]byte, 32)
+		r.Read(seed)
+		key := secp256k1.GenPrivKeySecp256k1(seed)
+		c := &checkTxSimClient{key: key, addr: sdk.AccAddress(key.PubKey().Address())}
+		sim.clients = append(sim.clients, c)
+		accs[i] = auth.BaseAccount{Address: c.addr, Coins: dex.NewCetCoins(1e14)}
+	}
+
+	// the msg queue is open, so the pub msgs of the txs and the modules are produced
+	sim.app = newPubMsgApp()
+	initChain(sim.app, func(genState *GenesisState) {
+		addGenesisAccounts(genState, accs...)
+		genState.AuthData = GetDefaultAuthGenesisState()
+	})
+	sim.app.Holder.SetPlugin(checkTxSimPlugin{})
+	bam.SetMinGasPrices(checkTxSimMinGasPrices)(sim.app.BaseApp)
+	sim.app.enableUnconfirmedLimit = true
+	sim.app.account2UnconfirmedTx = NewAccount2UnconfirmedTx(DefaultLimitTime)
+	sim.encode = auth.DefaultTxEncoder(sim.app.cdc)
+
+	ctx := sim.app.NewContext(false, abci.Header{})
+	for _, c := range sim.clients {
+		c.accNum = sim.app.accountKeeper.GetAccount(ctx, c.addr).GetAccountNumber()
+	}
+	sim.header = abci.Header{ChainID: testChainID, Time: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	// CheckTx sees the genesis state after the first commit
+	sim.runBlock(0, checkTxSimBlockTime)
+	return sim
+}
+
+func (sim *checkTxSim) signSend(c *checkTxSimClient, to sdk.AccAddress, amount, unlock, fee int64, memo string) []byte {
+	msg := bankx.NewMsgSend(c.addr, to, dex.NewCetCoins(amount), unlock)
+	tx := newStdTxBuilder().Msgs(msg).GasAndFee(checkTxSimGas, fee).
+		AccNumSeqKey(c.accNum, c.seq, c.key).BuildTxWithMemo(memo)
+	bz, err := sim.encode(tx)
+	require.NoError(sim.t, err)
+	return bz
+}
+
+// submit sends the first waiting intent of c to CheckTx, it waits if c has a tx in the mempool
+func (sim *checkTxSim) submit(c *checkTxSimClient) {
+	intent := c.waiting[0]
+	bz := sim.signSend(c, intent.to, intent.amount, intent.unlock, checkTxSimFee, "")
+	res := sim.app.CheckTx(abci.RequestCheckTx{Tx: bz})
+	switch {
+	case res.IsOK():
+		c.waiting = c.waiting[1:]
+		c.pending = &mempoolTx{bz: bz, client: c, intent: intent}
+		c.seq++
+		sim.mempool = append(sim.mempool, c.pending)
+		sim.stats["admitted"]++
+	case res.Code == uint32(CodeTooManyUnconfirmedTx):
+		require.NotNil(sim.t, c.pending, "a valid tx of %s is limited without an unconfirmed tx", c.addr)
+		sim.stats["unconfirmed_limited"]++
+	default:
+		require.Fail(sim.t, "valid tx rejected", "height %d: %s", sim.header.Height, res.Log)
+	}
+}
+
+// submitInvalid sends a tx which CheckTx must reject, for its memo or its fee
+func (sim *checkTxSim) submitInvalid(c *checkTxSimClient, to sdk.AccAddress) {
+	var res abci.ResponseCheckTx
+	if sim.r.Intn(2) == 0 {
+		res = sim.app.CheckTx(abci.RequestCheckTx{Tx: sim.signSend(c, to, 1, 0, checkTxSimFee, checkTxSimRejectMemo)})
+		require.Equal(sim.t, uint32(errCheckTxSimRejected.Code()), res.Code, res.Log)
+		sim.stats["plugin_rejected"]++
+	} else {
+		res = sim.app.CheckTx(abci.RequestCheckTx{Tx: sim.signSend(c, to, 1, 0, checkTxSimLowFee, "")})
+		require.False(sim.t, res.IsOK(), "a tx below the min gas price is admitted")
+		sim.stats["low_fee_rejected"]++
+	}
+}
+
+// step makes the clients send attempts txs and then runs a block, a client with a tx in the
+// mempool may send more, which wait for the tx to be committed
+func (sim *checkTxSim) step(attempts int) {
+	height := sim.header.Height + 1
+	for _, c := range sim.clients {
+		if len(c.waiting) != 0 {
+			sim.submit(c)
+		}
+	}
+	for i := 0; i < attempts; i++ {
+		c := sim.clients[sim.r.Intn(len(sim.clients))]
+		to := sim.clients[sim.r.Intn(len(sim.clients))].addr
+		if sim.r.Intn(10) < 2 {
+			sim.submitInvalid(c, to)
+			continue
+		}
+		intent := &txIntent{to: to, amount: 1 + sim.r.Int63n(1e6), since: height}
+		if sim.r.Intn(4) == 0 {
+			intent.unlock = sim.header.Time.Unix() + checkTxSimMinLock + sim.r.Int63n(checkTxSimMaxLock-checkTxSimMinLock)
+		}
+		c.waiting = append(c.waiting, intent)
+		if len(c.waiting) == 1 {
+			sim.submit(c)
+		}
+	}
+
+	// sometimes the proposer includes nothing, and sometimes a block comes late, so the txs
+	// stay in the mempool until their unconfirmed entries expire
+	numTxs := sim.maxBlockTxs
+	if sim.r.Intn(10) == 0 {
+		numTxs = 0
+	}
+	interval := checkTxSimBlockTime
+	if sim.r.Intn(20) == 0 {
+		interval = time.Duration(DefaultLimitTime+sim.r.Intn(DefaultLimitTime)) * time.Second
+	}
+	sim.runBlock(numTxs, interval)
+}
+
+func (sim *checkTxSim) runBlock(numTxs int, interval time.Duration) {
+	sim.header.Height++
+	sim.header.Time = sim.header.Time.Add(interval)
+	sim.app.BeginBlock(abci.RequestBeginBlock{Header: sim.header})
+	if numTxs > len(sim.mempool) {
+		numTxs = len(sim.mempool)
+	}
+	for _, mtx := range sim.mempool[:numTxs] {
+		res := sim.app.DeliverTx(abci.RequestDeliverTx{Tx: mtx.bz})
+		require.True(sim.t, res.IsOK(), "height %d: %s", sim.header.Height, res.Log)
+		if mtx.client.pending == mtx {
+			mtx.client.pending = nil
+		}
+		if wait := sim.header.Height - mtx.intent.since; wait > sim.maxWait {
+			sim.maxWait = wait
+		}
+		sim.stats["included"]++
+	}
+	sim.mempool = sim.mempool[numTxs:]
+	sim.app.EndBlock(abci.RequestEndBlock{Height: sim.header.Height})
+	if sim.onEndBlock != nil {
+		sim.onEndBlock()
+	}
+	require.NoError(sim.t, brokenInvariants(sim.app))
+	sim.app.Commit()
+	sim.recheck()
+	sim.checkUnconfirmed()
+}
+
+// recheck drops the txs which are not valid after the commit, the clients send them again
+func (sim *checkTxSim) recheck() {
+	kept := sim.mempool[:0]
+	for _, mtx := range sim.mempool {
+		res := sim.app.CheckTx(abci.RequestCheckTx{Tx: mtx.bz, Type: abci.CheckTxType_Recheck})
+		if res.IsOK() {
+			kept = append(kept, mtx)
+			continue
+		}
+		c := mtx.client
+		if c.pending == mtx {
+			c.pending = nil
+		}
+		c.waiting = append([]*txIntent{mtx.intent}, c.waiting...)
+		c.seq = sim.app.accountKeeper.GetAccount(sim.app.NewContext(true, abci.Header{}), c.addr).GetSequence()
+		sim.stats["recheck_dropped"]++
+	}
+	sim.mempool = kept
+}
+
+// checkUnconfirmed asserts that every unconfirmed entry is of a tx in the mempool, or expires
+// and is swept in time. The expired entries are dropped by the sweeps, which run at the commits
+// more than SweepPeriod apart, so an entry is not older than limitTime at the last sweep.
+func (sim *checkTxSim) checkUnconfirmed() {
+	now := sim.header.Time.Unix()
+	limit := sim.app.account2UnconfirmedTx.limitTime
+	sinceSweep := now - sim.app.account2UnconfirmedTx.lastSweepTime
+	require.True(sim.t, sinceSweep <= SweepPeriod,
+		"height %d: no sweep for %d seconds", sim.header.Height, sinceSweep)
+	for addr, utx := range sim.app.account2UnconfirmedTx.auMap {
+		inMempool := false
+		for _, mtx := range sim.mempool {
+			if string(mtx.client.addr) == addr && bytes.Equal(tmtypes.Tx(mtx.bz).Hash(), utx.HashID) {
+				inMempool = true
+				break
+			}
+		}
+		if !inMempool {
+			age := now - utx.Timestamp
+			require.True(sim.t, age <= limit+sinceSweep,
+				"height %d: the unconfirmed entry of %s leaks for %d seconds", sim.header.Height, sdk.AccAddress(addr), age)
+		}
+	}
+}
+
+// drain runs blocks without new txs until every waiting intent is included, and then until
+// the unconfirmed entries are swept and the locked coins are unlocked
+func (sim *checkTxSim) drain(maxBlocks int) {
+	for i := 0; ; i++ {
+		waiting := len(sim.mempool)
+		for _, c := range sim.clients {
+			waiting += len(c.waiting)
+		}
+		if waiting == 0 {
+			break
+		}
+		require.True(sim.t, i < maxBlocks, "%d txs are starved after %d blocks", waiting, maxBlocks)
+		sim.step(0)
+	}
+	sim.runBlock(0, time.Duration(DefaultLimitTime+SweepPeriod+1)*time.Second)
+	require.Empty(sim.t, sim.app.account2UnconfirmedTx.auMap)
+	sim.runBlock(0, checkTxSimMaxLock*time.Second)
+}
+
+func TestCheckTxSimulation(t *testing.T) {
+	// a short run unless the simulation is enabled
+	blocks, attempts := 30, 20
+	if enabled {
+		blocks, attempts = numBlocks, blockSize
+	}
+	r := rand.New(rand.NewSource(seed))
+	sim := newCheckTxSim(t, r, attempts, attempts)
+	for i := 0; i < blocks; i++ {
+		sim.step(attempts)
+	}
+	sim.drain(blocks)
+
+	fmt.Printf("CheckTx simulation of %d blocks: %v, max wait %d blocks\n", blocks, sim.stats, sim.maxWait)
+	require.True(t, sim.stats["included"] > 0)
+	require.True(t, sim.stats["unconfirmed_limited"] > 0)
+	require.True(t, sim.stats["plugin_rejected"] > 0)
+	require.True(t, sim.stats["low_fee_rejected"] > 0)
+}
@@ -0,0 +1,508 @@
+package app
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/libs/log"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/cosmos/cosmos-sdk/baseapp"
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/module"
+	"github.com/cosmos/cosmos-sdk/x/simulation"
+)
+
+// An op log records a simulation as JSON lines, the header and then the entries. An operation
+// is recorded by its index in testAndRunTxs and the seed of its rand, so the replay runs the
+// same code on the same state, and the recorded msgs are only for reading and for checking the
+// replay. The requests of BeginBlock are recorded by a module inserted into the app.
+const (
+	opLogBeginBlock = "begin_block"
+	opLogOp         = "op"
+	opLogFutureOp   = "future_op"
+	opLogEndBlock   = "end_block"
+
+	opLogModuleName = "oplog"
+)
+
+type opLogHeader struct {
+	Seed     int64           `json:"seed"`
+	ChainID  string          `json:"chain_id"`
+	AppState json.RawMessage `json:"app_state"`
+	Accounts []string        `json:"accounts"` // the private keys of the simulation accounts, amino in hex
+	NumOps   int             `json:"num_ops"`
+	Commit   bool            `json:"commit"`
+}
+
+type opLogEntry struct {
+	Kind   string    `json:"kind"`
+	Height int64     `json:"height"`
+	Time   time.Time `json:"time"`
+
+	BeginBlock *abci.RequestBeginBlock `json:"begin_block,omitempty"`
+
+	// a weighted op is the Op-th one of testAndRunTxs, a future op is the Op-th one
+	// returned by the Parent-th entry
+	Op      int              `json:"op"`
+	Parent  int              `json:"parent,omitempty"`
+	Seed    int64            `json:"seed,omitempty"`
+	Route   string           `json:"route,omitempty"`
+	Name    string           `json:"name,omitempty"`
+	OK      bool             `json:"ok,omitempty"`
+	Comment string           `json:"comment,omitempty"`
+	Msg     json.RawMessage  `json:"msg,omitempty"`
+	Signers []sdk.AccAddress `json:"signers,omitempty"`
+}
+
+type opLog struct {
+	Header  opLogHeader
+	Entries []opLogEntry
+}
+
+func readOpLog(path string) (*opLog, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	l := &opLog{}
+	dec := json.NewDecoder(bufio.NewReader(file))
+	if err = dec.Decode(&l.Header); err != nil {
+		return nil, err
+	}
+	for dec.More() {
+		var entry opLogEntry
+		if err = dec.Decode(&entry); err != nil {
+			return nil, fmt.Errorf("entry %d: %s", len(l.Entries), err.Error())
+		}
+		l.Entries = append(l.Entries, entry)
+	}
+	return l, nil
+}
+
+func (l *opLog) write(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	enc := json.NewEncoder(w)
+	if err = enc.Encode(l.Header); err != nil {
+		return err
+	}
+	for _, entry := range l.Entries {
+		if err = enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func (l *opLog) prefix(n int) *opLog {
+	return &opLog{Header: l.Header, Entries: l.Entries[:n]}
+}
+
+func (l *opLog) accounts(cdc *codec.Codec) ([]simulation.Account, error) {
+	accs := make([]simulation.Account, len(l.Header.Accounts))
+	for i, s := range l.Header.Accounts {
+		bz, err := hex.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		var privKey crypto.PrivKey
+		if err = cdc.UnmarshalBinaryBare(bz, &privKey); err != nil {
+			return nil, err
+		}
+		accs[i] = simulation.Account{PrivKey: privKey, PubKey: privKey.PubKey(),
+			Address: sdk.AccAddress(privKey.PubKey().Address())}
+	}
+	return accs, nil
+}
+
+func (entry *opLogEntry) setOpMsg(cdc *codec.Codec, opMsg simulation.OperationMsg) {
+	entry.Route, entry.Name, entry.OK, entry.Msg = opMsg.Route, opMsg.Name, opMsg.OK, opMsg.Msg
+	if entry.Comment == "" {
+		entry.Comment = opMsg.Comment
+	}
+	var msg sdk.Msg
+	if len(opMsg.Msg) != 0 && cdc.UnmarshalJSON(opMsg.Msg, &msg) == nil {
+		entry.Signers = msg.GetSigners()
+	}
+}
+
+// sameOpMsg tells whether the replay of an entry returns what was recorded
+func (entry *opLogEntry) sameOpMsg(opMsg simulation.OperationMsg) bool {
+	return entry.Route == opMsg.Route && entry.Name == opMsg.Name && entry.OK == opMsg.OK &&
+		string(entry.Msg) == string(opMsg.Msg)
+}
+
+//______________________________________________________________________________
+
+// opLogRecorder writes an op log while a simulation runs, a nil recorder records nothing
+type opLogRecorder struct {
+	path    string
+	cdc     *codec.Codec
+	file    *os.File
+	w       *bufio.Writer
+	enc     *json.Encoder
+	header  opLogHeader
+	entries int
+	err     error
+}
+
+func newOpLogRecorder(path string, app *CetChainApp, seed int64, commit bool) (*opLogRecorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	rec := &opLogRecorder{path: path, cdc: app.cdc, file: file, w: bufio.NewWriter(file)}
+	rec.enc = json.NewEncoder(rec.w)
+	rec.header.Seed, rec.header.Commit = seed, commit
+
+	m := opLogModule{rec: rec}
+	app.mm.Modules[opLogModuleName] = m
+	app.mm.OrderBeginBlockers = append([]string{opLogModuleName}, app.mm.OrderBeginBlockers...)
+	app.mm.OrderEndBlockers = append(app.mm.OrderEndBlockers, opLogModuleName)
+	return rec, nil
+}
+
+func (rec *opLogRecorder) appStateFn(appStateFn simulation.AppStateFn) simulation.AppStateFn {
+	if rec == nil {
+		return appStateFn
+	}
+	return func(r *rand.Rand, accs []simulation.Account) (json.RawMessage, []simulation.Account, string, time.Time) {
+		appState, accs, chainID, genesisTimestamp := appStateFn(r, accs)
+		rec.header.ChainID, rec.header.AppState = chainID, appState
+		for _, acc := range accs {
+			rec.header.Accounts = append(rec.header.Accounts, hex.EncodeToString(rec.cdc.MustMarshalBinaryBare(acc.PrivKey)))
+		}
+		if err := rec.enc.Encode(rec.header); err != nil && rec.err == nil {
+			rec.err = err
+		}
+		return appState, accs, chainID, genesisTimestamp
+	}
+}
+
+func (rec *opLogRecorder) operations(ops []simulation.WeightedOperation) []simulation.WeightedOperation {
+	if rec == nil {
+		return ops
+	}
+	rec.header.NumOps = len(ops)
+	recorded := make([]simulation.WeightedOperation, len(ops))
+	for i, op := range ops {
+		recorded[i] = simulation.WeightedOperation{Weight: op.Weight, Op: rec.record(op.Op, opLogEntry{Kind: opLogOp, Op: i})}
+	}
+	return recorded
+}
+
+// record runs op with a rand of a seed drawn from r, so the seed is enough to replay it
+func (rec *opLogRecorder) record(op simulation.Operation, template opLogEntry) simulation.Operation {
+	return func(r *rand.Rand, app *baseapp.BaseApp, ctx sdk.Context, accs []simulation.Account) (
+		opMsg simulation.OperationMsg, fOps []simulation.FutureOperation, err error) {
+
+		entry := template
+		entry.Seed = r.Int63()
+		entry.Height, entry.Time = ctx.BlockHeight(), ctx.BlockTime()
+		defer func() {
+			if p := recover(); p != nil {
+				entry.Comment = fmt.Sprintf("panic: %v", p)
+				rec.add(entry)
+				rec.flush()
+				panic(p)
+			}
+		}()
+		opMsg, fOps, err = op(rand.New(rand.NewSource(entry.Seed)), app, ctx, accs)
+		if err != nil {
+			entry.Comment = fmt.Sprintf("error: %s", err.Error())
+		}
+		entry.setOpMsg(rec.cdc, opMsg)
+		parent := rec.add(entry)
+		for i := range fOps {
+			fOps[i].Op = rec.record(fOps[i].Op, opLogEntry{Kind: opLogFutureOp, Op: i, Parent: parent})
+		}
+		return opMsg, fOps, err
+	}
+}
+
+func (rec *opLogRecorder) add(entry opLogEntry) int {
+	if err := rec.enc.Encode(entry); err != nil && rec.err == nil {
+		rec.err = err
+	}
+	rec.entries++
+	return rec.entries - 1
+}
+
+func (rec *opLogRecorder) flush() {
+	if err := rec.w.Flush(); err != nil && rec.err == nil {
+		rec.err = err
+	}
+}
+
+// finish closes the log, which is minimized if the simulation failed. It must be deferred
+// right after the recorder is created, to see the panics of the simulation.
+func (rec *opLogRecorder) finish(t *testing.T) {
+	p := recover()
+	rec.flush()
+	if err := rec.file.Close(); err != nil && rec.err == nil {
+		rec.err = err
+	}
+	if rec.err != nil {
+		t.Errorf("failed to record the op log: %s", rec.err.Error())
+	} else if p != nil || t.Failed() {
+		fmt.Printf("Minimizing the op log in %s...\n", rec.path)
+		minimizeOpLogFile(t, rec.path)
+	}
+	if p != nil {
+		panic(p)
+	}
+}
+
+// opLogModule records the requests of BeginBlock and the ends of the blocks
+type opLogModule struct {
+	module.AppModule
+	rec *opLogRecorder
+}
+
+func (m opLogModule) Name() string {
+	return opLogModuleName
+}
+
+func (m opLogModule) BeginBlock(ctx sdk.Context, req abci.RequestBeginBlock) {
+	m.rec.add(opLogEntry{Kind: opLogBeginBlock, Height: ctx.BlockHeight(), Time: ctx.BlockTime(), BeginBlock: &req})
+}
+
+func (m opLogModule) EndBlock(ctx sdk.Context, _ abci.RequestEndBlock) []abci.ValidatorUpdate {
+	m.rec.add(opLogEntry{Kind: opLogEndBlock, Height: ctx.BlockHeight(), Time: ctx.BlockTime()})
+	m.rec.flush()
+	return nil
+}
+
+//______________________________________________________________________________
+
+func newReplayApp() *CetChainApp {
+	return NewCetChainApp(log.NewNopLogger(), dbm.NewMemDB(), nil, true, 0, fauxMerkleModeOpt)
+}
+
+// replayOpLog runs the entries of the log on a new app, the invariants are asserted after
+// BeginBlock, EndBlock and the last entry, and also after every operation with
+// -SimulateEveryOperation. It returns the first broken invariant, error or panic.
+func replayOpLog(l *opLog) (err error) {
+	app := newReplayApp()
+	ops := testAndRunTxs(app)
+	if len(ops) != l.Header.NumOps {
+		return fmt.Errorf("the log is recorded with %d operations, but there are %d", l.Header.NumOps, len(ops))
+	}
+	accs, err := l.accounts(app.cdc)
+	if err != nil {
+		return err
+	}
+	blackListedAccs := app.ModuleAccountAddrs()
+	var simAccs []simulation.Account
+	for _, acc := range accs {
+		if !blackListedAccs[acc.Address.String()] {
+			simAccs = append(simAccs, acc)
+		}
+	}
+
+	current := "InitChain"
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic at %s: %v", current, p)
+		}
+	}()
+	app.InitChain(abci.RequestInitChain{AppStateBytes: l.Header.AppState, ChainId: l.Header.ChainID})
+
+	var ctx sdk.Context
+	futureOps := make(map[int][]simulation.FutureOperation)
+	checked := true
+	for i, entry := range l.Entries {
+		current = fmt.Sprintf("entry %d (%s at height %d)", i, entry.Kind, entry.Height)
+		checked = false
+		switch entry.Kind {
+		case opLogBeginBlock:
+			app.BeginBlock(*entry.BeginBlock)
+			ctx = app.NewContext(false, entry.BeginBlock.Header)
+			checked = true
+		case opLogOp, opLogFutureOp:
+			var op simulation.Operation
+			if entry.Kind == opLogOp && entry.Op < len(ops) {
+				op = ops[entry.Op].Op
+			} else if entry.Kind == opLogFutureOp && entry.Op < len(futureOps[entry.Parent]) {
+				op = futureOps[entry.Parent][entry.Op].Op
+			}
+			if op == nil {
+				return fmt.Errorf("%s: no such operation", current)
+			}
+			opMsg, fOps, err := op(rand.New(rand.NewSource(entry.Seed)), app.BaseApp, ctx, simAccs)
+			if err != nil {
+				return fmt.Errorf("%s: %s", current, err.Error())
+			}
+			if !entry.sameOpMsg(opMsg) {
+				return fmt.Errorf("%s diverged from the log: %s", current, opMsg.String())
+			}
+			futureOps[i] = fOps
+			checked = onOperation
+		case opLogEndBlock:
+			app.EndBlock(abci.RequestEndBlock{})
+			if err := brokenInvariants(app); err != nil {
+				return fmt.Errorf("%s: %s", current, err.Error())
+			}
+			if l.Header.Commit {
+				app.Commit()
+			}
+			checked = true
+			continue
+		default:
+			return fmt.Errorf("%s: unknown entry", current)
+		}
+		if checked {
+			if err := brokenInvariants(app); err != nil {
+				return fmt.Errorf("%s: %s", current, err.Error())
+			}
+		}
+	}
+	// the state after the last entry, unless it is checked already
+	if !checked {
+		if err := brokenInvariants(app); err != nil {
+			return fmt.Errorf("%s: %s", current, err.Error())
+		}
+	}
+	return nil
+}
+
+func brokenInvariants(app *CetChainApp) error {
+	ctx := app.NewContext(false, abci.Header{Height: app.LastBlockHeight() + 1})
+	var broken []string
+	for _, inv := range app.crisisKeeper.Invariants() {
+		if res, stop := inv(ctx); stop {
+			broken = append(broken, res)
+		}
+	}
+	if len(broken) != 0 {
+		return fmt.Errorf("invariants broken\n%s", strings.Join(broken, "\n"))
+	}
+	return nil
+}
+
+// shortestFailingPrefix bisects to the least n in [0, total] for which fails(n) returns an
+// error, assuming that a prefix fails if a shorter one fails. The failure is nil if even
+// fails(total) returns nil.
+func shortestFailingPrefix(total int, fails func(n int) error) (n int, failure error) {
+	if failure = fails(total); failure == nil {
+		return total, nil
+	}
+	if err := fails(0); err != nil {
+		return 0, err
+	}
+	passed, failed := 0, total
+	for failed-passed > 1 {
+		mid := passed + (failed-passed)/2
+		if err := fails(mid); err != nil {
+			failed, failure = mid, err
+		} else {
+			passed = mid
+		}
+	}
+	return failed, failure
+}
+
+// minimizeOpLogFile writes the shortest failing prefix of the log to path.min
+func minimizeOpLogFile(t *testing.T, path string) {
+	l, err := readOpLog(path)
+	if err != nil {
+		t.Errorf("failed to read the op log: %s", err.Error())
+		return
+	}
+	n, failure := shortestFailingPrefix(len(l.Entries), func(n int) error {
+		return replayOpLog(l.prefix(n))
+	})
+	if failure == nil {
+		t.Errorf("the replay of %s does not fail", path)
+		return
+	}
+	minPath := path + ".min"
+	if err = l.prefix(n).write(minPath); err != nil {
+		t.Errorf("failed to write the minimized op log: %s", err.Error())
+		return
+	}
+	fmt.Printf("The shortest failing prefix of the op log has %d of %d entries, written to %s:\n%s\n",
+		n, len(l.Entries), minPath, failure)
+}
+
+// TestReplayOpLog replays an op log recorded by -OpLog, which is minimized if it fails
+func TestReplayOpLog(t *testing.T) {
+	if replayOpLogPath == "" {
+		t.Skip("Skipping op log replay")
+	}
+	l, err := readOpLog(replayOpLogPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = replayOpLog(l); err != nil {
+		fmt.Println(err)
+		minimizeOpLogFile(t, replayOpLogPath)
+		t.FailNow()
+	}
+}
+
+func TestOpLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oplog")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ops.log")
+
+	app := newReplayApp()
+	rec, err := newOpLogRecorder(path, app, 6, true)
+	require.NoError(t, err)
+	_, _, err = simulation.SimulateFromSeed(t, ioutil.Discard, app.BaseApp, rec.appStateFn(appStateFn), 6,
+		rec.operations(testAndRunTxs(app)), invariants(app), 1, 3, 0, 20,
+		"", false, true, false, false, false, app.ModuleAccountAddrs())
+	require.NoError(t, err)
+	rec.finish(t)
+
+	l, err := readOpLog(path)
+	require.NoError(t, err)
+	require.Equal(t, opLogBeginBlock, l.Entries[0].Kind)
+	require.Equal(t, opLogEndBlock, l.Entries[len(l.Entries)-1].Kind)
+	require.NoError(t, replayOpLog(l))
+	require.NoError(t, replayOpLog(l.prefix(len(l.Entries)/2)))
+
+	// another seed runs the op differently
+	for i := range l.Entries {
+		if l.Entries[i].OK {
+			l.Entries[i].Seed++
+			err = replayOpLog(l)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), fmt.Sprintf("entry %d", i))
+			break
+		}
+	}
+
+	n, failure := shortestFailingPrefix(100, func(n int) error {
+		if n >= 42 {
+			return fmt.Errorf("failed at %d", n)
+		}
+		return nil
+	})
+	require.Equal(t, 42, n)
+	require.EqualError(t, failure, "failed at 42")
+	_, failure = shortestFailingPrefix(100, func(int) error { return nil })
+	require.NoError(t, failure)
+}
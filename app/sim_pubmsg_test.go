@@ -0,0 +1,362 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/cosmos/cosmos-sdk/baseapp"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	"github.com/cosmos/cosmos-sdk/x/simulation"
+
+	"github.com/coinexchain/cet-sdk/modules/authx"
+)
+
+const pubMsgSimTopics = "auth,authx,bancorlite,bank,bankx,comment,market"
+
+// newPubMsgApp creates an app like newApp, but the msg queue is configured by viper when the
+// app is created, so the keepers publish the msgs of the modules too
+func newPubMsgApp() *CetChainApp {
+	return WithPubMsgConfig([]string{"nop"}, pubMsgSimTopics, func() *CetChainApp {
+		return NewCetChainApp(log.NewNopLogger(), dbm.NewMemDB(), nil, true, 10000)
+	})
+}
+
+// recordPubMsgDigests makes app append the digest of the pub msgs of every block to digests,
+// it must be called before the app is sealed
+func recordPubMsgDigests(app *CetChainApp, digests *[]string) {
+	app.SetEndBlocker(func(ctx sdk.Context, req abci.RequestEndBlock) abci.ResponseEndBlock {
+		ret := app.endBlocker(ctx, req)
+		*digests = append(*digests, fmt.Sprintf("%d:%s", req.Height, pubMsgsDigest(app.pubMsgs)))
+		return ret
+	})
+}
+
+func pubMsgsDigest(msgs []PubMsg) string {
+	h := sha256.New()
+	for _, msg := range msgs {
+		h.Write(msg.Key)
+		h.Write([]byte{0})
+		h.Write(msg.Value)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// pubMsgConsumer rebuilds the balances of some accounts from the pub msgs, as a consumer of
+// the msg queue does, starting from their genesis balances. The fee of a tx is taken from
+// its notify_tx, the sends from its transfers, and the locked coins from the send_lock_coins
+// and notify_unlock msgs of bankx and authx.
+//
+// With followBalanceChanges, the balances are taken from the balance_change msgs only, for
+// the full simulation, whose txs move coins in more ways than a consumer can follow. The
+// balance before a change must be the rebuilt one, so a change without a msg is found.
+type pubMsgConsumer struct {
+	coins  map[string]sdk.Coins
+	locked map[string]sdk.Coins
+
+	followBalanceChanges bool
+}
+
+func newPubMsgConsumer() *pubMsgConsumer {
+	return &pubMsgConsumer{coins: make(map[string]sdk.Coins), locked: make(map[string]sdk.Coins)}
+}
+
+func (c *pubMsgConsumer) track(addr sdk.AccAddress, coins sdk.Coins) {
+	c.coins[addr.String()] = coins
+	c.locked[addr.String()] = sdk.Coins{}
+}
+
+func (c *pubMsgConsumer) move(from, to map[string]sdk.Coins, fromAddr, toAddr string, amount sdk.Coins) error {
+	if coins, ok := from[fromAddr]; ok {
+		left, neg := coins.SafeSub(amount)
+		if neg {
+			return fmt.Errorf("%s spends %s of %s", fromAddr, amount, coins)
+		}
+		from[fromAddr] = left
+	}
+	if coins, ok := to[toAddr]; ok {
+		to[toAddr] = coins.Add(amount)
+	}
+	return nil
+}
+
+func (c *pubMsgConsumer) consume(msgs []PubMsg) error {
+	for _, msg := range msgs {
+		var err error
+		if c.followBalanceChanges {
+			if string(msg.Key) == "balance_change" {
+				err = c.followBalanceChange(msg.Value)
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %v: %s", msg.Key, err, msg.Value)
+			}
+			continue
+		}
+		switch string(msg.Key) {
+		case "notify_tx":
+			err = c.consumeTx(msg.Value)
+		case "send_lock_coins":
+			var send struct {
+				ToAddress sdk.AccAddress `json:"to_address"`
+				Amount    sdk.Coins      `json:"amount"`
+			}
+			if err = json.Unmarshal(msg.Value, &send); err == nil {
+				// the coins were transferred to the recipient, and then locked
+				to := send.ToAddress.String()
+				err = c.move(c.coins, c.locked, to, to, send.Amount)
+			}
+		case "notify_unlock":
+			var unlock authx.NotificationUnlock
+			if err = json.Unmarshal(msg.Value, &unlock); err == nil {
+				addr := unlock.Address.String()
+				err = c.move(c.locked, c.coins, addr, addr, unlock.Unlocked)
+			}
+		case "balance_change":
+			err = c.consumeBalanceChange(msg.Value)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v: %s", msg.Key, err, msg.Value)
+		}
+	}
+	return nil
+}
+
+func (c *pubMsgConsumer) consumeTx(bz []byte) error {
+	var n4s NotificationTx
+	if err := json.Unmarshal(bz, &n4s); err != nil {
+		return err
+	}
+	var tx struct {
+		Fee struct {
+			Amount sdk.Coins `json:"amount"`
+		} `json:"fee"`
+	}
+	if err := json.Unmarshal([]byte(n4s.TxJSON), &tx); err != nil {
+		return err
+	}
+	if err := c.move(c.coins, nil, n4s.Signers[0].String(), "", tx.Fee.Amount); err != nil {
+		return err
+	}
+	for _, transfer := range n4s.Transfers {
+		// a send of bankx has an extra transfer event with the sender only
+		if transfer.Amount == "" {
+			continue
+		}
+		amount, err := sdk.ParseCoins(transfer.Amount)
+		if err != nil {
+			return err
+		}
+		if err = c.move(c.coins, c.coins, transfer.Sender, transfer.Recipient, amount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// consumeBalanceChange checks a balance_change against the balance rebuilt so far, which
+// comes after the other msgs of the block
+func (c *pubMsgConsumer) consumeBalanceChange(bz []byte) error {
+	var change NotificationBalanceChange
+	if err := json.Unmarshal(bz, &change); err != nil {
+		return err
+	}
+	if _, ok := c.coins[change.Address]; !ok {
+		return nil
+	}
+	if rebuilt := c.balance(change.Address); rebuilt.Coins != change.After.Coins ||
+		rebuilt.LockedCoins != change.After.LockedCoins {
+		return fmt.Errorf("rebuilt balance %+v", rebuilt)
+	}
+	return nil
+}
+
+func (c *pubMsgConsumer) followBalanceChange(bz []byte) error {
+	var change NotificationBalanceChange
+	if err := json.Unmarshal(bz, &change); err != nil {
+		return err
+	}
+	if _, ok := c.coins[change.Address]; !ok {
+		return nil
+	}
+	if rebuilt := c.balance(change.Address); rebuilt.Coins != change.Before.Coins ||
+		rebuilt.LockedCoins != change.Before.LockedCoins {
+		return fmt.Errorf("rebuilt balance %+v", rebuilt)
+	}
+	coins, err := sdk.ParseCoins(change.After.Coins)
+	if err != nil {
+		return err
+	}
+	locked, err := sdk.ParseCoins(change.After.LockedCoins)
+	if err != nil {
+		return err
+	}
+	c.coins[change.Address], c.locked[change.Address] = coins, locked
+	return nil
+}
+
+func (c *pubMsgConsumer) balance(addr string) AccountBalance {
+	return AccountBalance{Coins: c.coins[addr].String(), LockedCoins: c.locked[addr].String()}
+}
+
+// check compares the rebuilt balances with the ones in the keepers
+func (c *pubMsgConsumer) check(app *CetChainApp, ctx sdk.Context) error {
+	for addr := range c.coins {
+		accAddr, _ := sdk.AccAddressFromBech32(addr)
+		balance := app.getBalance(ctx, accAddr)
+		if rebuilt := c.balance(addr); rebuilt.Coins != balance.Coins || rebuilt.LockedCoins != balance.LockedCoins {
+			return fmt.Errorf("height %d: %s has %+v, but %+v is rebuilt from the pub msgs",
+				ctx.BlockHeight(), addr, balance, rebuilt)
+		}
+	}
+	return nil
+}
+
+// touchOpAccounts adds the addresses in the msgs of the operations to the touched accounts,
+// as DeliverTx does with the signers and the events of a tx, since an operation runs the
+// handler of its msg directly and its events are dropped
+func touchOpAccounts(app *CetChainApp, ops simulation.WeightedOperations) simulation.WeightedOperations {
+	touched := make(simulation.WeightedOperations, len(ops))
+	for i, op := range ops {
+		touched[i] = simulation.WeightedOperation{Weight: op.Weight, Op: touchAccountsOfOp(app, op.Op)}
+	}
+	return touched
+}
+
+func touchAccountsOfOp(app *CetChainApp, op simulation.Operation) simulation.Operation {
+	return func(r *rand.Rand, bApp *baseapp.BaseApp, ctx sdk.Context, accs []simulation.Account) (
+		opMsg simulation.OperationMsg, fOps []simulation.FutureOperation, err error) {
+
+		opMsg, fOps, err = op(r, bApp, ctx, accs)
+		if opMsg.OK && app.isBalanceChangeEnabled() {
+			var doc interface{}
+			if json.Unmarshal(opMsg.Msg, &doc) == nil {
+				app.touchedAccounts.addFromJSON(doc)
+				touchOperators(app.touchedAccounts, doc)
+			}
+		}
+		for i := range fOps {
+			fOps[i].Op = touchAccountsOfOp(app, fOps[i].Op)
+		}
+		return opMsg, fOps, err
+	}
+}
+
+// touchOperators adds the accounts of the validator operator addresses in doc, which are
+// the signers of the validator msgs, like withdraw_validator_commission
+func touchOperators(ta *TouchedAccounts, doc interface{}) {
+	switch v := doc.(type) {
+	case string:
+		if valAddr, err := sdk.ValAddressFromBech32(v); err == nil {
+			ta.Add(sdk.AccAddress(valAddr))
+		}
+	case []interface{}:
+		for _, e := range v {
+			touchOperators(ta, e)
+		}
+	case map[string]interface{}:
+		for _, e := range v {
+			touchOperators(ta, e)
+		}
+	}
+}
+
+// touchChangedAccounts adds the accounts whose balances are changed by op to the touched
+// accounts, for an operation without a msg, like deduct_fee, which stands for the fee paid
+// by the signer of a tx
+func touchChangedAccounts(app *CetChainApp, op simulation.Operation) simulation.Operation {
+	return func(r *rand.Rand, bApp *baseapp.BaseApp, ctx sdk.Context, accs []simulation.Account) (
+		opMsg simulation.OperationMsg, fOps []simulation.FutureOperation, err error) {
+
+		if !app.isBalanceChangeEnabled() {
+			return op(r, bApp, ctx, accs)
+		}
+		before := make([]AccountBalance, len(accs))
+		for i, acc := range accs {
+			before[i] = app.getBalance(ctx, acc.Address)
+		}
+		opMsg, fOps, err = op(r, bApp, ctx, accs)
+		for i, acc := range accs {
+			if app.getBalance(ctx, acc.Address) != before[i] {
+				app.touchedAccounts.Add(acc.Address)
+			}
+		}
+		return opMsg, fOps, err
+	}
+}
+
+// consumePubMsgsOfBlocks makes a consumer follow the balance changes of the accounts in the
+// genesis state, and check them against the keepers at the end of every block. It must be
+// called before the app is sealed.
+func consumePubMsgsOfBlocks(t *testing.T, app *CetChainApp) {
+	consumer := newPubMsgConsumer()
+	consumer.followBalanceChanges = true
+	moduleAccs := app.ModuleAccountAddrs()
+	app.SetInitChainer(func(ctx sdk.Context, req abci.RequestInitChain) abci.ResponseInitChain {
+		ret := app.initChainer(ctx, req)
+		app.accountKeeper.IterateAccounts(ctx, func(acc auth.Account) bool {
+			if !moduleAccs[acc.GetAddress().String()] {
+				consumer.track(acc.GetAddress(), acc.GetCoins())
+				accx, _ := app.accountXKeeper.GetAccountX(ctx, acc.GetAddress())
+				for _, lc := range accx.LockedCoins {
+					consumer.locked[acc.GetAddress().String()] = consumer.locked[acc.GetAddress().String()].Add(sdk.Coins{lc.Coin})
+				}
+			}
+			return false
+		})
+		return ret
+	})
+	app.SetEndBlocker(func(ctx sdk.Context, req abci.RequestEndBlock) abci.ResponseEndBlock {
+		ret := app.endBlocker(ctx, req)
+		require.NoError(t, consumer.consume(app.pubMsgs), "height %d", ctx.BlockHeight())
+		require.NoError(t, consumer.check(app, ctx))
+		return ret
+	})
+}
+
+// runPubMsgSimulation runs the CheckTx simulation, where the txs are delivered through
+// CetChainApp.DeliverTx and produce notify_tx, and returns the pub msg digests of its blocks
+func runPubMsgSimulation(t *testing.T, seed int64, blocks, attempts int) []string {
+	sim := newCheckTxSim(t, rand.New(rand.NewSource(seed)), attempts, attempts)
+	digests := make([]string, 0, blocks)
+	consumer := newPubMsgConsumer()
+	ctx := sim.app.NewContext(true, abci.Header{})
+	for _, c := range sim.clients {
+		consumer.track(c.addr, sim.app.accountKeeper.GetAccount(ctx, c.addr).GetCoins())
+	}
+	sim.onEndBlock = func() {
+		digests = append(digests, fmt.Sprintf("%d:%s", sim.header.Height, pubMsgsDigest(sim.app.pubMsgs)))
+		require.NoError(t, consumer.consume(sim.app.pubMsgs))
+		require.NoError(t, consumer.check(sim.app, sim.app.NewContext(false, sim.header)))
+		for _, msg := range sim.app.pubMsgs {
+			sim.stats[string(msg.Key)]++
+		}
+	}
+	for i := 0; i < blocks; i++ {
+		sim.step(attempts)
+	}
+	sim.drain(blocks)
+	require.True(t, sim.stats["notify_tx"] > 0)
+	require.True(t, sim.stats["send_lock_coins"] > 0)
+	require.True(t, sim.stats["notify_unlock"] > 0)
+	return digests
+}
+
+func TestPubMsgSimulation(t *testing.T) {
+	// a short run unless the simulation is enabled
+	blocks, attempts := 30, 20
+	if enabled {
+		blocks, attempts = numBlocks, blockSize
+	}
+	digests := runPubMsgSimulation(t, seed, blocks, attempts)
+	require.Equal(t, digests, runPubMsgSimulation(t, seed, blocks, attempts))
+}
@@ -0,0 +1,387 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/baseapp"
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/simapp"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	distr "github.com/cosmos/cosmos-sdk/x/distribution"
+	"github.com/cosmos/cosmos-sdk/x/gov"
+	"github.com/cosmos/cosmos-sdk/x/simulation"
+	"github.com/cosmos/cosmos-sdk/x/slashing"
+	"github.com/cosmos/cosmos-sdk/x/staking"
+
+	"github.com/coinexchain/cet-sdk/modules/alias"
+	"github.com/coinexchain/cet-sdk/modules/asset"
+	"github.com/coinexchain/cet-sdk/modules/bancorlite"
+	"github.com/coinexchain/cet-sdk/modules/bankx"
+	"github.com/coinexchain/cet-sdk/modules/comment"
+	"github.com/coinexchain/cet-sdk/modules/distributionx"
+	"github.com/coinexchain/cet-sdk/modules/market"
+)
+
+type simOpWeight struct {
+	module string
+	weight int
+}
+
+// defaultOpWeights are the weights of the operations when no scenario is selected, and the
+// modules which the scenarios weigh them by
+var defaultOpWeights = map[string]simOpWeight{
+	simapp.OpWeightDeductFee:                                  {auth.ModuleName, 5},
+	simapp.OpWeightMsgSend:                                    {bankx.ModuleName, 100},
+	simapp.OpWeightSingleInputMsgMultiSend:                    {bankx.ModuleName, 10},
+	simapp.OpWeightMsgSetWithdrawAddress:                      {distr.ModuleName, 50},
+	simapp.OpWeightMsgWithdrawDelegationReward:                {distr.ModuleName, 50},
+	simapp.OpWeightMsgWithdrawValidatorCommission:             {distr.ModuleName, 50},
+	simapp.OpWeightSubmitVotingSlashingTextProposal:           {gov.ModuleName, 5},
+	simapp.OpWeightSubmitVotingSlashingCommunitySpendProposal: {gov.ModuleName, 5},
+	simapp.OpWeightSubmitVotingSlashingParamChangeProposal:    {gov.ModuleName, 5},
+	simapp.OpWeightMsgDeposit:                                 {gov.ModuleName, 100},
+	simapp.OpWeightMsgCreateValidator:                         {staking.ModuleName, 100},
+	simapp.OpWeightMsgEditValidator:                           {staking.ModuleName, 5},
+	simapp.OpWeightMsgDelegate:                                {staking.ModuleName, 100},
+	simapp.OpWeightMsgUndelegate:                              {staking.ModuleName, 100},
+	simapp.OpWeightMsgBeginRedelegate:                         {staking.ModuleName, 100},
+	simapp.OpWeightMsgUnjail:                                  {slashing.ModuleName, 100},
+	OpWeightMsgAliasUpdate:                                    {alias.ModuleName, 100},
+	OpWeightMsgIssueToken:                                     {asset.ModuleName, 150},
+	OpWeightMsgBurnToken:                                      {asset.ModuleName, 50},
+	OpWeightMsgTransferOwnership:                              {asset.ModuleName, 60},
+	OpWeightMsgMintToken:                                      {asset.ModuleName, 50},
+	OpWeightMsgForbidToken:                                    {asset.ModuleName, 50},
+	OpWeightMsgUnForbidToken:                                  {asset.ModuleName, 50},
+	OpWeightMsgAddTokenWhitelist:                              {asset.ModuleName, 70},
+	OpWeightMsgRemoveTokenWhitelist:                           {asset.ModuleName, 60},
+	OpWeightMsgForbidAddr:                                     {asset.ModuleName, 30},
+	OpWeightMsgUnForbidAddr:                                   {asset.ModuleName, 25},
+	OpWeightMsgModifyTokenInfo:                                {asset.ModuleName, 40},
+	OpWeightMsgBancorInit:                                     {bancorlite.ModuleName, 100},
+	OpWeightMsgBancorTrade:                                    {bancorlite.ModuleName, 100},
+	OpWeightMsgBancorCancel:                                   {bancorlite.ModuleName, 100},
+	OpWeightCreateNewThread:                                   {comment.ModuleName, 100},
+	OpWeightCreateCommentRefs:                                 {comment.ModuleName, 100},
+	OpWeightMsgDonateToCommunityPool:                          {distributionx.ModuleName, 100},
+	OpWeightMsgCreateTradingPair:                              {market.ModuleName, 100},
+	OpWeightMsgCancelTradingPair:                              {market.ModuleName, 100},
+	OpWeightMsgModifyPricePrecision:                           {market.ModuleName, 100},
+	OpWeightMsgCreateOrder:                                    {market.ModuleName, 100},
+	OpWeightMsgCancelOrder:                                    {market.ModuleName, 100},
+	OpWeightMsgSetMemoRequired:                                {bankx.ModuleName, 2},
+}
+
+// simOtherModules is the key in simScenario.modules for the modules not listed
+const simOtherModules = "*"
+
+// simScenario is a preset of the simulation, which weighs the operations by their modules and
+// shapes the random genesis for them
+type simScenario struct {
+	name        string
+	description string
+	// the percentages of the default weights by module, 100 for a module not listed
+	// unless simOtherModules is
+	modules map[string]int
+	// the weights of single operations, over the ones by module
+	weights map[string]int
+	genesis func(cdc *codec.Codec, r *rand.Rand, genesisState map[string]json.RawMessage)
+}
+
+var simScenarios = []*simScenario{
+	{
+		name:        "market-heavy",
+		description: "trading pairs and orders of many tokens, with cheap markets",
+		modules:     map[string]int{market.ModuleName: 400, asset.ModuleName: 100, simOtherModules: 10},
+		weights:     map[string]int{OpWeightMsgCreateOrder: 1000, OpWeightMsgCancelOrder: 400},
+		genesis: func(cdc *codec.Codec, r *rand.Rand, genesisState map[string]json.RawMessage) {
+			var marketGenesis market.GenesisState
+			updateSimGenesis(cdc, genesisState, market.ModuleName, &marketGenesis, func() {
+				marketGenesis.Params.CreateMarketFee = 1e8
+				marketGenesis.Params.MarketFeeMin = 1e3
+				marketGenesis.Params.FeeForZeroDeal = 1e3
+			})
+			cheapTokens(cdc, genesisState)
+		},
+	},
+	{
+		name:        "bancor-stress",
+		description: "bancor pools initialized, traded and cancelled, with cheap pools",
+		modules:     map[string]int{bancorlite.ModuleName: 500, asset.ModuleName: 50, simOtherModules: 10},
+		genesis: func(cdc *codec.Codec, r *rand.Rand, genesisState map[string]json.RawMessage) {
+			var bancorGenesis bancorlite.GenesisState
+			updateSimGenesis(cdc, genesisState, bancorlite.ModuleName, &bancorGenesis, func() {
+				bancorGenesis.Params.CreateBancorFee = 1e8
+				bancorGenesis.Params.CancelBancorFee = 1e8
+			})
+			cheapTokens(cdc, genesisState)
+		},
+	},
+	{
+		name:        "governance-churn",
+		description: "proposals, deposits and votes among validators which come and go",
+		modules: map[string]int{gov.ModuleName: 1000, staking.ModuleName: 300, slashing.ModuleName: 300,
+			distr.ModuleName: 200, simOtherModules: 10},
+		genesis: func(cdc *codec.Codec, r *rand.Rand, genesisState map[string]json.RawMessage) {
+			// the proposals end within a few blocks, which are 1.4 to 2.8 hours apart
+			var govGenesis gov.GenesisState
+			updateSimGenesis(cdc, genesisState, gov.ModuleName, &govGenesis, func() {
+				period := time.Duration(1+r.Intn(6)) * time.Hour
+				govGenesis.DepositParams.MaxDepositPeriod = period
+				govGenesis.VotingParams.VotingPeriod = period
+			})
+		},
+	},
+	{
+		name:        "token-admin",
+		description: "tokens issued and administered by their owners, with cheap tokens",
+		modules: map[string]int{asset.ModuleName: 600, alias.ModuleName: 100, comment.ModuleName: 100,
+			simOtherModules: 10},
+		weights: map[string]int{OpWeightMsgSetMemoRequired: 50},
+		genesis: func(cdc *codec.Codec, r *rand.Rand, genesisState map[string]json.RawMessage) {
+			cheapTokens(cdc, genesisState)
+		},
+	},
+}
+
+func cheapTokens(cdc *codec.Codec, genesisState map[string]json.RawMessage) {
+	var assetGenesis asset.GenesisState
+	updateSimGenesis(cdc, genesisState, asset.ModuleName, &assetGenesis, func() {
+		assetGenesis.Params.IssueTokenFee = 1e8
+		assetGenesis.Params.Issue6CharTokenFee = 1e8
+		assetGenesis.Params.Issue5CharTokenFee = 1e8
+		assetGenesis.Params.Issue4CharTokenFee = 1e8
+		assetGenesis.Params.Issue3CharTokenFee = 1e8
+		assetGenesis.Params.IssueRareTokenFee = 1e8
+	})
+}
+
+func updateSimGenesis(cdc *codec.Codec, genesisState map[string]json.RawMessage, module string,
+	state interface{}, update func()) {
+
+	cdc.MustUnmarshalJSON(genesisState[module], state)
+	update()
+	genesisState[module] = cdc.MustMarshalJSON(state)
+}
+
+func simScenarioNames() []string {
+	names := make([]string, len(simScenarios))
+	for i, s := range simScenarios {
+		names[i] = s.name
+	}
+	return names
+}
+
+// selectedScenario returns the scenario of the -Scenario flag, or nil for the default weights
+func selectedScenario() *simScenario {
+	if scenarioName == "" {
+		return nil
+	}
+	for _, s := range simScenarios {
+		if s.name == scenarioName {
+			return s
+		}
+	}
+	panic(fmt.Sprintf("unknown scenario %q, the scenarios are %s", scenarioName,
+		strings.Join(simScenarioNames(), ", ")))
+}
+
+func (s *simScenario) title() string {
+	if s == nil {
+		return "default"
+	}
+	return fmt.Sprintf("%s (%s)", s.name, s.description)
+}
+
+func (s *simScenario) weight(key string) int {
+	def, ok := defaultOpWeights[key]
+	if !ok {
+		panic("no default weight for " + key)
+	}
+	if s == nil {
+		return def.weight
+	}
+	if w, ok := s.weights[key]; ok {
+		return w
+	}
+	percent, ok := s.modules[def.module]
+	if !ok {
+		if percent, ok = s.modules[simOtherModules]; !ok {
+			percent = 100
+		}
+	}
+	// an operation is kept alive unless its module is turned off
+	if w := def.weight * percent / 100; w > 0 || percent == 0 || def.weight == 0 {
+		return w
+	}
+	return 1
+}
+
+func (s *simScenario) shapeGenesis(cdc *codec.Codec, r *rand.Rand, genesisState map[string]json.RawMessage) {
+	if s != nil && s.genesis != nil {
+		s.genesis(cdc, r, genesisState)
+	}
+}
+
+type simOpStat struct {
+	weightKey string
+	weight    int
+	ok        int
+	skipped   int
+	failed    int
+	reasons   map[string]int
+}
+
+// simOpStats counts the operations which succeeded, were skipped or failed, by the keys of
+// their weights. The future operations scheduled by them are not counted
+type simOpStats struct {
+	stats []*simOpStat
+}
+
+func newSimOpStats() *simOpStats {
+	return &simOpStats{}
+}
+
+func (stats *simOpStats) record(key string, weight int, op simulation.Operation) simulation.Operation {
+	if stats == nil {
+		return op
+	}
+	stat := &simOpStat{weightKey: key, weight: weight, reasons: make(map[string]int)}
+	stats.stats = append(stats.stats, stat)
+	return func(r *rand.Rand, app *baseapp.BaseApp, ctx sdk.Context, accs []simulation.Account) (
+		opMsg simulation.OperationMsg, fOps []simulation.FutureOperation, err error) {
+
+		opMsg, fOps, err = op(r, app, ctx, accs)
+		switch {
+		case err != nil:
+			stat.failed++
+		case opMsg.OK:
+			stat.ok++
+		default:
+			stat.skipped++
+			reason := opMsg.Comment
+			if reason == "" {
+				reason = opMsg.Name
+			}
+			stat.reasons[reason]++
+		}
+		return opMsg, fOps, err
+	}
+}
+
+// report writes a line for every operation, with the most frequent reason of its skips
+func (stats *simOpStats) report(w io.Writer, scenario string) {
+	fmt.Fprintf(w, "\nOperations of the %s scenario:\n", scenario)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "operation\tweight\tok\tskipped\tfailed\ttop skip reason")
+	for _, stat := range stats.stats {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%s\n", strings.TrimPrefix(stat.weightKey, "op_weight_"),
+			stat.weight, stat.ok, stat.skipped, stat.failed, stat.topReason())
+	}
+	tw.Flush()
+}
+
+func (stat *simOpStat) topReason() string {
+	reasons := make([]string, 0, len(stat.reasons))
+	for reason := range stat.reasons {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		ri, rj := reasons[i], reasons[j]
+		if stat.reasons[ri] != stat.reasons[rj] {
+			return stat.reasons[ri] > stat.reasons[rj]
+		}
+		return ri < rj
+	})
+	if len(reasons) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s (%d)", reasons[0], stat.reasons[reasons[0]])
+}
+
+func TestSimScenarios(t *testing.T) {
+	modules := map[string]bool{simOtherModules: true}
+	for _, def := range defaultOpWeights {
+		modules[def.module] = true
+	}
+	ops := simOperations(newApp())
+	require.Equal(t, len(defaultOpWeights), len(ops))
+	for _, op := range ops {
+		require.Contains(t, defaultOpWeights, op.weightKey)
+	}
+
+	defer func(name string) { scenarioName = name }(scenarioName)
+	for _, s := range simScenarios {
+		for module := range s.modules {
+			require.True(t, modules[module], "%s: unknown module %s", s.name, module)
+		}
+		for key := range s.weights {
+			require.Contains(t, defaultOpWeights, key, s.name)
+		}
+
+		scenarioName = s.name
+		require.Equal(t, s, selectedScenario())
+		r := rand.New(rand.NewSource(1))
+		accs := simulation.RandomAccounts(r, 10)
+		appState, _, _ := appStateRandomizedFn(r, accs, time.Now(), make(simulation.AppParams))
+		var genesisState map[string]json.RawMessage
+		require.NoError(t, MakeCodec().UnmarshalJSON(appState, &genesisState), s.name)
+		require.NoError(t, ModuleBasics.ValidateGenesis(genesisState), s.name)
+	}
+
+	scenarioName = "governance-churn"
+	require.Equal(t, 5*1000/100, selectedScenario().weight(simapp.OpWeightSubmitVotingSlashingTextProposal))
+	require.Equal(t, 100*10/100, selectedScenario().weight(OpWeightMsgCreateOrder))
+	scenarioName = "market-heavy"
+	require.Equal(t, 1000, selectedScenario().weight(OpWeightMsgCreateOrder))
+	scenarioName = ""
+	require.Equal(t, 150, selectedScenario().weight(OpWeightMsgIssueToken))
+	scenarioName = "unknown"
+	require.Panics(t, func() { selectedScenario() })
+}
+
+func TestSimOpStats(t *testing.T) {
+	var results []simulation.OperationMsg
+	op := func(r *rand.Rand, app *baseapp.BaseApp, ctx sdk.Context, accs []simulation.Account) (
+		simulation.OperationMsg, []simulation.FutureOperation, error) {
+
+		opMsg := results[0]
+		results = results[1:]
+		if opMsg.Route == "" {
+			return opMsg, nil, fmt.Errorf("failed")
+		}
+		return opMsg, nil, nil
+	}
+	stats := newSimOpStats()
+	recorded := stats.record(OpWeightMsgCreateOrder, 100, op)
+	results = []simulation.OperationMsg{
+		{Route: market.ModuleName, OK: true},
+		{Route: market.ModuleName, Comment: "no pair"},
+		simulation.NoOpMsg(market.ModuleName),
+		{Route: market.ModuleName, Comment: "no pair"},
+		{},
+	}
+	ctx := newApp().NewContext(true, abci.Header{})
+	for range results {
+		_, _, _ = recorded(nil, nil, ctx, nil)
+	}
+	require.Nil(t, (*simOpStats)(nil).record(OpWeightMsgCreateOrder, 100, nil))
+
+	var out strings.Builder
+	stats.report(&out, "test")
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Equal(t, "Operations of the test scenario:", lines[0])
+	require.Equal(t, strings.Fields("msg_create_order 100 1 3 1 no pair (2)"), strings.Fields(lines[2]))
+}
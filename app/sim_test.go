@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"math/rand"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -70,9 +71,12 @@ var (
 	lean               bool
 	commit             bool
 	period             int
-	onOperation        bool // TODO Remove in favor of binary search for invariant violation
+	onOperation        bool
 	allInvariants      bool
 	genesisTime        int64
+	opLogPath          string
+	replayOpLogPath    string
+	scenarioName       string
 )
 
 func init() {
@@ -94,19 +98,22 @@ func init() {
 	flag.BoolVar(&onOperation, "SimulateEveryOperation", false, "run slow invariants every operation")
 	flag.BoolVar(&allInvariants, "PrintAllInvariants", false, "print all invariants if a broken invariant is found")
 	flag.Int64Var(&genesisTime, "GenesisTime", 0, "override genesis UNIX time instead of using a random UNIX time")
+	flag.StringVar(&opLogPath, "OpLog", "", "record the operations of the full simulation to this file, whose shortest failing prefix is written to <file>.min if the simulation fails")
+	flag.StringVar(&replayOpLogPath, "ReplayOpLog", "", "replay the operations recorded in this file, whose shortest failing prefix is written to <file>.min if the replay fails")
+	flag.StringVar(&scenarioName, "Scenario", "", "simulate a preset of operation weights and genesis, one of "+strings.Join(simScenarioNames(), ", "))
 }
 
 // helper function for populating input for SimulateFromSeed
 // TODO: clean up this function along with the simulation refactor
-func getSimulateFromSeedInput(tb testing.TB, w io.Writer, app *CetChainApp) (
+func getSimulateFromSeedInput(tb testing.TB, w io.Writer, app *CetChainApp, rec *opLogRecorder, stats *simOpStats) (
 	testing.TB, io.Writer, *baseapp.BaseApp, simulation.AppStateFn, int64,
 	simulation.WeightedOperations, sdk.Invariants, int, int, int, int, string,
 	bool, bool, bool, bool, bool, map[string]bool) {
 
 	exportParams := exportParamsPath != ""
 
-	return tb, w, app.BaseApp, appStateFn, seed,
-		testAndRunTxs(app), invariants(app),
+	return tb, w, app.BaseApp, rec.appStateFn(appStateFn), seed,
+		rec.operations(touchOpAccounts(app, weighOperations(simOperations(app), stats))), invariants(app),
 		initialBlockHeight, numBlocks, exportParamsHeight, blockSize,
 		exportStatsPath, exportParams, commit, lean, onOperation, allInvariants, app.ModuleAccountAddrs()
 }
@@ -199,6 +206,7 @@ func appStateRandomizedFn(
 	GenIncentiveDefaultGenesisState(cdc, genesisState)
 	GenMarketDefaultGenesisState(cdc, genesisState)
 	GenStakingxDefaultGenesisState(cdc, genesisState)
+	selectedScenario().shapeGenesis(cdc, r, genesisState)
 
 	appState, err := MakeCodec().MarshalJSON(genesisState)
 	if err != nil {
@@ -316,185 +324,210 @@ func GenAssetDefaultGenesisState(cdc *codec.Codec, accs []simulation.Account, am
 	genesisState[asset.ModuleName] = cdc.MustMarshalJSON(assetGenesis)
 }
 
-// TODO: add description
-func testAndRunTxs(app *CetChainApp) []simulation.WeightedOperation {
-	cdc := MakeCodec()
-	ap := make(simulation.AppParams)
-
-	if paramsFile != "" {
-		bz, err := ioutil.ReadFile(paramsFile)
-		if err != nil {
-			panic(err)
-		}
-
-		cdc.MustUnmarshalJSON(bz, &ap)
-	}
+// simOperation is an operation of the simulation and the key of its weight
+type simOperation struct {
+	weightKey string
+	op        simulation.Operation
+}
 
-	getWeightOrDefault := getIntOrDefaultFn(ap, cdc)
-	return []simulation.WeightedOperation{
+func simOperations(app *CetChainApp) []simOperation {
+	return []simOperation{
 		{
-			Weight: getWeightOrDefault(simapp.OpWeightDeductFee, 5),
-			Op:     authsim.SimulateDeductFee(app.accountKeeper, app.supplyKeeper),
+			weightKey: simapp.OpWeightDeductFee,
+			op:        touchChangedAccounts(app, authsim.SimulateDeductFee(app.accountKeeper, app.supplyKeeper)),
 		},
 		{
-			Weight: getWeightOrDefault(simapp.OpWeightMsgSend, 100),
-			Op:     bankxsim.SimulateMsgSend(app.accountKeeper, app.bankxKeeper),
+			weightKey: simapp.OpWeightMsgSend,
+			op:        bankxsim.SimulateMsgSend(app.accountKeeper, app.bankxKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(simapp.OpWeightSingleInputMsgMultiSend, 10),
-			Op:     bankxsim.SimulateSingleInputMsgMultiSend(app.accountKeeper, app.bankxKeeper),
+			weightKey: simapp.OpWeightSingleInputMsgMultiSend,
+			op:        bankxsim.SimulateSingleInputMsgMultiSend(app.accountKeeper, app.bankxKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(simapp.OpWeightMsgSetWithdrawAddress, 50),
-			Op:     distrsim.SimulateMsgSetWithdrawAddress(app.accountKeeper, app.distrKeeper),
+			weightKey: simapp.OpWeightMsgSetWithdrawAddress,
+			op:        distrsim.SimulateMsgSetWithdrawAddress(app.accountKeeper, app.distrKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(simapp.OpWeightMsgWithdrawDelegationReward, 50),
-			Op:     distrsim.SimulateMsgWithdrawDelegatorReward(app.accountKeeper, app.distrKeeper),
+			weightKey: simapp.OpWeightMsgWithdrawDelegationReward,
+			op:        distrsim.SimulateMsgWithdrawDelegatorReward(app.accountKeeper, app.distrKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(simapp.OpWeightMsgWithdrawValidatorCommission, 50),
-			Op:     distrsim.SimulateMsgWithdrawValidatorCommission(app.accountKeeper, app.distrKeeper),
+			weightKey: simapp.OpWeightMsgWithdrawValidatorCommission,
+			op:        distrsim.SimulateMsgWithdrawValidatorCommission(app.accountKeeper, app.distrKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(simapp.OpWeightSubmitVotingSlashingTextProposal, 5),
-			Op:     govsim.SimulateSubmittingVotingAndSlashingForProposal(app.govKeeper, govsim.SimulateTextProposalContent),
+			weightKey: simapp.OpWeightSubmitVotingSlashingTextProposal,
+			op:        govsim.SimulateSubmittingVotingAndSlashingForProposal(app.govKeeper, govsim.SimulateTextProposalContent),
 		},
 		{
-			Weight: getWeightOrDefault(simapp.OpWeightSubmitVotingSlashingCommunitySpendProposal, 5),
-			Op:     govsim.SimulateSubmittingVotingAndSlashingForProposal(app.govKeeper, distrsim.SimulateCommunityPoolSpendProposalContent(app.distrKeeper)),
+			weightKey: simapp.OpWeightSubmitVotingSlashingCommunitySpendProposal,
+			op:        govsim.SimulateSubmittingVotingAndSlashingForProposal(app.govKeeper, distrsim.SimulateCommunityPoolSpendProposalContent(app.distrKeeper)),
 		},
 		{
-			Weight: getWeightOrDefault(simapp.OpWeightSubmitVotingSlashingParamChangeProposal, 5),
-			Op:     govsim.SimulateSubmittingVotingAndSlashingForProposal(app.govKeeper, paramsim.SimulateParamChangeProposalContent),
+			weightKey: simapp.OpWeightSubmitVotingSlashingParamChangeProposal,
+			op:        govsim.SimulateSubmittingVotingAndSlashingForProposal(app.govKeeper, paramsim.SimulateParamChangeProposalContent),
 		},
 		{
-			Weight: getWeightOrDefault(simapp.OpWeightMsgDeposit, 100),
-			Op:     govsim.SimulateMsgDeposit(app.govKeeper),
+			weightKey: simapp.OpWeightMsgDeposit,
+			op:        govsim.SimulateMsgDeposit(app.govKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(simapp.OpWeightMsgCreateValidator, 100),
-			Op:     stakingsim.SimulateMsgCreateValidator(app.accountKeeper, app.stakingKeeper),
+			weightKey: simapp.OpWeightMsgCreateValidator,
+			op:        stakingsim.SimulateMsgCreateValidator(app.accountKeeper, app.stakingKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(simapp.OpWeightMsgEditValidator, 5),
-			Op:     stakingsim.SimulateMsgEditValidator(app.stakingKeeper),
+			weightKey: simapp.OpWeightMsgEditValidator,
+			op:        stakingsim.SimulateMsgEditValidator(app.stakingKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(simapp.OpWeightMsgDelegate, 100),
-			Op:     stakingsim.SimulateMsgDelegate(app.accountKeeper, app.stakingKeeper),
+			weightKey: simapp.OpWeightMsgDelegate,
+			op:        stakingsim.SimulateMsgDelegate(app.accountKeeper, app.stakingKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(simapp.OpWeightMsgUndelegate, 100),
-			Op:     stakingsim.SimulateMsgUndelegate(app.accountKeeper, app.stakingKeeper),
+			weightKey: simapp.OpWeightMsgUndelegate,
+			op:        stakingsim.SimulateMsgUndelegate(app.accountKeeper, app.stakingKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(simapp.OpWeightMsgBeginRedelegate, 100),
-			Op:     stakingsim.SimulateMsgBeginRedelegate(app.accountKeeper, app.stakingKeeper),
+			weightKey: simapp.OpWeightMsgBeginRedelegate,
+			op:        stakingsim.SimulateMsgBeginRedelegate(app.accountKeeper, app.stakingKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(simapp.OpWeightMsgUnjail, 100),
-			Op:     slashingsim.SimulateMsgUnjail(app.slashingKeeper),
+			weightKey: simapp.OpWeightMsgUnjail,
+			op:        slashingsim.SimulateMsgUnjail(app.slashingKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(OpWeightMsgAliasUpdate, 100),
-			Op:     aliassim.SimulateMsgAliasUpdate(app.aliasKeeper),
+			weightKey: OpWeightMsgAliasUpdate,
+			op:        aliassim.SimulateMsgAliasUpdate(app.aliasKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(OpWeightMsgIssueToken, 150),
-			Op:     assetsim.SimulateMsgIssueToken(app.assetKeeper),
+			weightKey: OpWeightMsgIssueToken,
+			op:        assetsim.SimulateMsgIssueToken(app.assetKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(OpWeightMsgBurnToken, 50),
-			Op:     assetsim.SimulateMsgBurnToken(app.assetKeeper),
+			weightKey: OpWeightMsgBurnToken,
+			op:        assetsim.SimulateMsgBurnToken(app.assetKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(OpWeightMsgTransferOwnership, 60),
-			Op:     assetsim.SimulateMsgTransferOwnership(app.assetKeeper),
+			weightKey: OpWeightMsgTransferOwnership,
+			op:        assetsim.SimulateMsgTransferOwnership(app.assetKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(OpWeightMsgMintToken, 50),
-			Op:     assetsim.SimulateMsgMintToken(app.assetKeeper),
+			weightKey: OpWeightMsgMintToken,
+			op:        assetsim.SimulateMsgMintToken(app.assetKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(OpWeightMsgForbidToken, 50),
-			Op:     assetsim.SimulateMsgForbidToken(app.assetKeeper),
+			weightKey: OpWeightMsgForbidToken,
+			op:        assetsim.SimulateMsgForbidToken(app.assetKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(OpWeightMsgUnForbidToken, 50),
-			Op:     assetsim.SimulateMsgUnForbidToken(app.assetKeeper),
+			weightKey: OpWeightMsgUnForbidToken,
+			op:        assetsim.SimulateMsgUnForbidToken(app.assetKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(OpWeightMsgAddTokenWhitelist, 70),
-			Op:     assetsim.SimulateMsgAddTokenWhitelist(app.assetKeeper),
+			weightKey: OpWeightMsgAddTokenWhitelist,
+			op:        assetsim.SimulateMsgAddTokenWhitelist(app.assetKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(OpWeightMsgRemoveTokenWhitelist, 60),
-			Op:     assetsim.SimulateMsgRemoveTokenWhitelist(app.assetKeeper),
+			weightKey: OpWeightMsgRemoveTokenWhitelist,
+			op:        assetsim.SimulateMsgRemoveTokenWhitelist(app.assetKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(OpWeightMsgForbidAddr, 30),
-			Op:     assetsim.SimulateMsgForbidAddr(app.assetKeeper),
+			weightKey: OpWeightMsgForbidAddr,
+			op:        assetsim.SimulateMsgForbidAddr(app.assetKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(OpWeightMsgUnForbidAddr, 25),
-			Op:     assetsim.SimulateMsgUnForbidAddr(app.assetKeeper),
+			weightKey: OpWeightMsgUnForbidAddr,
+			op:        assetsim.SimulateMsgUnForbidAddr(app.assetKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(OpWeightMsgModifyTokenInfo, 40),
-			Op:     assetsim.SimulateMsgModifyTokenInfo(app.assetKeeper),
+			weightKey: OpWeightMsgModifyTokenInfo,
+			op:        assetsim.SimulateMsgModifyTokenInfo(app.assetKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(OpWeightMsgBancorInit, 100),
-			Op:     bancorsim.SimulateMsgBancorInit(app.assetKeeper, app.bancorKeeper),
+			weightKey: OpWeightMsgBancorInit,
+			op:        bancorsim.SimulateMsgBancorInit(app.assetKeeper, app.bancorKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(OpWeightMsgBancorTrade, 100),
-			Op:     bancorsim.SimulateMsgBancorTrade(app.accountKeeper, app.bancorKeeper),
+			weightKey: OpWeightMsgBancorTrade,
+			op:        bancorsim.SimulateMsgBancorTrade(app.accountKeeper, app.bancorKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(OpWeightMsgBancorCancel, 100),
-			Op:     bancorsim.SimulateMsgBancorCancel(app.bancorKeeper),
+			weightKey: OpWeightMsgBancorCancel,
+			op:        bancorsim.SimulateMsgBancorCancel(app.bancorKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(OpWeightCreateNewThread, 100),
-			Op:     commentsim.SimulateCreateNewThread(app.commentKeeper, app.assetKeeper, app.accountKeeper),
+			weightKey: OpWeightCreateNewThread,
+			op:        commentsim.SimulateCreateNewThread(app.commentKeeper, app.assetKeeper, app.accountKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(OpWeightCreateCommentRefs, 100),
-			Op:     commentsim.SimulateCreateCommentRefs(app.commentKeeper, app.assetKeeper, app.accountKeeper),
+			weightKey: OpWeightCreateCommentRefs,
+			op:        commentsim.SimulateCreateCommentRefs(app.commentKeeper, app.assetKeeper, app.accountKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(OpWeightMsgDonateToCommunityPool, 100),
-			Op:     distrxim.SimulateMsgDonateToCommunityPool(app.accountKeeper, app.distrxKeeper),
+			weightKey: OpWeightMsgDonateToCommunityPool,
+			op:        distrxim.SimulateMsgDonateToCommunityPool(app.accountKeeper, app.distrxKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(OpWeightMsgCreateTradingPair, 100),
-			Op:     marketsim.SimulateMsgCreateTradingPair(app.marketKeeper, app.assetKeeper),
+			weightKey: OpWeightMsgCreateTradingPair,
+			op:        marketsim.SimulateMsgCreateTradingPair(app.marketKeeper, app.assetKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(OpWeightMsgCancelTradingPair, 100),
-			Op:     marketsim.SimulateMsgCancelTradingPair(app.marketKeeper),
+			weightKey: OpWeightMsgCancelTradingPair,
+			op:        marketsim.SimulateMsgCancelTradingPair(app.marketKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(OpWeightMsgModifyPricePrecision, 100),
-			Op:     marketsim.SimulateMsgModifyPricePrecision(app.marketKeeper),
+			weightKey: OpWeightMsgModifyPricePrecision,
+			op:        marketsim.SimulateMsgModifyPricePrecision(app.marketKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(OpWeightMsgCreateOrder, 100),
-			Op:     marketsim.SimulateMsgCreateOrder(app.marketKeeper, app.accountKeeper),
+			weightKey: OpWeightMsgCreateOrder,
+			op:        marketsim.SimulateMsgCreateOrder(app.marketKeeper, app.accountKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(OpWeightMsgCancelOrder, 100),
-			Op:     marketsim.SimulateMsgCancelOrder(app.marketKeeper),
+			weightKey: OpWeightMsgCancelOrder,
+			op:        marketsim.SimulateMsgCancelOrder(app.marketKeeper),
 		},
 		{
-			Weight: getWeightOrDefault(OpWeightMsgSetMemoRequired, 2),
-			Op:     bankxsim.SimulateMsgSetMemoRequired(app.bankxKeeper),
+			weightKey: OpWeightMsgSetMemoRequired,
+			op:        bankxsim.SimulateMsgSetMemoRequired(app.bankxKeeper),
 		},
 	}
 }
 
+// testAndRunTxs returns the weighted operations of the simulation, one for each of
+// simOperations and in the same order, whatever their weights are, an op whose weight is zero
+// is kept in its place. The op log records an op by its index in this list, so the replay of
+// a log runs the same ops as long as simOperations is not reordered.
+func testAndRunTxs(app *CetChainApp) []simulation.WeightedOperation {
+	return weighOperations(simOperations(app), nil)
+}
+
+// weighOperations takes the weights of ops from the params file, or else from the selected
+// scenario and defaultOpWeights, and counts the results of ops in stats if it is not nil
+func weighOperations(ops []simOperation, stats *simOpStats) []simulation.WeightedOperation {
+	cdc := MakeCodec()
+	ap := make(simulation.AppParams)
+
+	if paramsFile != "" {
+		bz, err := ioutil.ReadFile(paramsFile)
+		if err != nil {
+			panic(err)
+		}
+
+		cdc.MustUnmarshalJSON(bz, &ap)
+	}
+
+	getWeightOrDefault := getIntOrDefaultFn(ap, cdc)
+	scenario := selectedScenario()
+	weighted := make([]simulation.WeightedOperation, len(ops))
+	for i, op := range ops {
+		weight := getWeightOrDefault(op.weightKey, scenario.weight(op.weightKey))
+		weighted[i] = simulation.WeightedOperation{Weight: weight, Op: stats.record(op.weightKey, weight, op.op)}
+	}
+	return weighted
+}
+
 func getIntOrDefaultFn(ap simulation.AppParams, cdc *codec.Codec) func(string, int) int {
 	return func(key string, defaultValue int) int {
 		var v int
@@ -536,7 +569,7 @@ func BenchmarkFullAppSimulation(b *testing.B) {
 
 	// Run randomized simulation
 	// TODO: parameterize numbers, save for a later PR
-	_, params, simErr := simulation.SimulateFromSeed(getSimulateFromSeedInput(b, os.Stdout, app))
+	_, params, simErr := simulation.SimulateFromSeed(getSimulateFromSeedInput(b, os.Stdout, app, nil, nil))
 
 	// export state and params before the simulation error is checked
 	if exportStatePath != "" {
@@ -602,11 +635,26 @@ func TestFullAppSimulation(t *testing.T) {
 		os.RemoveAll(dir)
 	}()
 
-	app := NewCetChainApp(logger, db, nil, true, 0, fauxMerkleModeOpt)
+	// the balances are rebuilt from the pub msgs of every block
+	app := WithPubMsgConfig([]string{"nop"}, pubMsgSimTopics, func() *CetChainApp {
+		return NewCetChainApp(logger, db, nil, false, 0, fauxMerkleModeOpt)
+	})
+	consumePubMsgsOfBlocks(t, app)
+	require.NoError(t, app.LoadLatestVersion(app.keyMain))
 	require.Equal(t, "CoinExChainApp", app.Name())
 
+	var rec *opLogRecorder
+	if opLogPath != "" {
+		var err error
+		rec, err = newOpLogRecorder(opLogPath, app, seed, commit)
+		require.NoError(t, err)
+		defer rec.finish(t)
+	}
+
 	// Run randomized simulation
-	_, params, simErr := simulation.SimulateFromSeed(getSimulateFromSeedInput(t, os.Stdout, app))
+	stats := newSimOpStats()
+	_, params, simErr := simulation.SimulateFromSeed(getSimulateFromSeedInput(t, os.Stdout, app, rec, stats))
+	stats.report(os.Stdout, selectedScenario().title())
 
 	// export state and params before the simulation error is checked
 	if exportStatePath != "" {
@@ -664,7 +712,7 @@ func TestAppImportExport(t *testing.T) {
 	require.Equal(t, "CoinExChainApp", app.Name())
 
 	// Run randomized simulation
-	_, simParams, simErr := simulation.SimulateFromSeed(getSimulateFromSeedInput(t, os.Stdout, app))
+	_, simParams, simErr := simulation.SimulateFromSeed(getSimulateFromSeedInput(t, os.Stdout, app, nil, nil))
 
 	// export state and simParams before the simulation error is checked
 	if exportStatePath != "" {
@@ -782,7 +830,7 @@ func TestAppSimulationAfterImport(t *testing.T) {
 	require.Equal(t, "CoinExChainApp", app.Name())
 
 	// Run randomized simulation
-	stopEarly, params, simErr := simulation.SimulateFromSeed(getSimulateFromSeedInput(t, os.Stdout, app))
+	stopEarly, params, simErr := simulation.SimulateFromSeed(getSimulateFromSeedInput(t, os.Stdout, app, nil, nil))
 
 	// export state and params before the simulation error is checked
 	if exportStatePath != "" {
@@ -843,7 +891,7 @@ func TestAppSimulationAfterImport(t *testing.T) {
 	})
 
 	// Run randomized simulation on imported app
-	_, _, err = simulation.SimulateFromSeed(getSimulateFromSeedInput(t, os.Stdout, newApp))
+	_, _, err = simulation.SimulateFromSeed(getSimulateFromSeedInput(t, os.Stdout, newApp, nil, nil))
 	require.Nil(t, err)
 }
 
@@ -857,13 +905,18 @@ func TestAppStateDeterminism(t *testing.T) {
 	numSeeds := 3
 	numTimesToRunPerSeed := 5
 	appHashList := make([]json.RawMessage, numTimesToRunPerSeed)
+	pubMsgDigestsList := make([][]string, numTimesToRunPerSeed)
 
 	for i := 0; i < numSeeds; i++ {
 		seed := rand.Int63()
 		for j := 0; j < numTimesToRunPerSeed; j++ {
 			logger := log.NewNopLogger()
 			db := dbm.NewMemDB()
-			app := NewCetChainApp(logger, db, nil, false, 0)
+			app := WithPubMsgConfig([]string{"nop"}, pubMsgSimTopics, func() *CetChainApp {
+				return NewCetChainApp(logger, db, nil, false, 0)
+			})
+			pubMsgDigestsList[j] = nil
+			recordPubMsgDigests(app, &pubMsgDigestsList[j])
 			// intercept InitChainer to create fee_collector account
 			app.SetInitChainer(func(ctx sdk.Context, req abci.RequestInitChain) abci.ResponseInitChain {
 				app.supplyKeeper.GetModuleAccount(app.NewContext(false, abci.Header{}), auth.FeeCollectorName)
@@ -891,6 +944,7 @@ func TestAppStateDeterminism(t *testing.T) {
 		}
 		for k := 1; k < numTimesToRunPerSeed; k++ {
 			require.Equal(t, appHashList[0], appHashList[k], "appHash list: %v", appHashList)
+			require.Equal(t, pubMsgDigestsList[0], pubMsgDigestsList[k], "the pub msgs of the attempts differ")
 		}
 	}
 }
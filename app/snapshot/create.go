@@ -0,0 +1,168 @@
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+
+	tmtypes "github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// DefaultChunkSize is the default size limit of the chunk files
+const DefaultChunkSize = 64 << 20
+
+// Create writes the IAVL nodes of the named stores at height to chunk files in dir, and a
+// manifest with their checksums. header is the one of height+1, which has the app hash.
+func Create(db dbm.DB, height int64, storeNames []string, header *tmtypes.Header,
+	dir string, chunkSize int64) (*Manifest, error) {
+
+	ci, err := loadCommitInfo(db, height)
+	if err != nil {
+		return nil, err
+	}
+	committed := make(map[string][]byte, len(ci.StoreInfos))
+	for _, si := range ci.StoreInfos {
+		committed[si.Name] = si.Core.CommitID.Hash
+	}
+	if len(committed) != len(storeNames) {
+		return nil, fmt.Errorf("%d stores are committed at height %d, but %d are mounted",
+			len(committed), height, len(storeNames))
+	}
+
+	m := &Manifest{Height: height, AppHash: ci.Hash(), Header: header}
+	for _, name := range storeNames {
+		rootHash, ok := committed[name]
+		if !ok {
+			return nil, fmt.Errorf("store %s is not committed at height %d", name, height)
+		}
+		root := storeDB(db, name).Get(rootKey(height))
+		if root == nil {
+			return nil, fmt.Errorf("store %s has no root at height %d, which may have been pruned", name, height)
+		}
+		if !bytes.Equal(root, rootHash) {
+			return nil, fmt.Errorf("root of store %s does not match the commit info", name)
+		}
+		m.Stores = append(m.Stores, Store{Name: name, RootHash: rootHash})
+	}
+	if err = m.Verify(); err != nil {
+		return nil, err
+	}
+
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	w := &chunkWriter{dir: dir, limit: chunkSize}
+	for i := range m.Stores {
+		store := &m.Stores[i]
+		if store.Nodes, err = writeTree(w, uint64(i), storeDB(db, store.Name), store.RootHash); err != nil {
+			return nil, err
+		}
+	}
+	if m.Chunks, err = w.close(); err != nil {
+		return nil, err
+	}
+	return m, writeManifest(dir, m)
+}
+
+// writeTree writes the nodes from the root, a parent is always written before its children
+func writeTree(w *chunkWriter, storeIndex uint64, sdb dbm.DB, rootHash []byte) (count int64, err error) {
+	if len(rootHash) == 0 {
+		return 0, nil
+	}
+	stack := [][]byte{rootHash}
+	for len(stack) != 0 {
+		h := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		bz := sdb.Get(nodeKey(h))
+		if bz == nil {
+			return count, fmt.Errorf("missing node %X", h)
+		}
+		n, err := decodeNode(bz)
+		if err != nil {
+			return count, err
+		}
+		if err = w.write(storeIndex, h, bz); err != nil {
+			return count, err
+		}
+		count++
+		if !n.isLeaf() {
+			stack = append(stack, n.rightHash, n.leftHash)
+		}
+	}
+	return count, nil
+}
+
+// chunkWriter writes the records of nodes, a record is the index of its store, the hash
+// and the bytes of the node. A new chunk file is started when one reaches the size limit.
+type chunkWriter struct {
+	dir    string
+	limit  int64
+	chunks []Chunk
+
+	file   *os.File
+	buf    *bufio.Writer
+	hasher hash.Hash
+	size   int64
+	record bytes.Buffer
+}
+
+func (w *chunkWriter) write(storeIndex uint64, h, bz []byte) error {
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+	w.record.Reset()
+	writeUvarint(&w.record, storeIndex)
+	writeBytes(&w.record, h)
+	writeBytes(&w.record, bz)
+	if _, err := w.buf.Write(w.record.Bytes()); err != nil {
+		return err
+	}
+	w.hasher.Write(w.record.Bytes())
+	w.size += int64(w.record.Len())
+	if w.size >= w.limit {
+		return w.finish()
+	}
+	return nil
+}
+
+func (w *chunkWriter) open() (err error) {
+	name := fmt.Sprintf("chunk-%06d", len(w.chunks))
+	if w.file, err = os.Create(filepath.Join(w.dir, name)); err != nil {
+		return err
+	}
+	w.buf = bufio.NewWriter(w.file)
+	w.hasher = sha256.New()
+	w.size = 0
+	w.chunks = append(w.chunks, Chunk{File: name})
+	return nil
+}
+
+func (w *chunkWriter) finish() error {
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	chunk := &w.chunks[len(w.chunks)-1]
+	chunk.Size = w.size
+	chunk.SHA256 = w.hasher.Sum(nil)
+	w.file = nil
+	return nil
+}
+
+func (w *chunkWriter) close() ([]Chunk, error) {
+	if w.file != nil {
+		if err := w.finish(); err != nil {
+			return nil, err
+		}
+	}
+	return w.chunks, nil
+}
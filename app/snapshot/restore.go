@@ -0,0 +1,133 @@
+package snapshot
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	dbm "github.com/tendermint/tm-db"
+)
+
+// Restore rebuilds the stores in db from the snapshot in dir. Every node is checked against
+// its hash, and every tree against the root hash in the manifest, before the commit info is
+// written, so db can not be loaded if the snapshot is broken.
+func Restore(db dbm.DB, dir string) (*Manifest, error) {
+	if db.Has([]byte(latestVersionKey)) {
+		return nil, errors.New("the db is not empty")
+	}
+	m, err := ReadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err = m.Verify(); err != nil {
+		return nil, err
+	}
+
+	r := newTreeRestorer(db, m)
+	for _, chunk := range m.Chunks {
+		bz, err := ioutil.ReadFile(filepath.Join(dir, chunk.File))
+		if err != nil {
+			return nil, err
+		}
+		if sum := sha256.Sum256(bz); int64(len(bz)) != chunk.Size || !bytes.Equal(sum[:], chunk.SHA256) {
+			return nil, fmt.Errorf("checksum mismatch of %s", chunk.File)
+		}
+		if err = r.restoreChunk(bz); err != nil {
+			return nil, fmt.Errorf("%s: %s", chunk.File, err.Error())
+		}
+	}
+	if err = r.finish(); err != nil {
+		return nil, err
+	}
+	saveCommitInfo(db, commitInfoFromStores(m.Height, m.Stores))
+	return m, nil
+}
+
+type treeRestorer struct {
+	m       *Manifest
+	dbs     []dbm.DB
+	pending []map[string]bool // the nodes expected in each store
+	counts  []int64
+}
+
+func newTreeRestorer(db dbm.DB, m *Manifest) *treeRestorer {
+	r := &treeRestorer{m: m}
+	for _, store := range m.Stores {
+		pending := make(map[string]bool)
+		if len(store.RootHash) != 0 {
+			pending[string(store.RootHash)] = true
+		}
+		r.dbs = append(r.dbs, storeDB(db, store.Name))
+		r.pending = append(r.pending, pending)
+		r.counts = append(r.counts, 0)
+	}
+	return r
+}
+
+func (r *treeRestorer) restoreChunk(bz []byte) error {
+	batches := make([]dbm.Batch, len(r.dbs))
+	for i, sdb := range r.dbs {
+		batches[i] = sdb.NewBatch()
+		defer batches[i].Close()
+	}
+	reader := bytes.NewReader(bz)
+	for reader.Len() != 0 {
+		idx, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return err
+		}
+		if idx >= uint64(len(r.dbs)) {
+			return fmt.Errorf("invalid store index %d", idx)
+		}
+		h, err := readBytes(reader)
+		if err != nil {
+			return err
+		}
+		nodeBz, err := readBytes(reader)
+		if err != nil {
+			return err
+		}
+		if !r.pending[idx][string(h)] {
+			return fmt.Errorf("unexpected node %X of store %s", h, r.m.Stores[idx].Name)
+		}
+		n, err := decodeNode(nodeBz)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(n.hash(), h) {
+			return fmt.Errorf("hash mismatch of node %X of store %s", h, r.m.Stores[idx].Name)
+		}
+		delete(r.pending[idx], string(h))
+		if !n.isLeaf() {
+			r.pending[idx][string(n.leftHash)] = true
+			r.pending[idx][string(n.rightHash)] = true
+		}
+		batches[idx].Set(nodeKey(h), nodeBz)
+		r.counts[idx]++
+	}
+	for _, batch := range batches {
+		batch.Write()
+	}
+	return nil
+}
+
+func (r *treeRestorer) finish() error {
+	for i, store := range r.m.Stores {
+		if len(r.pending[i]) != 0 {
+			return fmt.Errorf("%d nodes of store %s are missing", len(r.pending[i]), store.Name)
+		}
+		if r.counts[i] != store.Nodes {
+			return fmt.Errorf("store %s has %d nodes, but %d are restored", store.Name, store.Nodes, r.counts[i])
+		}
+		root := []byte(store.RootHash)
+		if root == nil {
+			root = []byte{}
+		}
+		r.dbs[i].SetSync(rootKey(r.m.Height), root)
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package snapshot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// Rollback is the result of rolling the stores back to Height from LatestHeight
+type Rollback struct {
+	Height       int64           `json:"height"`
+	LatestHeight int64           `json:"latest_height"`
+	AppHash      cmn.HexBytes    `json:"app_hash"`
+	Stores       []StoreRollback `json:"stores"`
+}
+
+// StoreRollback counts what is deleted from a store, Roots are the versions after the height,
+// Nodes are the ones created after the height and Orphans are the orphan records
+// which expire at or after the height, as the nodes they point to are alive again.
+type StoreRollback struct {
+	Name    string `json:"name"`
+	Roots   int    `json:"roots"`
+	Nodes   int    `json:"nodes"`
+	Orphans int    `json:"orphans"`
+}
+
+// RollbackStores makes height the latest version of the stores in db, as if the later
+// versions were never committed. Nothing is written when dryRun is true, or when height
+// is already the latest version, so an interrupted rollback can be run again.
+func RollbackStores(db dbm.DB, height int64, dryRun bool) (*Rollback, error) {
+	latest, err := loadLatestVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	if height <= 0 || height > latest {
+		return nil, fmt.Errorf("height %d is not between 0 and the latest height %d", height, latest)
+	}
+	ci, err := loadCommitInfo(db, height)
+	if err != nil {
+		return nil, err
+	}
+	for _, si := range ci.StoreInfos {
+		root := storeDB(db, si.Name).Get(rootKey(height))
+		if root == nil {
+			return nil, fmt.Errorf("store %s has no root at height %d, which may have been pruned", si.Name, height)
+		}
+		if !bytes.Equal(root, si.Core.CommitID.Hash) {
+			return nil, fmt.Errorf("root of store %s does not match the commit info", si.Name)
+		}
+	}
+	if height == latest {
+		return &Rollback{Height: height, LatestHeight: latest, AppHash: ci.Hash()}, nil
+	}
+	latestCI, err := loadCommitInfo(db, latest)
+	if err != nil {
+		return nil, err
+	}
+
+	// the stores added after height are rolled back as well, so they are empty again
+	var names []string
+	seen := make(map[string]bool)
+	for _, si := range append(ci.StoreInfos, latestCI.StoreInfos...) {
+		if !seen[si.Name] {
+			seen[si.Name] = true
+			names = append(names, si.Name)
+		}
+	}
+	sort.Strings(names)
+
+	r := &Rollback{Height: height, LatestHeight: latest, AppHash: ci.Hash()}
+	for _, name := range names {
+		sr, err := rollbackStore(storeDB(db, name), height, dryRun)
+		if err != nil {
+			return nil, fmt.Errorf("store %s: %s", name, err.Error())
+		}
+		sr.Name = name
+		r.Stores = append(r.Stores, sr)
+	}
+	if dryRun {
+		return r, nil
+	}
+
+	batch := db.NewBatch()
+	defer batch.Close()
+	for version := height + 1; version <= latest; version++ {
+		batch.Delete([]byte(fmt.Sprintf(commitInfoKeyFmt, version)))
+	}
+	batch.Set([]byte(latestVersionKey), cdc.MustMarshalBinaryLengthPrefixed(height))
+	batch.WriteSync()
+	return r, nil
+}
+
+func loadLatestVersion(db dbm.DB) (latest int64, err error) {
+	bz := db.Get([]byte(latestVersionKey))
+	if bz == nil {
+		return 0, fmt.Errorf("no version is committed")
+	}
+	err = cdc.UnmarshalBinaryLengthPrefixed(bz, &latest)
+	return
+}
+
+// rollbackStore deletes the versions after height from an IAVL store, a node of a later
+// version is reachable from a later root through nodes of later versions only, or is orphaned
+// after height, as the version of a node is never lower than the ones of its children
+func rollbackStore(sdb dbm.DB, height int64, dryRun bool) (sr StoreRollback, err error) {
+	var roots, orphans [][]byte
+	nodes := make(map[string]bool)
+	var stack [][]byte
+
+	itr := sdb.Iterator(rootKey(height+1), []byte{'r' + 1})
+	for ; itr.Valid(); itr.Next() {
+		roots = append(roots, itr.Key())
+		if len(itr.Value()) != 0 {
+			stack = append(stack, itr.Value())
+		}
+	}
+	itr.Close()
+
+	// an orphan key is 'o', the version it expires at, the version it was created at and its hash
+	itr = sdb.Iterator(orphanKeyPrefix(height), []byte{'o' + 1})
+	for ; itr.Valid(); itr.Next() {
+		key := itr.Key()
+		if len(key) < 17 {
+			itr.Close()
+			return sr, fmt.Errorf("invalid orphan key %X", key)
+		}
+		orphans = append(orphans, key)
+		if int64(binary.BigEndian.Uint64(key[9:17])) > height {
+			stack = append(stack, key[17:])
+		}
+	}
+	itr.Close()
+
+	for len(stack) != 0 {
+		h := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if nodes[string(h)] {
+			continue
+		}
+		bz := sdb.Get(nodeKey(h))
+		if bz == nil {
+			// deleted by pruning
+			continue
+		}
+		n, err := decodeNode(bz)
+		if err != nil {
+			return sr, err
+		}
+		if n.version <= height {
+			continue
+		}
+		nodes[string(h)] = true
+		if !n.isLeaf() {
+			stack = append(stack, n.leftHash, n.rightHash)
+		}
+	}
+
+	sr = StoreRollback{Roots: len(roots), Nodes: len(nodes), Orphans: len(orphans)}
+	if dryRun {
+		return sr, nil
+	}
+	batch := sdb.NewBatch()
+	defer batch.Close()
+	for _, key := range roots {
+		batch.Delete(key)
+	}
+	for _, key := range orphans {
+		batch.Delete(key)
+	}
+	for h := range nodes {
+		batch.Delete(nodeKey([]byte(h)))
+	}
+	batch.WriteSync()
+	return sr, nil
+}
+
+func orphanKeyPrefix(toVersion int64) []byte {
+	key := make([]byte, 9)
+	key[0] = 'o'
+	binary.BigEndian.PutUint64(key[1:], uint64(toVersion))
+	return key
+}
@@ -0,0 +1,75 @@
+package snapshot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	dbm "github.com/tendermint/tm-db"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// dumpDB returns all the key/values in db, the store infos in a commit info are not sorted,
+// so its hash is returned instead
+func dumpDB(db dbm.DB) map[string]string {
+	m := make(map[string]string)
+	itr := db.Iterator(nil, nil)
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		key := string(itr.Key())
+		m[key] = string(itr.Value())
+		var ci commitInfo
+		if strings.HasPrefix(key, "s/") && !strings.HasPrefix(key, "s/k:") && key != latestVersionKey {
+			cdc.MustUnmarshalBinaryLengthPrefixed(itr.Value(), &ci)
+			m[key] = string(ci.Hash())
+		}
+	}
+	return m
+}
+
+func TestRollbackStores(t *testing.T) {
+	db := dbm.NewMemDB()
+	header := prepareStores(t, db, 4)
+	expected := dbm.NewMemDB()
+	prepareStores(t, expected, 2)
+
+	before := dumpDB(db)
+	r, err := RollbackStores(db, 2, true)
+	require.Nil(t, err)
+	require.Equal(t, before, dumpDB(db))
+	require.Equal(t, int64(4), r.LatestHeight)
+	require.Equal(t, []byte(header.AppHash), []byte(r.AppHash))
+	require.Equal(t, 3, len(r.Stores))
+	require.Equal(t, 2, r.Stores[0].Roots)
+	require.True(t, r.Stores[0].Nodes > 0 && r.Stores[0].Orphans > 0)
+
+	_, err = RollbackStores(db, 5, false)
+	require.NotNil(t, err)
+	_, err = RollbackStores(db, 0, false)
+	require.NotNil(t, err)
+
+	r2, err := RollbackStores(db, 2, false)
+	require.Nil(t, err)
+	require.Equal(t, r, r2)
+	// the same as if the versions after 2 were never committed
+	require.Equal(t, dumpDB(expected), dumpDB(db))
+	r3, err := RollbackStores(db, 2, false)
+	require.Nil(t, err)
+	require.Equal(t, int64(2), r3.LatestHeight)
+	require.Empty(t, r3.Stores)
+
+	cms, keys := newMultiStore(db)
+	require.Equal(t, sdk.CommitID{Version: 2, Hash: header.AppHash}, cms.LastCommitID())
+	require.Nil(t, cms.GetKVStore(keys[0]).Get([]byte("acc3-0")))
+	cms.GetKVStore(keys[1]).Set([]byte("k"), []byte("v"))
+	require.Equal(t, int64(3), cms.Commit().Version)
+}
+
+func TestRollbackPrunedStores(t *testing.T) {
+	db := dbm.NewMemDB()
+	prepareStores(t, db, 3)
+	storeDB(db, "market").Delete(rootKey(1))
+	_, err := RollbackStores(db, 1, true)
+	require.EqualError(t, err, "store market has no root at height 1, which may have been pruned")
+}
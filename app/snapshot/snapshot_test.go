@@ -0,0 +1,144 @@
+package snapshot
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	tmtypes "github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/cosmos/cosmos-sdk/store"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+var storeNames = []string{"acc", "empty", "market"}
+
+func newMultiStore(db dbm.DB) (sdk.CommitMultiStore, []*sdk.KVStoreKey) {
+	cms := store.NewCommitMultiStore(db)
+	cms.SetPruning(store.PruneNothing)
+	var keys []*sdk.KVStoreKey
+	for _, name := range storeNames {
+		key := sdk.NewKVStoreKey(name)
+		cms.MountStoreWithDB(key, sdk.StoreTypeIAVL, nil)
+		keys = append(keys, key)
+	}
+	if err := cms.LoadLatestVersion(); err != nil {
+		panic(err)
+	}
+	return cms, keys
+}
+
+// commits some versions and returns the header which has the app hash of version 2
+func prepareStores(t *testing.T, db dbm.DB, versions int) *tmtypes.Header {
+	cms, keys := newMultiStore(db)
+	var header *tmtypes.Header
+	for version := 1; version <= versions; version++ {
+		for i := 0; i < 100; i++ {
+			cms.GetKVStore(keys[0]).Set([]byte(fmt.Sprintf("acc%d-%d", version, i)), []byte{byte(i)})
+			cms.GetKVStore(keys[2]).Set([]byte(fmt.Sprintf("order%d", i)), []byte{byte(version)})
+		}
+		cms.GetKVStore(keys[0]).Delete([]byte("acc1-7"))
+		commitID := cms.Commit()
+		if version == 2 {
+			header = &tmtypes.Header{Height: 3, AppHash: commitID.Hash}
+		}
+	}
+	return header
+}
+
+func TestCreateAndRestore(t *testing.T) {
+	db := dbm.NewMemDB()
+	header := prepareStores(t, db, 3)
+	dir, err := ioutil.TempDir("", "snapshot")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	m, err := Create(db, 2, storeNames, header, dir, 1024)
+	require.Nil(t, err)
+	require.True(t, len(m.Chunks) > 1)
+	require.Equal(t, int64(0), m.Stores[1].Nodes)
+
+	_, err = Create(db, 2, storeNames[:2], header, dir, 1024)
+	require.NotNil(t, err)
+	_, err = Create(db, 5, storeNames, header, dir, 1024)
+	require.NotNil(t, err)
+	_, err = Create(db, 1, storeNames, header, dir, 1024)
+	require.NotNil(t, err) // the header is not of height 2
+
+	db2 := dbm.NewMemDB()
+	m2, err := Restore(db2, dir)
+	require.Nil(t, err)
+	require.Equal(t, m.AppHash, m2.AppHash)
+	cms, keys := newMultiStore(db2)
+	require.Equal(t, sdk.CommitID{Version: 2, Hash: header.AppHash}, cms.LastCommitID())
+	require.Equal(t, []byte{2}, cms.GetKVStore(keys[2]).Get([]byte("order99")))
+	require.Nil(t, cms.GetKVStore(keys[0]).Get([]byte("acc3-0")))
+
+	// the restored stores can go on
+	cms.GetKVStore(keys[1]).Set([]byte("k"), []byte("v"))
+	require.Equal(t, int64(3), cms.Commit().Version)
+
+	_, err = Restore(db2, dir)
+	require.EqualError(t, err, "the db is not empty")
+}
+
+func TestRestoreBrokenSnapshot(t *testing.T) {
+	db := dbm.NewMemDB()
+	header := prepareStores(t, db, 3)
+	dir, err := ioutil.TempDir("", "snapshot")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	m, err := Create(db, 2, storeNames, header, dir, 1024)
+	require.Nil(t, err)
+
+	// a modified chunk
+	file := filepath.Join(dir, m.Chunks[1].File)
+	bz, err := ioutil.ReadFile(file)
+	require.Nil(t, err)
+	bz[len(bz)-1]++
+	require.Nil(t, ioutil.WriteFile(file, bz, 0644))
+	_, err = Restore(dbm.NewMemDB(), dir)
+	require.EqualError(t, err, fmt.Sprintf("checksum mismatch of %s", m.Chunks[1].File))
+
+	// a modified chunk with its checksum
+	m.Chunks[1].SHA256 = sha256Sum(bz)
+	require.Nil(t, writeManifest(dir, m))
+	_, err = Restore(dbm.NewMemDB(), dir)
+	require.NotNil(t, err)
+
+	// a missing chunk
+	m.Chunks = m.Chunks[:1]
+	require.Nil(t, writeManifest(dir, m))
+	_, err = Restore(dbm.NewMemDB(), dir)
+	require.NotNil(t, err)
+
+	// another header
+	m.Header.AppHash = []byte("hash")
+	require.Nil(t, writeManifest(dir, m))
+	_, err = Restore(dbm.NewMemDB(), dir)
+	require.NotNil(t, err)
+}
+
+func TestNodeHash(t *testing.T) {
+	db := dbm.NewMemDB()
+	prepareStores(t, db, 3)
+	sdb := storeDB(db, "acc")
+	root := sdb.Get(rootKey(3))
+	n, err := decodeNode(sdb.Get(nodeKey(root)))
+	require.Nil(t, err)
+	require.False(t, n.isLeaf())
+	require.Equal(t, root, n.hash())
+	leaf, err := decodeNode(sdb.Get(nodeKey(n.leftHash)))
+	require.Nil(t, err)
+	require.Equal(t, n.leftHash, leaf.hash())
+}
+
+func sha256Sum(bz []byte) []byte {
+	sum := sha256.Sum256(bz)
+	return sum[:]
+}
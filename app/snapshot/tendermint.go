@@ -0,0 +1,93 @@
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	tmtypes "github.com/tendermint/tendermint/types"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+)
+
+// TendermintFile is the name of the Tendermint data in a snapshot directory
+const TendermintFile = "tendermint.json"
+
+// Tendermint has the block and the state of Tendermint at the height of a snapshot, which
+// are restored with the stores on a fresh node, so it starts from the next block. All of
+// them are verified against the header of Height+1 in the manifest.
+type Tendermint struct {
+	Block           *tmtypes.Block          `json:"block"`            // the block of Height
+	Commit          *tmtypes.Commit         `json:"commit"`           // the commit of Height, the last commit of the header
+	NextCommit      *tmtypes.Commit         `json:"next_commit"`      // the commit of the header
+	Validators      []*tmtypes.ValidatorSet `json:"validators"`       // the validators of Height, Height+1 and Height+2
+	ConsensusParams tmtypes.ConsensusParams `json:"consensus_params"` // the consensus params of Height+1
+}
+
+var tmCdc = codec.New()
+
+func init() {
+	tmtypes.RegisterBlockAmino(tmCdc)
+}
+
+// Verify checks t against the header in m, which must have been verified
+func (t *Tendermint) Verify(m *Manifest) error {
+	header := m.Header
+	if header == nil {
+		return errors.New("missing header")
+	}
+	if t.Block == nil || t.Commit == nil || t.NextCommit == nil || len(t.Validators) != 3 {
+		return errors.New("missing block, commits or validators")
+	}
+	if err := t.Block.ValidateBasic(); err != nil {
+		return err
+	}
+	blockID := header.LastBlockID
+	parts := t.Block.MakePartSet(tmtypes.BlockPartSizeBytes)
+	if t.Block.Height != m.Height || !bytes.Equal(t.Block.Hash(), blockID.Hash) || !parts.Header().Equals(blockID.PartsHeader) {
+		return fmt.Errorf("block %d is not the last block of the header", t.Block.Height)
+	}
+	if !bytes.Equal(t.Commit.Hash(), header.LastCommitHash) {
+		return errors.New("commit is not the last commit of the header")
+	}
+
+	lastVals, vals, nextVals := t.Validators[0], t.Validators[1], t.Validators[2]
+	if !bytes.Equal(lastVals.Hash(), t.Block.ValidatorsHash) ||
+		!bytes.Equal(vals.Hash(), header.ValidatorsHash) ||
+		!bytes.Equal(nextVals.Hash(), header.NextValidatorsHash) {
+		return errors.New("validators do not match the headers")
+	}
+	if !bytes.Equal(t.ConsensusParams.Hash(), header.ConsensusHash) {
+		return errors.New("consensus params do not match the header")
+	}
+	if err := lastVals.VerifyCommit(header.ChainID, blockID, m.Height, t.Commit); err != nil {
+		return err
+	}
+	nextBlockID := t.NextCommit.BlockID
+	if !bytes.Equal(nextBlockID.Hash, header.Hash()) {
+		return errors.New("next commit is not the one of the header")
+	}
+	return vals.VerifyCommit(header.ChainID, nextBlockID, header.Height, t.NextCommit)
+}
+
+func ReadTendermint(dir string) (*Tendermint, error) {
+	bz, err := ioutil.ReadFile(filepath.Join(dir, TendermintFile))
+	if err != nil {
+		return nil, err
+	}
+	var t Tendermint
+	if err = tmCdc.UnmarshalJSON(bz, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func WriteTendermint(dir string, t *Tendermint) error {
+	bz, err := codec.MarshalJSONIndent(tmCdc, t)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, TendermintFile), bz, 0644)
+}
@@ -0,0 +1,287 @@
+package snapshot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/tendermint/tendermint/crypto/merkle"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	tmtypes "github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// ManifestFile is the name of the manifest in a snapshot directory
+const ManifestFile = "manifest.json"
+
+// Manifest describes a snapshot of the stores at Height. The IAVL nodes of all the stores
+// are in Chunks, each node is kept as it is, so the restored stores have the same hashes.
+type Manifest struct {
+	Height  int64           `json:"height"`
+	AppHash cmn.HexBytes    `json:"app_hash"`
+	Header  *tmtypes.Header `json:"header"` // the header of Height+1, which has AppHash
+	Stores  []Store         `json:"stores"`
+	Chunks  []Chunk         `json:"chunks"`
+}
+
+type Store struct {
+	Name     string       `json:"name"`
+	RootHash cmn.HexBytes `json:"root_hash"`
+	Nodes    int64        `json:"nodes"`
+}
+
+type Chunk struct {
+	File   string       `json:"file"`
+	Size   int64        `json:"size"`
+	SHA256 cmn.HexBytes `json:"sha256"`
+}
+
+// ComputeAppHash returns the app hash committed by the multistore
+func (m *Manifest) ComputeAppHash() []byte {
+	return commitInfoFromStores(m.Height, m.Stores).Hash()
+}
+
+// Verify checks the app hash against the stores and the header
+func (m *Manifest) Verify() error {
+	if !bytes.Equal(m.ComputeAppHash(), m.AppHash) {
+		return fmt.Errorf("app hash %s does not match the stores", m.AppHash)
+	}
+	if m.Header == nil {
+		return errors.New("missing header")
+	}
+	if m.Header.Height != m.Height+1 {
+		return fmt.Errorf("header of height %d is not the next block of %d", m.Header.Height, m.Height)
+	}
+	if !bytes.Equal(m.Header.AppHash, m.AppHash) {
+		return fmt.Errorf("app hash %s does not match %s in the header", m.AppHash, m.Header.AppHash)
+	}
+	return nil
+}
+
+var cdc = codec.New()
+
+func ReadManifest(dir string) (*Manifest, error) {
+	bz, err := ioutil.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err = cdc.UnmarshalJSON(bz, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func writeManifest(dir string, m *Manifest) error {
+	bz, err := codec.MarshalJSONIndent(cdc, m)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, ManifestFile), bz, 0644)
+}
+
+// the same as those of rootmulti.Store, to read and write the commit info directly
+const (
+	latestVersionKey = "s/latest"
+	commitInfoKeyFmt = "s/%d"
+)
+
+type commitInfo struct {
+	Version    int64
+	StoreInfos []storeInfo
+}
+
+type storeInfo struct {
+	Name string
+	Core storeCore
+}
+
+type storeCore struct {
+	CommitID sdk.CommitID
+}
+
+func (ci commitInfo) Hash() []byte {
+	m := make(map[string][]byte, len(ci.StoreInfos))
+	for _, si := range ci.StoreInfos {
+		m[si.Name] = tmhash.Sum(si.Core.CommitID.Hash)
+	}
+	return merkle.SimpleHashFromMap(m)
+}
+
+func commitInfoFromStores(height int64, stores []Store) commitInfo {
+	ci := commitInfo{Version: height}
+	for _, s := range stores {
+		ci.StoreInfos = append(ci.StoreInfos, storeInfo{
+			Name: s.Name,
+			Core: storeCore{CommitID: sdk.CommitID{Version: height, Hash: s.RootHash}},
+		})
+	}
+	return ci
+}
+
+func loadCommitInfo(db dbm.DB, height int64) (ci commitInfo, err error) {
+	bz := db.Get([]byte(fmt.Sprintf(commitInfoKeyFmt, height)))
+	if bz == nil {
+		return ci, fmt.Errorf("height %d is not committed or has been pruned", height)
+	}
+	err = cdc.UnmarshalBinaryLengthPrefixed(bz, &ci)
+	return
+}
+
+// CommittedStores returns the names of the stores committed at height
+func CommittedStores(db dbm.DB, height int64) ([]string, error) {
+	ci, err := loadCommitInfo(db, height)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(ci.StoreInfos))
+	for i, si := range ci.StoreInfos {
+		names[i] = si.Name
+	}
+	return names, nil
+}
+
+// LatestVersion returns the latest version committed to db, or 0 if there is none
+func LatestVersion(db dbm.DB) int64 {
+	latest, err := loadLatestVersion(db)
+	if err != nil {
+		return 0
+	}
+	return latest
+}
+
+// InitEmptyStore saves an empty root of the store named name at version, so a store which
+// is mounted after the chain has been started commits the same versions as the others.
+// It fails if the store has other versions.
+func InitEmptyStore(db dbm.DB, name string, version int64) error {
+	sdb := storeDB(db, name)
+	itr := sdb.Iterator([]byte{'r'}, []byte{'r' + 1})
+	defer itr.Close()
+	if itr.Valid() {
+		key, value := itr.Key(), itr.Value()
+		itr.Next()
+		if !bytes.Equal(key, rootKey(version)) || len(value) != 0 || itr.Valid() {
+			return fmt.Errorf("store %s is not empty", name)
+		}
+		return nil
+	}
+	sdb.SetSync(rootKey(version), []byte{})
+	return nil
+}
+
+func saveCommitInfo(db dbm.DB, ci commitInfo) {
+	batch := db.NewBatch()
+	defer batch.Close()
+	batch.Set([]byte(fmt.Sprintf(commitInfoKeyFmt, ci.Version)), cdc.MustMarshalBinaryLengthPrefixed(ci))
+	batch.Set([]byte(latestVersionKey), cdc.MustMarshalBinaryLengthPrefixed(ci.Version))
+	batch.WriteSync()
+}
+
+// the layout of an IAVL store in the db of rootmulti.Store
+func storeDB(db dbm.DB, name string) dbm.DB {
+	return dbm.NewPrefixDB(db, []byte("s/k:"+name+"/"))
+}
+
+func rootKey(version int64) []byte {
+	key := make([]byte, 9)
+	key[0] = 'r'
+	binary.BigEndian.PutUint64(key[1:], uint64(version))
+	return key
+}
+
+func nodeKey(hash []byte) []byte {
+	return append([]byte{'n'}, hash...)
+}
+
+// node is an IAVL node decoded from the bytes saved in the db
+type node struct {
+	height    int8
+	size      int64
+	version   int64
+	key       []byte
+	value     []byte
+	leftHash  []byte
+	rightHash []byte
+}
+
+func (n *node) isLeaf() bool {
+	return n.height == 0
+}
+
+func decodeNode(bz []byte) (*node, error) {
+	r := bytes.NewReader(bz)
+	height, err := binary.ReadVarint(r)
+	if err != nil || height < -128 || height > 127 {
+		return nil, errors.New("invalid node height")
+	}
+	n := &node{height: int8(height)}
+	if n.size, err = binary.ReadVarint(r); err != nil {
+		return nil, err
+	}
+	if n.version, err = binary.ReadVarint(r); err != nil {
+		return nil, err
+	}
+	if n.key, err = readBytes(r); err != nil {
+		return nil, err
+	}
+	if n.isLeaf() {
+		n.value, err = readBytes(r)
+		return n, err
+	}
+	if n.leftHash, err = readBytes(r); err != nil {
+		return nil, err
+	}
+	n.rightHash, err = readBytes(r)
+	return n, err
+}
+
+// hash is the same as the one computed by IAVL
+func (n *node) hash() []byte {
+	var buf bytes.Buffer
+	writeVarint(&buf, int64(n.height))
+	writeVarint(&buf, n.size)
+	writeVarint(&buf, n.version)
+	if n.isLeaf() {
+		writeBytes(&buf, n.key)
+		writeBytes(&buf, tmhash.Sum(n.value))
+	} else {
+		writeBytes(&buf, n.leftHash)
+		writeBytes(&buf, n.rightHash)
+	}
+	return tmhash.Sum(buf.Bytes())
+}
+
+func writeVarint(buf *bytes.Buffer, i int64) {
+	var b [binary.MaxVarintLen64]byte
+	buf.Write(b[:binary.PutVarint(b[:], i)])
+}
+
+func writeUvarint(buf *bytes.Buffer, i uint64) {
+	var b [binary.MaxVarintLen64]byte
+	buf.Write(b[:binary.PutUvarint(b[:], i)])
+}
+
+func writeBytes(buf *bytes.Buffer, bz []byte) {
+	writeUvarint(buf, uint64(len(bz)))
+	buf.Write(bz)
+}
+
+func readBytes(r *bytes.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if size > uint64(r.Len()) {
+		return nil, errors.New("unexpected end of data")
+	}
+	bz := make([]byte, size)
+	_, err = r.Read(bz)
+	return bz, err
+}
@@ -0,0 +1,267 @@
+// Package testkit builds a CetChainApp on a memDB from a genesis of named accounts, tokens,
+// markets and validators, and drives it in unit tests: the txs are signed and delivered
+// block by block, the validators vote, the invariants are asserted after every block,
+// and the pub msgs of every block are captured.
+package testkit
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	authexported "github.com/cosmos/cosmos-sdk/x/auth/exported"
+	"github.com/cosmos/cosmos-sdk/x/auth/types"
+
+	"github.com/coinexchain/cet-sdk/testutil"
+	"github.com/coinexchain/dex/app"
+)
+
+// App is a CetChainApp driven by the test kit, which is created by GenesisBuilder.Build.
+// A block is begun by the first tx delivered in it, and ended by NextBlock.
+type App struct {
+	*app.CetChainApp
+	Cdc     *codec.Codec
+	ChainID string
+	// the time between two blocks
+	BlockInterval time.Duration
+
+	t          testing.TB
+	pubMsgPath string
+	fee        int64
+
+	height   int64
+	time     time.Time
+	inBlock  bool
+	accounts map[string]*Account
+	// the accounts whose sequences are unknown until the block is committed
+	stale   map[string]bool
+	offline map[string]bool
+	pubMsgs map[int64][]app.PubMsg
+	// the validators who sign block h are valSets[h]
+	valSets map[int64][]abci.Validator
+	// the number of pub msgs read from the file
+	pubMsgCount int
+}
+
+// Close removes the file of the pub msgs
+func (a *App) Close() {
+	_ = os.Remove(a.pubMsgPath)
+}
+
+// Height is the height of the latest committed block
+func (a *App) Height() int64 {
+	return a.height
+}
+
+// Time is the time of the latest block
+func (a *App) Time() time.Time {
+	return a.time
+}
+
+// Account returns the genesis account of name, any unknown name fails the test
+func (a *App) Account(name string) *Account {
+	acc, ok := a.accounts[name]
+	if !ok {
+		a.t.Fatalf("unknown account: %s", name)
+	}
+	return acc
+}
+
+// Deliver signs a tx of msgs with the key of signer and delivers it in the current block.
+// The sequence of signer is unknown after a failed tx, in which case the block is ended
+// before the next tx of signer.
+func (a *App) Deliver(signer string, msgs ...sdk.Msg) sdk.Result {
+	acc := a.Account(signer)
+	if a.stale[signer] {
+		a.NextBlock()
+	}
+	a.beginBlock()
+	tx := testutil.NewStdTxBuilder(a.ChainID).
+		Msgs(msgs...).GasAndFee(DefaultGas, a.fee).AccNumSeqKey(acc.AccNum, acc.Seq, acc.Key).Build()
+	txBytes, err := auth.DefaultTxEncoder(a.Cdc)(tx)
+	if err != nil {
+		a.t.Fatalf("encode tx: %s", err.Error())
+	}
+	res := a.DeliverTx(abci.RequestDeliverTx{Tx: txBytes})
+	if res.Code == uint32(sdk.CodeOK) {
+		acc.Seq++
+	} else {
+		a.stale[signer] = true
+	}
+	return sdk.Result{
+		Code:      sdk.CodeType(res.Code),
+		Codespace: sdk.CodespaceType(res.Codespace),
+		Data:      res.Data,
+		Log:       res.Log,
+		GasWanted: uint64(res.GasWanted),
+		GasUsed:   uint64(res.GasUsed),
+	}
+}
+
+// MustDeliver is Deliver which fails the test if the tx fails
+func (a *App) MustDeliver(signer string, msgs ...sdk.Msg) sdk.Result {
+	res := a.Deliver(signer, msgs...)
+	if !res.IsOK() {
+		a.t.Fatalf("tx of %s failed: %s", signer, res.Log)
+	}
+	return res
+}
+
+// NextBlock ends and commits the current block, an empty block is committed if no tx is
+// delivered after the last one. A broken invariant fails the test.
+func (a *App) NextBlock() {
+	a.beginBlock()
+	var res abci.ResponseEndBlock
+	func() {
+		defer a.recoverPanic("EndBlock")
+		res = a.EndBlock(abci.RequestEndBlock{Height: a.height})
+	}()
+	// the updates of block h take effect at block h+2
+	vals := a.valSets[a.height+1]
+	for _, update := range res.ValidatorUpdates {
+		vals = applyValidatorUpdate(vals, update)
+	}
+	a.valSets[a.height+2] = vals
+	a.Commit()
+	a.inBlock = false
+	a.readPubMsgs()
+	a.syncAccounts()
+}
+
+// NextBlocks commits n blocks
+func (a *App) NextBlocks(n int) {
+	for i := 0; i < n; i++ {
+		a.NextBlock()
+	}
+}
+
+// AdvanceTime ends the current block, and makes the next block d later than the usual
+func (a *App) AdvanceTime(d time.Duration) {
+	if a.inBlock {
+		a.NextBlock()
+	}
+	a.time = a.time.Add(d)
+}
+
+// SetOffline makes the validator of name sign no blocks from the next one, or sign again
+func (a *App) SetOffline(name string, offline bool) {
+	acc := a.Account(name)
+	if acc.ConsKey == nil {
+		a.t.Fatalf("%s is not a validator", name)
+	}
+	a.offline[string(acc.ConsKey.PubKey().Address())] = offline
+}
+
+// PubMsgs returns the msgs published at the block of height, without the "commit" msg
+func (a *App) PubMsgs(height int64) []app.PubMsg {
+	return a.pubMsgs[height]
+}
+
+// HasPubMsg reports whether a msg with key is published at the block of height
+func (a *App) HasPubMsg(height int64, key string) bool {
+	for _, msg := range a.pubMsgs[height] {
+		if string(msg.Key) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Query sends a query of path with params in JSON to the committed state, and decodes the
+// result into res, any error fails the test
+func (a *App) Query(path string, params interface{}, res interface{}) {
+	if err := a.query(path, params, res); err != nil {
+		a.t.Fatalf("query %s: %s", path, err.Error())
+	}
+}
+
+func (a *App) query(path string, params interface{}, res interface{}) error {
+	var data []byte
+	if params != nil {
+		var err error
+		if data, err = a.Cdc.MarshalJSON(params); err != nil {
+			return err
+		}
+	}
+	resp := a.CetChainApp.Query(abci.RequestQuery{Path: path, Data: data})
+	if resp.Code != uint32(sdk.CodeOK) {
+		return fmt.Errorf("%s", resp.Log)
+	}
+	return a.Cdc.UnmarshalJSON(resp.Value, res)
+}
+
+// QueryAccount returns the committed account of addr, which is nil if it does not exist
+func (a *App) QueryAccount(addr sdk.AccAddress) authexported.Account {
+	var acc authexported.Account
+	if err := a.query("custom/acc/account", types.NewQueryAccountParams(addr), &acc); err != nil {
+		return nil
+	}
+	return acc
+}
+
+// beginBlock begins the next block if it is not begun, which is signed by the online validators
+func (a *App) beginBlock() {
+	if a.inBlock {
+		return
+	}
+	a.inBlock = true
+	a.height++
+	a.time = a.time.Add(a.BlockInterval)
+
+	header := abci.Header{ChainID: a.ChainID, Height: a.height, Time: a.time}
+	for _, val := range a.valSets[a.height] {
+		if !a.offline[string(val.Address)] {
+			header.ProposerAddress = val.Address
+			break
+		}
+	}
+	var votes []abci.VoteInfo
+	if a.height > 1 {
+		for _, val := range a.valSets[a.height-1] {
+			votes = append(votes, abci.VoteInfo{Validator: val, SignedLastBlock: !a.offline[string(val.Address)]})
+		}
+	}
+	func() {
+		defer a.recoverPanic("BeginBlock")
+		a.BeginBlock(abci.RequestBeginBlock{Header: header, LastCommitInfo: abci.LastCommitInfo{Votes: votes}})
+	}()
+	delete(a.valSets, a.height-2)
+}
+
+// recoverPanic fails the test with the panic in stage, such as a broken invariant
+func (a *App) recoverPanic(stage string) {
+	if r := recover(); r != nil {
+		a.t.Fatalf("%s of block %d: %v", stage, a.height, r)
+	}
+}
+
+func (a *App) readPubMsgs() {
+	msgs, err := ReadPubMsgs(a.pubMsgPath)
+	if err != nil {
+		a.t.Fatalf("pub msgs: %s", err.Error())
+	}
+	// the msgs of the block are followed by a "commit" msg
+	block := msgs[a.pubMsgCount:]
+	a.pubMsgCount = len(msgs)
+	if len(block) > 0 {
+		block = block[:len(block)-1]
+	}
+	a.pubMsgs[a.height] = block
+}
+
+// syncAccounts reads the sequences of the accounts whose txs failed
+func (a *App) syncAccounts() {
+	for name := range a.stale {
+		acc := a.accounts[name]
+		if stored := a.QueryAccount(acc.Address); stored != nil {
+			acc.Seq = stored.GetSequence()
+		}
+		delete(a.stale, name)
+	}
+}
@@ -0,0 +1,109 @@
+package testkit
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/staking"
+
+	"github.com/coinexchain/cet-sdk/modules/bankx"
+	"github.com/coinexchain/cet-sdk/modules/market"
+	dex "github.com/coinexchain/cet-sdk/types"
+	"github.com/coinexchain/dex/app"
+)
+
+func TestMain(m *testing.M) {
+	dex.InitSdkConfig()
+	os.Exit(m.Run())
+}
+
+func TestSend(t *testing.T) {
+	tk := NewGenesisBuilder().
+		Validator("val", 1e13).
+		Account("val", dex.NewCetCoins(1e9)).
+		Account("alice", dex.NewCetCoins(1e10)).
+		Account("bob", dex.NewCetCoins(1e8)).
+		Build(t)
+	defer tk.Close()
+	alice, bob := tk.Account("alice"), tk.Account("bob")
+	require.Equal(t, uint64(1), alice.AccNum)
+
+	tk.MustDeliver("alice", bankx.NewMsgSend(alice.Address, bob.Address, dex.NewCetCoins(1e9), 0))
+	// the sequence of alice is resynced after a failed tx
+	require.False(t, tk.Deliver("alice", bankx.NewMsgSend(alice.Address, bob.Address, dex.NewCetCoins(1e11), 0)).IsOK())
+	tk.MustDeliver("alice", bankx.NewMsgSend(alice.Address, bob.Address, dex.NewCetCoins(1e9), 0))
+	tk.MustDeliver("val", bankx.NewMsgSend(tk.Account("val").Address, bob.Address, dex.NewCetCoins(1e8), 0))
+	tk.NextBlock()
+	require.Equal(t, int64(2), tk.Height())
+	require.True(t, tk.HasPubMsg(1, "notify_tx"))
+	require.True(t, tk.HasPubMsg(2, "notify_tx"))
+	require.True(t, tk.HasPubMsg(2, "height_info"))
+	require.Equal(t, uint64(3), alice.Seq)
+	require.Equal(t, int64(22e8), tk.QueryAccount(bob.Address).GetCoins().AmountOf(dex.CET).Int64())
+
+	tk.NextBlock()
+	require.False(t, tk.HasPubMsg(3, "notify_tx"))
+	require.Equal(t, DefaultGenesisTime.Add(3*tk.BlockInterval), tk.Time())
+	tk.AdvanceTime(time.Hour)
+	tk.NextBlock()
+	require.Equal(t, DefaultGenesisTime.Add(4*tk.BlockInterval+time.Hour), tk.Time())
+}
+
+func TestMarketOrder(t *testing.T) {
+	tk := NewGenesisBuilder().
+		Validator("val", 1e13).
+		Account("alice", dex.NewCetCoins(1e10)).
+		Token("abc", "alice", 1e16).
+		Market("abc", dex.CET).
+		Build(t)
+	defer tk.Close()
+
+	tk.MustDeliver("alice", market.MsgCreateOrder{
+		Sender:         tk.Account("alice").Address,
+		TradingPair:    "abc" + market.SymbolSeparator + dex.CET,
+		OrderType:      market.LimitOrder,
+		PricePrecision: 8,
+		Price:          100,
+		Quantity:       10000000,
+		Side:           market.SELL,
+		TimeInForce:    market.GTE,
+	})
+	tk.NextBlock()
+	require.True(t, tk.HasPubMsg(1, "create_order_info"))
+}
+
+func TestOfflineValidator(t *testing.T) {
+	tk := NewGenesisBuilder().
+		Validator("val1", 3e13).
+		Validator("val2", 1e13).
+		Modify(func(genState *app.GenesisState) {
+			genState.SlashingData.Params.SignedBlocksWindow = 10
+			genState.SlashingData.Params.MinSignedPerWindow = sdk.NewDecWithPrec(5, 1)
+		}).
+		Build(t)
+	defer tk.Close()
+
+	val2 := tk.Account("val2")
+	jailed := func() bool {
+		var val staking.Validator
+		tk.Query("custom/staking/validator", staking.NewQueryValidatorParams(val2.ValAddress()), &val)
+		return val.Jailed
+	}
+	tk.NextBlocks(3)
+	require.False(t, jailed())
+
+	tk.SetOffline("val2", true)
+	slashed := false
+	for i := 0; i < 20 && !jailed(); i++ {
+		tk.NextBlock()
+		slashed = slashed || tk.HasPubMsg(tk.Height(), "slash")
+	}
+	require.True(t, jailed())
+	require.True(t, slashed)
+	// the chain goes on with the power of val1
+	tk.NextBlocks(3)
+}
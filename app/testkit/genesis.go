@@ -0,0 +1,303 @@
+package testkit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+	"github.com/tendermint/tendermint/libs/log"
+	tmtypes "github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	"github.com/cosmos/cosmos-sdk/x/genaccounts"
+
+	"github.com/coinexchain/cet-sdk/modules/asset"
+	"github.com/coinexchain/cet-sdk/modules/market"
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+	"github.com/coinexchain/dex/app"
+)
+
+const (
+	DefaultChainID = "testkit"
+
+	// the gas limit of the txs delivered by the test kit
+	DefaultGas = 1000000
+)
+
+// DefaultGenesisTime is the time of the genesis, the first block is one block interval later
+var DefaultGenesisTime = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Account is a genesis account of the test kit, its keys are derived from its name
+type Account struct {
+	Name    string
+	Key     crypto.PrivKey
+	Address sdk.AccAddress
+	// the consensus key, which is nil if the account is not a validator
+	ConsKey crypto.PrivKey
+	AccNum  uint64
+	Seq     uint64
+}
+
+// NewAccount returns the account of name, whose keys are always the same
+func NewAccount(name string) *Account {
+	key := secp256k1.GenPrivKeySecp256k1([]byte(name))
+	return &Account{Name: name, Key: key, Address: sdk.AccAddress(key.PubKey().Address())}
+}
+
+// ValAddress is the operator address of the account as a validator
+func (acc *Account) ValAddress() sdk.ValAddress {
+	return sdk.ValAddress(acc.Address)
+}
+
+// ConsAddress is the consensus address of the account as a validator
+func (acc *Account) ConsAddress() sdk.ConsAddress {
+	return sdk.ConsAddress(acc.ConsKey.PubKey().Address())
+}
+
+type genesisAccount struct {
+	*Account
+	coins sdk.Coins
+	stake int64
+}
+
+type genesisToken struct {
+	symbol      string
+	owner       string
+	totalSupply int64
+}
+
+// GenesisBuilder builds the genesis state of an app of the test kit, its methods can be chained
+//
+//	tk := testkit.NewGenesisBuilder().
+//		Validator("val", 1e13).
+//		Account("alice", dex.NewCetCoins(1e10)).
+//		Token("abc", "alice", 1e16).
+//		Market("abc", "cet").
+//		Build(t)
+type GenesisBuilder struct {
+	chainID  string
+	time     time.Time
+	accounts []*genesisAccount
+	tokens   []genesisToken
+	markets  []market.MarketInfo
+	modifies []func(*app.GenesisState)
+}
+
+// NewGenesisBuilder returns a builder of a genesis without accounts, with the default params
+// except that the min gas price and the min self delegation are lowered, so that any stake
+// makes a validator and a tx delivered by the test kit costs little
+func NewGenesisBuilder() *GenesisBuilder {
+	return &GenesisBuilder{chainID: DefaultChainID, time: DefaultGenesisTime}
+}
+
+func (b *GenesisBuilder) ChainID(chainID string) *GenesisBuilder {
+	b.chainID = chainID
+	return b
+}
+
+func (b *GenesisBuilder) Time(t time.Time) *GenesisBuilder {
+	b.time = t
+	return b
+}
+
+func (b *GenesisBuilder) account(name string) *genesisAccount {
+	for _, acc := range b.accounts {
+		if acc.Name == name {
+			return acc
+		}
+	}
+	acc := &genesisAccount{Account: NewAccount(name), coins: sdk.NewCoins()}
+	b.accounts = append(b.accounts, acc)
+	return acc
+}
+
+// Account adds coins to the account of name, which is created if it does not exist
+func (b *GenesisBuilder) Account(name string, coins sdk.Coins) *GenesisBuilder {
+	acc := b.account(name)
+	acc.coins = acc.coins.Add(coins)
+	return b
+}
+
+// Token issues a token whose total supply is in the account of its owner
+func (b *GenesisBuilder) Token(symbol, owner string, totalSupply int64) *GenesisBuilder {
+	b.Account(owner, sdk.NewCoins(sdk.NewInt64Coin(symbol, totalSupply)))
+	b.tokens = append(b.tokens, genesisToken{symbol: symbol, owner: owner, totalSupply: totalSupply})
+	return b
+}
+
+// Market creates the trading pair of stock and money
+func (b *GenesisBuilder) Market(stock, money string) *GenesisBuilder {
+	b.markets = append(b.markets, market.MarketInfo{
+		Stock:             stock,
+		Money:             money,
+		PricePrecision:    8,
+		LastExecutedPrice: sdk.ZeroDec(),
+	})
+	return b
+}
+
+// Validator makes the account of name a validator with a gentx, which self-delegates stake,
+// the stake is added to the coins of the account
+func (b *GenesisBuilder) Validator(name string, stake int64) *GenesisBuilder {
+	acc := b.account(name)
+	acc.coins = acc.coins.Add(dex.NewCetCoins(stake))
+	acc.stake += stake
+	acc.ConsKey = ed25519.GenPrivKeyFromSecret([]byte(name))
+	return b
+}
+
+// Modify changes the genesis state after the accounts, the tokens and the markets are added
+func (b *GenesisBuilder) Modify(modify func(*app.GenesisState)) *GenesisBuilder {
+	b.modifies = append(b.modifies, modify)
+	return b
+}
+
+// GenesisState returns the genesis state which is built
+func (b *GenesisBuilder) GenesisState() (app.GenesisState, error) {
+	genState := app.NewDefaultGenesisState()
+	genState.AuthData = app.GetDefaultAuthGenesisState()
+	genState.AuthXData.Params.MinGasPriceLimit = sdk.MustNewDecFromStr("0.00000001")
+	genState.StakingData.Params.BondDenom = dex.DefaultBondDenom
+	genState.StakingXData.Params.MinSelfDelegation = 1
+
+	totalCET := sdk.ZeroInt()
+	for i, acc := range b.accounts {
+		acc.AccNum = uint64(i)
+		acc.Seq = 0
+		baseAcc := auth.NewBaseAccountWithAddress(acc.Address)
+		baseAcc.Coins = acc.coins
+		// the accounts are numbered in this order in InitGenesis
+		baseAcc.AccountNumber = acc.AccNum
+		genState.Accounts = append(genState.Accounts, genaccounts.NewGenesisAccount(&baseAcc))
+		totalCET = totalCET.Add(acc.coins.AmountOf(dex.CET))
+	}
+	if !totalCET.IsPositive() {
+		return genState, fmt.Errorf("no cet in the genesis accounts")
+	}
+
+	cet, err := asset.NewToken("CoinEx Chain Native Token", dex.CET, totalCET, b.accounts[0].Address,
+		false, true, false, false, "", "", asset.TestIdentityString)
+	if err != nil {
+		return genState, err
+	}
+	genState.AssetData.Tokens = append(genState.AssetData.Tokens, cet)
+	for _, t := range b.tokens {
+		token, err := asset.NewToken(t.symbol+" token", t.symbol, sdk.NewInt(t.totalSupply), b.account(t.owner).Address,
+			true, true, false, false, "", "", asset.TestIdentityString)
+		if err != nil {
+			return genState, fmt.Errorf("token %s: %s", t.symbol, err.Error())
+		}
+		genState.AssetData.Tokens = append(genState.AssetData.Tokens, token)
+	}
+	genState.MarketData.MarketInfos = append(genState.MarketData.MarketInfos, b.markets...)
+
+	cdc := app.MakeCodec()
+	for _, acc := range b.accounts {
+		if acc.stake == 0 {
+			continue
+		}
+		msg := testutil.NewMsgCreateValidatorBuilder(acc.ValAddress(), acc.ConsKey.PubKey()).
+			Description(acc.Name, "", "", "").
+			Commission("0.1", "0.2", "0.01").
+			MinSelfDelegation(1).
+			SelfDelegation(acc.stake).
+			Build()
+		// the signatures of the gentxs are verified with the account number 0
+		tx := testutil.NewStdTxBuilder(b.chainID).
+			Msgs(msg).GasAndFee(DefaultGas, 0).AccNumSeqKey(0, 0, acc.Key).Build()
+		genState.GenUtil.GenTxs = append(genState.GenUtil.GenTxs, cdc.MustMarshalJSON(tx))
+		acc.Seq = 1
+	}
+
+	for _, modify := range b.modifies {
+		modify(&genState)
+	}
+	return genState, nil
+}
+
+// Build creates an app on a memDB and initializes its chain, any error fails t
+func (b *GenesisBuilder) Build(t testing.TB) *App {
+	genState, err := b.GenesisState()
+	if err != nil {
+		t.Fatalf("genesis: %s", err.Error())
+	}
+	f, err := ioutil.TempFile("", "testkit_pub_msgs")
+	if err != nil {
+		t.Fatalf("pub msg file: %s", err.Error())
+	}
+	_ = f.Close()
+
+	cdc := app.MakeCodec()
+	a := &App{
+		CetChainApp:   NewPubMsgApp(log.NewNopLogger(), dbm.NewMemDB(), f.Name(), 1),
+		Cdc:           cdc,
+		ChainID:       b.chainID,
+		BlockInterval: 5 * time.Second,
+		t:             t,
+		pubMsgPath:    f.Name(),
+		time:          b.time,
+		accounts:      make(map[string]*Account, len(b.accounts)),
+		offline:       make(map[string]bool),
+		pubMsgs:       make(map[int64][]app.PubMsg),
+		valSets:       make(map[int64][]abci.Validator),
+		stale:         make(map[string]bool),
+	}
+	for _, acc := range b.accounts {
+		a.accounts[acc.Name] = acc.Account
+	}
+	limit := genState.AuthXData.Params.MinGasPriceLimit
+	a.fee = limit.MulInt64(DefaultGas).Ceil().RoundInt64()
+
+	stateBytes, err := cdc.MarshalJSON(genState)
+	if err != nil {
+		os.Remove(f.Name())
+		t.Fatalf("genesis: %s", err.Error())
+	}
+	var res abci.ResponseInitChain
+	func() {
+		defer a.recoverPanic("InitChain")
+		res = a.InitChain(abci.RequestInitChain{Time: b.time, ChainId: b.chainID, AppStateBytes: stateBytes})
+	}()
+	var vals []abci.Validator
+	for _, update := range res.Validators {
+		vals = applyValidatorUpdate(vals, update)
+	}
+	// the validators of the genesis sign the first two blocks
+	a.valSets[1], a.valSets[2] = vals, vals
+	return a
+}
+
+// applyValidatorUpdate returns vals with the power of a validator changed, the validators
+// with no power are removed
+func applyValidatorUpdate(vals []abci.Validator, update abci.ValidatorUpdate) []abci.Validator {
+	pubKey, err := tmtypes.PB2TM.PubKey(update.PubKey)
+	if err != nil {
+		panic(err)
+	}
+	addr := pubKey.Address()
+	result := make([]abci.Validator, 0, len(vals)+1)
+	found := false
+	for _, val := range vals {
+		if string(val.Address) == string(addr) {
+			found = true
+			if update.Power == 0 {
+				continue
+			}
+			val.Power = update.Power
+		}
+		result = append(result, val)
+	}
+	if !found && update.Power > 0 {
+		result = append(result, abci.Validator{Address: addr, Power: update.Power})
+	}
+	return result
+}
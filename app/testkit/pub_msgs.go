@@ -0,0 +1,50 @@
+package testkit
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/tendermint/tendermint/libs/log"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/cosmos/cosmos-sdk/baseapp"
+
+	"github.com/coinexchain/cet-sdk/msgqueue"
+	"github.com/coinexchain/dex/app"
+)
+
+// PubMsgTopics are the topics the apps of the test kit publish
+const PubMsgTopics = "auth,authx,bancorlite,bank,comment,market"
+
+// NewPubMsgApp creates an app which writes its pub msgs to the file at path, the msg
+// queue is configured by viper when the app is created, so the keys are set meanwhile
+func NewPubMsgApp(logger log.Logger, db dbm.DB, path string, invCheckPeriod uint,
+	baseAppOptions ...func(*baseapp.BaseApp)) *app.CetChainApp {
+
+	return app.WithPubMsgConfig([]string{msgqueue.CfgPrefixFile + path}, PubMsgTopics, func() *app.CetChainApp {
+		return app.NewCetChainApp(logger, db, nil, true, invCheckPeriod, baseAppOptions...)
+	})
+}
+
+// ReadPubMsgs returns the msgs in a file written by an app of NewPubMsgApp, a "commit"
+// msg follows the ones of every block
+func ReadPubMsgs(path string) ([]app.PubMsg, error) {
+	bz, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	lines := bytes.Split(bz, []byte("\r\n"))
+	// the last one is empty or being written
+	lines = lines[:len(lines)-1]
+	msgs := make([]app.PubMsg, len(lines))
+	for i, line := range lines {
+		kv := bytes.SplitN(line, []byte("#"), 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid pub msg: %s", line)
+		}
+		msgs[i] = app.PubMsg{Key: kv[0], Value: kv[1]}
+	}
+	return msgs, nil
+}
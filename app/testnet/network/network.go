@@ -0,0 +1,334 @@
+// Package network runs a testnet generated by package testnet in process, for the
+// integration tests. It is kept out of package testnet, so that cetd does not link
+// the test kit.
+package network
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/spf13/viper"
+	tmconfig "github.com/tendermint/tendermint/config"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/node"
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/privval"
+	"github.com/tendermint/tendermint/proxy"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+	rpcserver "github.com/tendermint/tendermint/rpc/lib/server"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/cosmos/cosmos-sdk/baseapp"
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/client/context"
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/cosmos/cosmos-sdk/client/keys"
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	authrest "github.com/cosmos/cosmos-sdk/x/auth/client/rest"
+	"github.com/cosmos/cosmos-sdk/x/genaccounts"
+
+	"github.com/coinexchain/cet-sdk/modules/authx"
+	dex "github.com/coinexchain/cet-sdk/types"
+	"github.com/coinexchain/dex/app"
+	overviewrest "github.com/coinexchain/dex/app/overview/client/rest"
+	"github.com/coinexchain/dex/app/testkit"
+	"github.com/coinexchain/dex/app/testnet"
+)
+
+const (
+	pubMsgFile = "pub_msgs.txt"
+	txGas      = 200000
+)
+
+// NetworkConfig is how a Network is generated and run
+type NetworkConfig struct {
+	Topology       *testnet.Topology
+	ChainID        string // a random one if empty
+	MinGasPrices   string
+	TimeoutPropose time.Duration
+	TimeoutCommit  time.Duration
+	InvCheckPeriod uint
+	Logger         log.Logger
+}
+
+// DefaultNetworkConfig is a network of 4 validators with equal stakes, which produces a
+// block about every second, and asserts the invariants in every block
+func DefaultNetworkConfig() NetworkConfig {
+	return NetworkConfig{
+		Topology:       testnet.DefaultTopology(4, "node"),
+		MinGasPrices:   fmt.Sprintf("%s%s", authx.DefaultMinGasPriceLimit, dex.DefaultBondDenom),
+		TimeoutPropose: time.Second,
+		TimeoutCommit:  500 * time.Millisecond,
+		InvCheckPeriod: 1,
+		Logger:         log.NewNopLogger(),
+	}
+}
+
+// Network is a testnet whose nodes run in this process, on 127.0.0.1 with free ports.
+// Only the first node serves RPC and LCD, as Tendermint keeps the state of its RPC in
+// global variables, so RPCClient, CLIContext and LCDURL stop working when it is stopped.
+type Network struct {
+	Dir     string
+	ChainID string
+	Cdc     *codec.Codec
+	Nodes   []*Node
+
+	RPCClient  rpcclient.Client
+	CLIContext context.CLIContext
+	LCDURL     string
+
+	config      NetworkConfig
+	lcdListener net.Listener
+}
+
+// Node is a validator or a full node of a Network, a stopped node is not started again
+type Node struct {
+	testnet.NodeInfo
+	App    *app.CetChainApp
+	TMNode *node.Node
+
+	stopped bool
+}
+
+// NewNetwork generates the files of the nodes in dir, and starts them. The network must
+// be cleaned up, even if an error is returned.
+func NewNetwork(dir string, cfg NetworkConfig) (*Network, error) {
+	if cfg.Logger == nil {
+		cfg.Logger = log.NewNopLogger()
+	}
+	if cfg.ChainID == "" {
+		cfg.ChainID = testnet.IntegrationTestChainID + cmn.RandStr(6)
+	}
+	n := &Network{Dir: dir, ChainID: cfg.ChainID, Cdc: app.MakeCodec(), config: cfg}
+	numNodes := len(cfg.Topology.Validators) + len(cfg.Topology.FullNodes)
+	portOffset, err := freePortOffset(numNodes)
+	if err != nil {
+		return n, err
+	}
+	opts := testnet.Options{
+		OutputDir:      dir,
+		ChainID:        cfg.ChainID,
+		MinGasPrices:   cfg.MinGasPrices,
+		NodeDirPrefix:  "node",
+		NodeDaemonHome: "cetd",
+		NodeCLIHome:    "cetcli",
+		SingleHost:     true,
+		PortOffset:     portOffset,
+		Topology:       cfg.Topology,
+	}
+	infos, err := testnet.InitFiles(tmconfig.DefaultConfig(), n.Cdc, app.ModuleBasics, genaccounts.AppModuleBasic{}, opts)
+	if err != nil {
+		return n, err
+	}
+	for i, info := range infos {
+		nd, err := n.startNode(info, i == 0)
+		if err != nil {
+			return n, err
+		}
+		n.Nodes = append(n.Nodes, nd)
+	}
+
+	rpcAddr := fmt.Sprintf("tcp://%s:%d", testnet.SingleHostIP, infos[0].Ports.RPC)
+	n.RPCClient = rpcclient.NewHTTP(rpcAddr, "/websocket")
+	n.CLIContext = context.CLIContext{
+		Codec:         n.Cdc,
+		Client:        n.RPCClient,
+		NodeURI:       rpcAddr,
+		TrustNode:     true,
+		Output:        ioutil.Discard,
+		BroadcastMode: flags.BroadcastBlock,
+	}
+	return n, n.startLCD(infos[0].Ports.LCD)
+}
+
+func (n *Network) startNode(info testnet.NodeInfo, serveRPC bool) (*Node, error) {
+	config, err := loadNodeConfig(info.Home)
+	if err != nil {
+		return nil, err
+	}
+	config.Consensus.TimeoutPropose = n.config.TimeoutPropose
+	config.Consensus.TimeoutCommit = n.config.TimeoutCommit
+	if !serveRPC {
+		config.RPC.ListenAddress = ""
+	}
+	logger := n.config.Logger.With("node", info.Moniker)
+
+	cetApp := testkit.NewPubMsgApp(logger, dbm.NewMemDB(), filepath.Join(info.Home, pubMsgFile),
+		n.config.InvCheckPeriod, baseapp.SetMinGasPrices(n.config.MinGasPrices))
+	nodeKey, err := p2p.LoadNodeKey(config.NodeKeyFile())
+	if err != nil {
+		return nil, err
+	}
+	tmNode, err := node.NewNode(
+		config,
+		privval.LoadFilePV(config.PrivValidatorKeyFile(), config.PrivValidatorStateFile()),
+		nodeKey,
+		proxy.NewLocalClientCreator(cetApp),
+		node.DefaultGenesisDocProviderFunc(config),
+		func(*node.DBContext) (dbm.DB, error) { return dbm.NewMemDB(), nil },
+		node.DefaultMetricsProvider(config.Instrumentation),
+		logger,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err = tmNode.Start(); err != nil {
+		return nil, err
+	}
+	return &Node{NodeInfo: info, App: cetApp, TMNode: tmNode}, nil
+}
+
+// loadNodeConfig reads the config.toml of a node, as 'cetd start' does
+func loadNodeConfig(home string) (*tmconfig.Config, error) {
+	v := viper.New()
+	v.SetConfigFile(filepath.Join(home, "config", "config.toml"))
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	config := tmconfig.DefaultConfig()
+	if err := v.Unmarshal(config); err != nil {
+		return nil, err
+	}
+	config.SetRoot(home)
+	return config, config.ValidateBasic()
+}
+
+func (n *Network) startLCD(port int) (err error) {
+	r := mux.NewRouter()
+	client.RegisterRoutes(n.CLIContext, r)
+	authrest.RegisterTxRoutes(n.CLIContext, r)
+	overviewrest.RegisterRoutes(n.CLIContext, r)
+	app.ModuleBasics.RegisterRESTRoutes(n.CLIContext, r)
+
+	cfg := rpcserver.DefaultConfig()
+	n.lcdListener, err = rpcserver.Listen(fmt.Sprintf("tcp://%s:%d", testnet.SingleHostIP, port), cfg)
+	if err != nil {
+		return err
+	}
+	logger := n.config.Logger.With("module", "rest-server")
+	go func() {
+		_ = rpcserver.StartHTTPServer(n.lcdListener, r, logger, cfg)
+	}()
+	n.LCDURL = fmt.Sprintf("http://%s:%d", testnet.SingleHostIP, port)
+	return nil
+}
+
+// Cleanup stops the LCD and the nodes, the files are kept in the directory of the network
+func (n *Network) Cleanup() {
+	if n.lcdListener != nil {
+		_ = n.lcdListener.Close()
+	}
+	for _, nd := range n.Nodes {
+		nd.Stop()
+	}
+}
+
+// Height is the height of the latest block of the first running node
+func (n *Network) Height() int64 {
+	for _, nd := range n.Nodes {
+		if !nd.stopped {
+			return nd.Height()
+		}
+	}
+	return 0
+}
+
+// WaitForHeight waits until the block of height is committed, or returns an error if it
+// is not committed within timeout
+func (n *Network) WaitForHeight(height int64, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for n.Height() < height {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("height %d is not reached in %s, the latest height is %d", height, timeout, n.Height())
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return nil
+}
+
+// WaitForNextBlock waits until a new block is committed, or returns an error if there is
+// no new block within timeout, e.g. when the network halts
+func (n *Network) WaitForNextBlock(timeout time.Duration) error {
+	return n.WaitForHeight(n.Height()+1, timeout)
+}
+
+// SendTx signs the msgs with the key of a validator, and broadcasts the tx by the RPC of
+// the first node, it returns after the tx is committed
+func (n *Network) SendTx(signer *Node, msgs ...sdk.Msg) (sdk.TxResponse, error) {
+	if !signer.Validator {
+		return sdk.TxResponse{}, fmt.Errorf("%s has no key", signer.Moniker)
+	}
+	kb, err := keys.NewKeyBaseFromDir(signer.CLIHome)
+	if err != nil {
+		return sdk.TxResponse{}, err
+	}
+	accNum, seq, err := auth.NewAccountRetriever(n.CLIContext).GetAccountNumberSequence(signer.Address)
+	if err != nil {
+		return sdk.TxResponse{}, err
+	}
+	gasPrices, err := sdk.ParseDecCoins(n.config.MinGasPrices)
+	if err != nil {
+		return sdk.TxResponse{}, err
+	}
+	txBldr := auth.NewTxBuilder(auth.DefaultTxEncoder(n.Cdc), accNum, seq, txGas, 0,
+		false, n.ChainID, "", nil, gasPrices).WithKeybase(kb)
+	txBytes, err := txBldr.BuildAndSign(signer.KeyName, app.DefaultKeyPass, msgs)
+	if err != nil {
+		return sdk.TxResponse{}, err
+	}
+	return n.CLIContext.BroadcastTxCommit(txBytes)
+}
+
+// Stop stops the node, which is not started again
+func (nd *Node) Stop() {
+	if nd.stopped {
+		return
+	}
+	nd.stopped = true
+	_ = nd.TMNode.Stop()
+}
+
+// Height is the height of the latest block of the node
+func (nd *Node) Height() int64 {
+	return nd.TMNode.BlockStore().Height()
+}
+
+// PubMsgs returns the msgs published by the app of the node so far, a "commit" msg
+// follows the ones of every block
+func (nd *Node) PubMsgs() ([]app.PubMsg, error) {
+	return testkit.ReadPubMsgs(filepath.Join(nd.Home, pubMsgFile))
+}
+
+// freePortOffset finds a port offset with which the ports of the nodes are not in use
+func freePortOffset(numNodes int) (int, error) {
+	const step = 1000
+	start := cmn.RandIntn(20)
+	for i := 0; i < 20; i++ {
+		offset := 10000 + (start+i)%20*step
+		if portsAvailable(offset, numNodes) {
+			return offset, nil
+		}
+	}
+	return 0, fmt.Errorf("no free ports for %d nodes", numNodes)
+}
+
+func portsAvailable(offset, numNodes int) bool {
+	for i := 0; i < numNodes; i++ {
+		ports := testnet.SingleHostNodePorts(i, offset)
+		for _, port := range []int{ports.P2P, ports.RPC, ports.LCD} {
+			l, err := net.Listen("tcp", fmt.Sprintf("%s:%d", testnet.SingleHostIP, port))
+			if err != nil {
+				return false
+			}
+			_ = l.Close()
+		}
+	}
+	return true
+}
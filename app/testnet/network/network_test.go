@@ -0,0 +1,103 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/staking"
+
+	dex "github.com/coinexchain/cet-sdk/types"
+	"github.com/coinexchain/dex/app/testnet"
+)
+
+func TestMain(m *testing.M) {
+	dex.InitSdkConfig()
+	os.Exit(m.Run())
+}
+
+func hasPubMsg(t *testing.T, nd *Node, key string) bool {
+	msgs, err := nd.PubMsgs()
+	require.Nil(t, err)
+	for _, msg := range msgs {
+		if string(msg.Key) == key {
+			return true
+		}
+	}
+	return false
+}
+
+func isJailed(t *testing.T, n *Network, nd *Node) bool {
+	resp, err := http.Get(fmt.Sprintf("%s/staking/validators/%s", n.LCDURL, sdk.ValAddress(nd.Address)))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	bz, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(bz))
+	var val struct {
+		Result struct {
+			Jailed bool `json:"jailed"`
+		} `json:"result"`
+	}
+	require.Nil(t, json.Unmarshal(bz, &val))
+	return val.Result.Jailed
+}
+
+func TestNetwork(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the in-process network in short mode")
+	}
+	dir, err := ioutil.TempDir("", "network")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := DefaultNetworkConfig()
+	for i := range cfg.Topology.Validators {
+		cfg.Topology.Validators[i].Stake = "2000000000000"
+	}
+	cfg.Topology.Params = map[string]map[string]interface{}{
+		"staking":  {"unbonding_time": "5000000000"},
+		"slashing": {"signed_blocks_window": 10, "min_signed_per_window": "0.5"},
+	}
+	n, err := NewNetwork(dir, cfg)
+	defer n.Cleanup()
+	require.Nil(t, err)
+	require.Equal(t, 4, len(n.Nodes))
+	require.Nil(t, n.WaitForHeight(2, 30*time.Second))
+
+	status, err := n.RPCClient.Status()
+	require.Nil(t, err)
+	require.Equal(t, n.ChainID, status.NodeInfo.Network)
+
+	// unbonding completes after 5 seconds
+	val1 := n.Nodes[1]
+	res, err := n.SendTx(val1, staking.NewMsgUndelegate(val1.Address, sdk.ValAddress(val1.Address),
+		sdk.NewCoin(dex.DefaultBondDenom, sdk.NewInt(testnet.MinSelfDelegation))))
+	require.Nil(t, err)
+	require.Equal(t, uint32(0), res.Code, res.RawLog)
+	require.True(t, hasPubMsg(t, n.Nodes[0], "begin_unbonding"))
+
+	// the network goes on without a validator, which is jailed for being offline
+	n.Nodes[3].Stop()
+	require.False(t, isJailed(t, n, n.Nodes[3]))
+	for i := 0; !isJailed(t, n, n.Nodes[3]); i++ {
+		require.True(t, i < 60, "validator is not jailed")
+		require.Nil(t, n.WaitForNextBlock(10*time.Second))
+	}
+	require.True(t, hasPubMsg(t, n.Nodes[0], "slash"))
+	for i := 0; !hasPubMsg(t, n.Nodes[0], "complete_unbonding"); i++ {
+		require.True(t, i < 30, "unbonding is not completed")
+		require.Nil(t, n.WaitForNextBlock(10*time.Second))
+	}
+
+	// 3/5 of the power is left, less than 2/3, so the network halts after the block in flight
+	n.Nodes[2].Stop()
+	require.NotNil(t, n.WaitForHeight(n.Height()+2, 5*time.Second))
+}
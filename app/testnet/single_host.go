@@ -0,0 +1,109 @@
+package testnet
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	tmconfig "github.com/tendermint/tendermint/config"
+)
+
+const (
+	SingleHostIP = "127.0.0.1"
+
+	// the ports of node i are the defaults plus the port offset plus i*SingleHostPortStep,
+	// and its LCD port is 1317 plus the port offset plus i
+	SingleHostPortStep = 10
+	DefaultP2PPort     = 26656
+	DefaultRPCPort     = 26657
+	DefaultABCIPort    = 26658
+	DefaultLCDPort     = 1317
+)
+
+// NodePorts are the ports a testnet node listens on
+type NodePorts struct {
+	P2P  int
+	RPC  int
+	ABCI int
+	LCD  int
+}
+
+// nodeAddr is where a node of the testnet is reached, the nodes of a single-host
+// testnet are all on 127.0.0.1, with distinct ports
+type nodeAddr struct {
+	ip         string
+	ports      NodePorts
+	singleHost bool
+}
+
+func defaultNodePorts() NodePorts {
+	return NodePorts{P2P: DefaultP2PPort, RPC: DefaultRPCPort, ABCI: DefaultABCIPort, LCD: DefaultLCDPort}
+}
+
+// SingleHostNodePorts returns the ports of node i of a single-host testnet
+func SingleHostNodePorts(i, portOffset int) NodePorts {
+	return NodePorts{
+		P2P:  DefaultP2PPort + portOffset + i*SingleHostPortStep,
+		RPC:  DefaultRPCPort + portOffset + i*SingleHostPortStep,
+		ABCI: DefaultABCIPort + portOffset + i*SingleHostPortStep,
+		LCD:  DefaultLCDPort + portOffset + i,
+	}
+}
+
+// testnetAddr returns the address of node i, ip is the one in the topology, which is
+// ignored for a single-host testnet
+func testnetAddr(ip string, i int, opts Options) (nodeAddr, error) {
+	if opts.SingleHost {
+		return nodeAddr{ip: SingleHostIP, ports: SingleHostNodePorts(i, opts.PortOffset), singleHost: true}, nil
+	}
+	ip, err := topologyIP(ip, i, opts.StartingIPAddress)
+	return nodeAddr{ip: ip, ports: defaultNodePorts()}, err
+}
+
+// p2pAddr is the address of the node in persistent_peers
+func (a nodeAddr) p2pAddr() string {
+	return fmt.Sprintf("%s:%d", a.ip, a.ports.P2P)
+}
+
+// configure sets the listen addresses of the node in config, which is shared by all the nodes
+func (a nodeAddr) configure(config *tmconfig.Config) {
+	if !a.singleHost {
+		config.RPC.ListenAddress = "tcp://0.0.0.0:26657"
+		return
+	}
+	config.P2P.ListenAddress = fmt.Sprintf("tcp://%s:%d", SingleHostIP, a.ports.P2P)
+	config.RPC.ListenAddress = fmt.Sprintf("tcp://%s:%d", SingleHostIP, a.ports.RPC)
+	config.ProxyApp = fmt.Sprintf("tcp://%s:%d", SingleHostIP, a.ports.ABCI)
+	// the peers on the loopback address are neither routable nor distinct IPs
+	config.P2P.AddrBookStrict = false
+	config.P2P.AllowDuplicateIP = true
+}
+
+// writeScripts writes start.sh and stop.sh to the output directory, which run all the nodes
+// and their LCDs of a single-host testnet in the background, with the logs and the pid
+// files in the node directories. CETD and CETCLI can be set to the paths of the binaries.
+func writeScripts(opts Options, nodes []NodeInfo) error {
+	var start, stop bytes.Buffer
+	start.WriteString("#!/bin/sh\n# generated by cetd testnet --single-host\nset -e\ncd \"$(dirname \"$0\")\"\n")
+	start.WriteString("CETD=${CETD:-cetd}\nCETCLI=${CETCLI:-cetcli}\n")
+	stop.WriteString("#!/bin/sh\n# generated by cetd testnet --single-host\ncd \"$(dirname \"$0\")\"\n")
+	for _, n := range nodes {
+		home := filepath.Join(n.DirName, opts.NodeDaemonHome)
+		cliHome := filepath.Join(n.DirName, opts.NodeCLIHome)
+		fmt.Fprintf(&start, "\n\"$CETD\" start --home %s --minimum-gas-prices %s > %s 2>&1 &\necho $! > %s\n",
+			home, opts.MinGasPrices, filepath.Join(n.DirName, "cetd.log"), filepath.Join(n.DirName, "cetd.pid"))
+		fmt.Fprintf(&start, "\"$CETCLI\" rest-server --home %s --chain-id %s --node tcp://%s:%d --laddr tcp://%s:%d --trust-node > %s 2>&1 &\necho $! > %s\n",
+			cliHome, opts.ChainID, n.IP, n.Ports.RPC, n.IP, n.Ports.LCD,
+			filepath.Join(n.DirName, "lcd.log"), filepath.Join(n.DirName, "lcd.pid"))
+		fmt.Fprintf(&start, "echo \"%s: rpc tcp://%s:%d, lcd http://%s:%d\"\n", n.DirName, n.IP, n.Ports.RPC, n.IP, n.Ports.LCD)
+		for _, pid := range []string{"lcd.pid", "cetd.pid"} {
+			pidFile := filepath.Join(n.DirName, pid)
+			fmt.Fprintf(&stop, "[ -f %s ] && kill $(cat %s) 2>/dev/null; rm -f %s\n", pidFile, pidFile, pidFile)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(opts.OutputDir, "start.sh"), start.Bytes(), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(opts.OutputDir, "stop.sh"), stop.Bytes(), 0755)
+}
@@ -0,0 +1,595 @@
+// Package testnet generates the files of the nodes of a testnet, for 'cetd testnet'.
+package testnet
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tmconfig "github.com/tendermint/tendermint/config"
+	"github.com/tendermint/tendermint/crypto"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/types"
+	tmtime "github.com/tendermint/tendermint/types/time"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/client/keys"
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/server"
+	srvconfig "github.com/cosmos/cosmos-sdk/server/config"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	authexported "github.com/cosmos/cosmos-sdk/x/auth/exported"
+	"github.com/cosmos/cosmos-sdk/x/genaccounts"
+	"github.com/cosmos/cosmos-sdk/x/genutil"
+	"github.com/cosmos/cosmos-sdk/x/staking"
+
+	"github.com/coinexchain/cet-sdk/modules/asset"
+	"github.com/coinexchain/cet-sdk/modules/stakingx"
+	dex "github.com/coinexchain/cet-sdk/types"
+	"github.com/coinexchain/dex/app"
+)
+
+const nodeDirPerm = 0755
+
+var (
+	TokenSupply       = sdk.NewInt(588788547005740000)
+	MinSelfDelegation = int64(10000e8)
+
+	IntegrationTestChainID = "coinex-integrationtest"
+)
+
+type GenesisAccountsIterator interface {
+	IterateGenesisAccounts(
+		cdc *codec.Codec,
+		appGenesis map[string]json.RawMessage,
+		iterateFn func(authexported.Account) (stop bool),
+	)
+}
+
+// Options are how the files of a testnet are generated, the nodes are in the directories
+// NodeDirPrefix0, NodeDirPrefix1, ... of OutputDir, the validators first
+type Options struct {
+	OutputDir         string
+	ChainID           string // a random one if empty
+	MinGasPrices      string
+	NodeDirPrefix     string
+	NodeDaemonHome    string
+	NodeCLIHome       string
+	StartingIPAddress string
+	SingleHost        bool
+	PortOffset        int // added to the ports of a single-host testnet
+	Topology          *Topology
+
+	// the passwords of the keys of the validators are read from KeyPassInput line by line,
+	// app.DefaultKeyPass is used if it is nil
+	KeyPassInput io.Reader
+}
+
+// NodeInfo is a node of the generated testnet
+type NodeInfo struct {
+	Moniker   string
+	DirName   string
+	Home      string // of cetd
+	CLIHome   string // of cetcli, with the key of the validator
+	ID        string
+	IP        string
+	Ports     NodePorts
+	Validator bool
+	// the account of the validator, and the name of its key
+	Address sdk.AccAddress
+	KeyName string
+}
+
+type testnetNodeInfo struct {
+	nodeID    string
+	valPubKey crypto.PubKey
+	acc       genaccounts.GenesisAccount
+	genFile   string
+}
+
+// InitFiles creates the directories of the nodes and populates each with the necessary
+// files (private validator, genesis, config, etc.), and returns the nodes
+func InitFiles(config *tmconfig.Config, cdc *codec.Codec, mbm dex.OrderedBasicManager,
+	genAccIterator GenesisAccountsIterator, opts Options) ([]NodeInfo, error) {
+
+	outputDir := opts.OutputDir
+	if opts.ChainID == "" {
+		opts.ChainID = IntegrationTestChainID + cmn.RandStr(6)
+	}
+	topology := opts.Topology
+	if err := topology.parse(); err != nil {
+		return nil, err
+	}
+
+	numValidators := len(topology.Validators)
+	nodeIDs := make([]string, numValidators)
+	valPubKeys := make([]crypto.PubKey, numValidators)
+	monikers := make([]string, numValidators)
+	addrs := make([]nodeAddr, numValidators)
+	accs := make([]genaccounts.GenesisAccount, numValidators)
+	genFiles := make([]string, numValidators)
+	peers := make([]string, numValidators)
+	owners := make(map[string]sdk.AccAddress)
+	nodes := make([]NodeInfo, 0, numValidators+len(topology.FullNodes))
+
+	keyPassInput := opts.KeyPassInput
+	if keyPassInput == nil {
+		keyPassInput = strings.NewReader("")
+	}
+	buf := bufio.NewReader(keyPassInput)
+
+	// generate private keys, node IDs, and initial transactions
+	for i, val := range topology.Validators {
+		addr, err := testnetAddr(val.IP, i, opts)
+		if err != nil {
+			_ = os.RemoveAll(outputDir)
+			return nil, err
+		}
+		nodeDirName := fmt.Sprintf("%s%d", opts.NodeDirPrefix, i)
+		nodeInfo, err := initTestnetNode(config, cdc, buf, outputDir, opts.ChainID,
+			nodeDirName, opts.NodeDaemonHome, opts.NodeCLIHome, addr, val)
+		if err != nil {
+			return nil, err
+		}
+
+		nodeIDs[i] = nodeInfo.nodeID
+		valPubKeys[i] = nodeInfo.valPubKey
+		monikers[i] = val.Moniker
+		addrs[i] = addr
+		accs[i] = nodeInfo.acc
+		genFiles[i] = nodeInfo.genFile
+		peers[i] = fmt.Sprintf("%s@%s", nodeInfo.nodeID, addr.p2pAddr())
+		owners[val.Moniker] = nodeInfo.acc.Address
+		nodes = append(nodes, NodeInfo{
+			Moniker:   val.Moniker,
+			DirName:   nodeDirName,
+			Home:      filepath.Join(outputDir, nodeDirName, opts.NodeDaemonHome),
+			CLIHome:   filepath.Join(outputDir, nodeDirName, opts.NodeCLIHome),
+			ID:        nodeInfo.nodeID,
+			IP:        addr.ip,
+			Ports:     addr.ports,
+			Validator: true,
+			Address:   nodeInfo.acc.Address,
+			KeyName:   nodeDirName,
+		})
+	}
+
+	for _, account := range topology.Accounts {
+		addr, err := sdk.AccAddressFromBech32(account.Address)
+		if account.Address == "" {
+			addr, err = generateTestnetAccount(outputDir, account.Name)
+		}
+		if err != nil {
+			_ = os.RemoveAll(outputDir)
+			return nil, err
+		}
+		accs = append(accs, genaccounts.GenesisAccount{Address: addr, Coins: account.coins})
+		owners[account.Name] = addr
+	}
+
+	// the total supply of a token is in the account of its owner
+	for _, token := range topology.Tokens {
+		for i := range accs {
+			if accs[i].Address.Equals(owners[token.Owner]) {
+				accs[i].Coins = accs[i].Coins.Add(sdk.NewCoins(sdk.NewCoin(token.Symbol, token.totalSupply)))
+			}
+		}
+	}
+
+	if err := initGenFiles(cdc, mbm, opts.ChainID, accs, genFiles, topology, owners); err != nil {
+		return nil, err
+	}
+
+	err := collectGenFiles(
+		cdc, config, opts.ChainID, nodeIDs, valPubKeys, monikers, addrs,
+		outputDir, opts.NodeDirPrefix, opts.NodeDaemonHome, genAccIterator,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for j, node := range topology.FullNodes {
+		i := numValidators + j
+		addr, err := testnetAddr(node.IP, i, opts)
+		if err != nil {
+			return nil, err
+		}
+		nodeDirName := fmt.Sprintf("%s%d", opts.NodeDirPrefix, i)
+		nodeID, err := initTestnetFullNode(config, outputDir, nodeDirName, opts.NodeDaemonHome,
+			node.Moniker, addr, genFiles[0], strings.Join(peers, ","))
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, NodeInfo{
+			Moniker: node.Moniker,
+			DirName: nodeDirName,
+			Home:    filepath.Join(outputDir, nodeDirName, opts.NodeDaemonHome),
+			CLIHome: filepath.Join(outputDir, nodeDirName, opts.NodeCLIHome),
+			ID:      nodeID,
+			IP:      addr.ip,
+			Ports:   addr.ports,
+		})
+	}
+
+	if opts.SingleHost {
+		if err = writeScripts(opts, nodes); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+func topologyIP(ip string, i int, startingIPAddr string) (string, error) {
+	if ip != "" {
+		return ip, nil
+	}
+	return getIP(i, startingIPAddr)
+}
+
+func initTestnetNode(config *tmconfig.Config, cdc *codec.Codec, buf *bufio.Reader,
+	outputDir, chainID, nodeDirName, nodeDaemonHome, nodeCLIHome string, addr nodeAddr, val TopologyValidator,
+) (testnetNodeInfo, error) {
+
+	nodeDir := filepath.Join(outputDir, nodeDirName, nodeDaemonHome)
+	clientDir := filepath.Join(outputDir, nodeDirName, nodeCLIHome)
+	gentxsDir := filepath.Join(outputDir, "gentxs")
+
+	config.SetRoot(nodeDir)
+	addr.configure(config)
+
+	if err := mkNodeHomeDirs(outputDir, nodeDir, clientDir); err != nil {
+		_ = os.RemoveAll(outputDir)
+		return testnetNodeInfo{}, err
+	}
+
+	config.Moniker = val.Moniker
+
+	nodeID, valPubKey, err := genutil.InitializeNodeValidatorFiles(config)
+	if err != nil {
+		_ = os.RemoveAll(outputDir)
+		return testnetNodeInfo{}, err
+	}
+
+	memo := fmt.Sprintf("%s@%s", nodeID, addr.p2pAddr())
+	genFile := config.GenesisFile()
+
+	prompt := fmt.Sprintf(
+		"Password for account '%s' (default %s):", nodeDirName, app.DefaultKeyPass,
+	)
+
+	keyPass, err := client.GetPassword(prompt, buf)
+	if err != nil && keyPass != "" {
+		// An error was returned that either failed to read the password from
+		// STDIN or the given password is not empty but failed to meet minimum
+		// length requirements.
+		return testnetNodeInfo{}, err
+	}
+
+	if keyPass == "" {
+		keyPass = app.DefaultKeyPass
+	}
+
+	accAddr, secret, err := server.GenerateSaveCoinKey(clientDir, nodeDirName, keyPass, true)
+	if err != nil {
+		_ = os.RemoveAll(outputDir)
+		return testnetNodeInfo{}, err
+	}
+
+	info := map[string]string{"secret": secret}
+
+	cliPrint, err := json.Marshal(info)
+	if err != nil {
+		return testnetNodeInfo{}, err
+	}
+
+	// save private key seed words
+	err = writeFile(fmt.Sprintf("%v.json", "key_seed"), clientDir, cliPrint)
+	if err != nil {
+		return testnetNodeInfo{}, err
+	}
+
+	acc := genaccounts.GenesisAccount{
+		Address: accAddr,
+		Coins: sdk.Coins{
+			sdk.NewCoin(dex.DefaultBondDenom, val.balance),
+		},
+	}
+
+	msg := staking.NewMsgCreateValidator(
+		sdk.ValAddress(accAddr),
+		valPubKey,
+		sdk.NewCoin(dex.DefaultBondDenom, val.stake),
+		staking.NewDescription(val.Moniker, "", "", ""),
+		val.commission,
+		sdk.NewInt(MinSelfDelegation),
+	)
+	kb, err := keys.NewKeyBaseFromDir(clientDir)
+	if err != nil {
+		return testnetNodeInfo{}, err
+	}
+	tx := auth.NewStdTx([]sdk.Msg{msg}, auth.StdFee{}, []auth.StdSignature{}, memo)
+	txBldr := auth.NewTxBuilderFromCLI().WithChainID(chainID).WithMemo(memo).WithKeybase(kb)
+
+	signedTx, err := txBldr.SignStdTx(nodeDirName, app.DefaultKeyPass, tx, false)
+	if err != nil {
+		_ = os.RemoveAll(outputDir)
+		return testnetNodeInfo{}, err
+	}
+
+	txBytes, err := cdc.MarshalJSON(signedTx)
+	if err != nil {
+		_ = os.RemoveAll(outputDir)
+		return testnetNodeInfo{}, err
+	}
+
+	// gather gentxs folder
+	err = writeFile(fmt.Sprintf("%v.json", nodeDirName), gentxsDir, txBytes)
+	if err != nil {
+		_ = os.RemoveAll(outputDir)
+		return testnetNodeInfo{}, err
+	}
+
+	configFilePath := filepath.Join(nodeDir, "config/cetd.toml")
+	srvconfig.WriteConfigFile(configFilePath, srvconfig.DefaultConfig())
+	return testnetNodeInfo{
+		nodeID:    nodeID,
+		valPubKey: valPubKey,
+		acc:       acc,
+		genFile:   genFile,
+	}, nil
+}
+
+// initTestnetFullNode initializes a node which does not validate, with the genesis file
+// of the validators, and the validators as its persistent peers
+func initTestnetFullNode(config *tmconfig.Config, outputDir, nodeDirName, nodeDaemonHome,
+	moniker string, addr nodeAddr, genFile, peers string) (string, error) {
+
+	nodeDir := filepath.Join(outputDir, nodeDirName, nodeDaemonHome)
+	if err := os.MkdirAll(filepath.Join(nodeDir, "config"), nodeDirPerm); err != nil {
+		return "", err
+	}
+	config.SetRoot(nodeDir)
+	addr.configure(config)
+	config.P2P.ExternalAddress = addr.p2pAddr()
+	config.Moniker = moniker
+	config.P2P.PersistentPeers = peers
+
+	nodeID, _, err := genutil.InitializeNodeValidatorFiles(config)
+	if err != nil {
+		return "", err
+	}
+	genesis, err := ioutil.ReadFile(genFile)
+	if err != nil {
+		return "", err
+	}
+	if err = cmn.WriteFile(config.GenesisFile(), genesis, 0644); err != nil {
+		return "", err
+	}
+	tmconfig.WriteConfigFile(filepath.Join(nodeDir, "config", "config.toml"), config)
+	srvconfig.WriteConfigFile(filepath.Join(nodeDir, "config/cetd.toml"), srvconfig.DefaultConfig())
+	return nodeID, nil
+}
+
+// generateTestnetAccount saves a new key of name in the "accounts" directory, and its seed words
+func generateTestnetAccount(outputDir, name string) (sdk.AccAddress, error) {
+	dir := filepath.Join(outputDir, "accounts")
+	addr, secret, err := server.GenerateSaveCoinKey(dir, name, app.DefaultKeyPass, true)
+	if err != nil {
+		return nil, err
+	}
+	info, err := json.Marshal(map[string]string{"secret": secret})
+	if err != nil {
+		return nil, err
+	}
+	return addr, writeFile(fmt.Sprintf("%v_seed.json", name), dir, info)
+}
+
+func mkNodeHomeDirs(outputDir, nodeDir, clientDir string) error {
+	if err := os.MkdirAll(filepath.Join(nodeDir, "config"), nodeDirPerm); err != nil {
+		_ = os.RemoveAll(outputDir)
+		return err
+	}
+
+	if err := os.MkdirAll(clientDir, nodeDirPerm); err != nil {
+		_ = os.RemoveAll(outputDir)
+		return err
+	}
+
+	return nil
+}
+
+func initGenFiles(cdc *codec.Codec, mbm dex.OrderedBasicManager, chainID string,
+	accs []genaccounts.GenesisAccount, genFiles []string,
+	topology *Topology, owners map[string]sdk.AccAddress) error {
+
+	appGenState := mbm.DefaultGenesis()
+
+	// set the accounts in the genesis state
+	appGenState = genaccounts.SetGenesisStateInAppState(cdc, appGenState, accs)
+
+	addCetTokenForTesting(cdc, appGenState, TokenSupply, accs[0].Address)
+	modifyGenStateForTesting(cdc, appGenState, MinSelfDelegation)
+	if err := applyTopology(cdc, appGenState, topology, owners); err != nil {
+		return err
+	}
+
+	accs, err := assureTokenDistributionInGenesis(accs, TokenSupply)
+	if err != nil {
+		return err
+	}
+	appGenState[genaccounts.ModuleName] = cdc.MustMarshalJSON(accs)
+
+	appGenStateJSON, err := codec.MarshalJSONIndent(cdc, appGenState)
+	if err != nil {
+		return err
+	}
+
+	genDoc := types.GenesisDoc{
+		ChainID:    chainID,
+		AppState:   appGenStateJSON,
+		Validators: nil,
+	}
+
+	// generate empty genesis files for each validator and save
+	for _, genFile := range genFiles {
+		if err := genDoc.SaveAs(genFile); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func assureTokenDistributionInGenesis(accs []genaccounts.GenesisAccount,
+	testnetSupply sdk.Int) ([]genaccounts.GenesisAccount, error) {
+
+	distributedTokens := sdk.ZeroInt()
+	for _, acc := range accs {
+		distributedTokens = distributedTokens.Add(acc.Coins.AmountOf(dex.DefaultBondDenom))
+	}
+
+	if testnetSupply.LT(distributedTokens) {
+		return nil, fmt.Errorf("%s%s are distributed, more than the total supply %s%s",
+			distributedTokens, dex.DefaultBondDenom, testnetSupply, dex.DefaultBondDenom)
+	}
+	if testnetSupply.GT(distributedTokens) {
+		accs = append(accs, genaccounts.GenesisAccount{
+			Address: sdk.AccAddress(crypto.AddressHash([]byte("left_tokens"))),
+			Coins: sdk.Coins{
+				sdk.NewCoin(dex.DefaultBondDenom, testnetSupply.Sub(distributedTokens)),
+			},
+		})
+	}
+	return accs, nil
+}
+
+func modifyGenStateForTesting(cdc *codec.Codec, appGenState map[string]json.RawMessage, testnetMinSelfDelegation int64) {
+
+	var stakingxData stakingx.GenesisState
+	cdc.MustUnmarshalJSON(appGenState[stakingx.ModuleName], &stakingxData)
+
+	stakingxData.Params.MinSelfDelegation = testnetMinSelfDelegation
+	stakingxData.Params.MinMandatoryCommissionRate = sdk.NewDecWithPrec(2, 2)
+
+	appGenState[stakingx.ModuleName] = cdc.MustMarshalJSON(stakingxData)
+}
+
+func addCetTokenForTesting(cdc *codec.Codec,
+	appGenState map[string]json.RawMessage, tokenTotalSupply sdk.Int, cetOwner sdk.AccAddress) {
+
+	var assetData asset.GenesisState
+	cdc.MustUnmarshalJSON(appGenState[asset.ModuleName], &assetData)
+
+	baseToken, _ := asset.NewToken("CoinEx Chain Native Token",
+		dex.CET,
+		tokenTotalSupply,
+		cetOwner,
+		false,
+		true,
+		false,
+		false,
+		"www.coinex.org",
+		"A public chain built for the decentralized exchange",
+		"CF1FAAA36A78BE02",
+	)
+
+	var token asset.Token = baseToken
+	assetData.Tokens = []asset.Token{token}
+
+	appGenState[asset.ModuleName] = cdc.MustMarshalJSON(assetData)
+}
+
+func collectGenFiles(
+	cdc *codec.Codec, config *tmconfig.Config, chainID string,
+	nodeIDs []string, valPubKeys []crypto.PubKey,
+	monikers []string, addrs []nodeAddr, outputDir, nodeDirPrefix, nodeDaemonHome string,
+	genAccIterator GenesisAccountsIterator) error {
+
+	var appState json.RawMessage
+	genTime := tmtime.Now()
+
+	for i, moniker := range monikers {
+		nodeDirName := fmt.Sprintf("%s%d", nodeDirPrefix, i)
+		nodeDir := filepath.Join(outputDir, nodeDirName, nodeDaemonHome)
+		gentxsDir := filepath.Join(outputDir, "gentxs")
+		config.Moniker = moniker
+		addrs[i].configure(config)
+
+		config.SetRoot(nodeDir)
+
+		nodeID, valPubKey := nodeIDs[i], valPubKeys[i]
+		initCfg := genutil.NewInitConfig(chainID, gentxsDir, moniker, nodeID, valPubKey)
+
+		genDoc, err := types.GenesisDocFromFile(config.GenesisFile())
+		if err != nil {
+			return err
+		}
+
+		nodeAppState, err := genutil.GenAppStateFromConfig(cdc, config, initCfg, *genDoc, genAccIterator)
+		if err != nil {
+			return err
+		}
+
+		if appState == nil {
+			// set the canonical application state (they should not differ)
+			appState = nodeAppState
+		}
+
+		genFile := config.GenesisFile()
+
+		// overwrite each validator's genesis file to have a canonical genesis time
+		if err := genutil.ExportGenesisFileWithTime(genFile, chainID, nil, appState, genTime); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func getIP(i int, startingIPAddr string) (ip string, err error) {
+	if len(startingIPAddr) == 0 {
+		ip, err = server.ExternalIP()
+		if err != nil {
+			return "", err
+		}
+		return ip, nil
+	}
+	return calculateIP(startingIPAddr, i)
+}
+
+func calculateIP(ip string, i int) (string, error) {
+	ipv4 := net.ParseIP(ip).To4()
+	if ipv4 == nil {
+		return "", fmt.Errorf("%v: non ipv4 address", ip)
+	}
+
+	for j := 0; j < i; j++ {
+		ipv4[3]++
+	}
+
+	return ipv4.String(), nil
+}
+
+func writeFile(name string, dir string, contents []byte) error {
+	writePath := filepath.Join(dir)
+	file := filepath.Join(writePath, name)
+
+	err := cmn.EnsureDir(writePath, 0700)
+	if err != nil {
+		return err
+	}
+
+	err = cmn.WriteFile(file, contents, 0600)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
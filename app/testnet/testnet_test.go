@@ -0,0 +1,25 @@
+package testnet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+func TestParseTopology(t *testing.T) {
+	topology := DefaultTopology(2, "node")
+	require.Nil(t, topology.parse())
+	require.Equal(t, "node1", topology.Validators[1].Moniker)
+	require.Equal(t, sdk.NewInt(MinSelfDelegation).MulRaw(10), topology.Validators[1].balance)
+
+	topology.FullNodes = []TopologyNode{{Moniker: "node0"}}
+	require.EqualError(t, topology.parse(), "duplicated name: node0")
+
+	topology = DefaultTopology(1, "node")
+	topology.Tokens = []TopologyToken{{Symbol: "abc", TotalSupply: "100", Owner: "nobody"}}
+	require.EqualError(t, topology.parse(), "owner of abc is not a validator or an account: nobody")
+
+	require.EqualError(t, (&Topology{}).parse(), "there must be at least one validator")
+}
@@ -0,0 +1,332 @@
+package testnet
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/viper"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/staking"
+
+	"github.com/coinexchain/cet-sdk/modules/asset"
+	"github.com/coinexchain/cet-sdk/modules/market"
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+// Topology describes the nodes and the genesis state of a testnet, it can be read
+// from a YAML, JSON or TOML file by LoadTopology. The amounts are in sato.CET.
+//
+//	validators:
+//	  - moniker: alice
+//	    stake: 1000000000000
+//	    commission_rate: "0.05"
+//	full_nodes:
+//	  - moniker: seed
+//	accounts:
+//	  - name: faucet
+//	    coins: 100000000000000cet
+//	tokens:
+//	  - symbol: abc
+//	    name: ABC Token
+//	    total_supply: 2100000000000000
+//	    owner: faucet
+//	    identity: 552A83BA62F9B1F8
+//	markets:
+//	  - stock: abc
+//	    money: cet
+//	params:
+//	  market:
+//	    create_market_fee: 100000000
+type Topology struct {
+	Validators []TopologyValidator `mapstructure:"validators"`
+	FullNodes  []TopologyNode      `mapstructure:"full_nodes"`
+	Accounts   []TopologyAccount   `mapstructure:"accounts"`
+	Tokens     []TopologyToken     `mapstructure:"tokens"`
+	Markets    []TopologyMarket    `mapstructure:"markets"`
+
+	// the params of a module, or its section in the app state if it has no params, are
+	// overwritten by the given fields
+	Params map[string]map[string]interface{} `mapstructure:"params"`
+}
+
+// TopologyValidator is a validator node, stake is the self delegation, and the balance
+// of its account is 10 times the stake by default
+type TopologyValidator struct {
+	Moniker                 string `mapstructure:"moniker"`
+	IP                      string `mapstructure:"ip"`
+	Stake                   string `mapstructure:"stake"`
+	Balance                 string `mapstructure:"balance"`
+	CommissionRate          string `mapstructure:"commission_rate"`
+	CommissionMaxRate       string `mapstructure:"commission_max_rate"`
+	CommissionMaxChangeRate string `mapstructure:"commission_max_change_rate"`
+
+	stake      sdk.Int
+	balance    sdk.Int
+	commission staking.CommissionRates
+}
+
+// TopologyNode is a full node which does not validate
+type TopologyNode struct {
+	Moniker string `mapstructure:"moniker"`
+	IP      string `mapstructure:"ip"`
+}
+
+// TopologyAccount is a funded account, a key is generated for it if the address is empty
+type TopologyAccount struct {
+	Name    string `mapstructure:"name"`
+	Address string `mapstructure:"address"`
+	Coins   string `mapstructure:"coins"`
+
+	coins sdk.Coins
+}
+
+// TopologyToken is an issued token, its total supply is in the account of its owner,
+// which is the moniker of a validator or the name of an account
+type TopologyToken struct {
+	Symbol           string `mapstructure:"symbol"`
+	Name             string `mapstructure:"name"`
+	TotalSupply      string `mapstructure:"total_supply"`
+	Owner            string `mapstructure:"owner"`
+	Mintable         bool   `mapstructure:"mintable"`
+	Burnable         bool   `mapstructure:"burnable"`
+	AddrForbiddable  bool   `mapstructure:"addr_forbiddable"`
+	TokenForbiddable bool   `mapstructure:"token_forbiddable"`
+	URL              string `mapstructure:"url"`
+	Description      string `mapstructure:"description"`
+	Identity         string `mapstructure:"identity"`
+
+	totalSupply sdk.Int
+}
+
+// TopologyMarket is a trading pair
+type TopologyMarket struct {
+	Stock          string `mapstructure:"stock"`
+	Money          string `mapstructure:"money"`
+	PricePrecision byte   `mapstructure:"price_precision"`
+	OrderPrecision byte   `mapstructure:"order_precision"`
+}
+
+// DefaultTopology is the testnet of 'cetd testnet --v'
+func DefaultTopology(numValidators int, nodeDirPrefix string) *Topology {
+	topology := &Topology{}
+	for i := 0; i < numValidators; i++ {
+		topology.Validators = append(topology.Validators, TopologyValidator{Moniker: fmt.Sprintf("%s%d", nodeDirPrefix, i)})
+	}
+	return topology
+}
+
+// LoadTopology reads a topology from a YAML, JSON or TOML file
+func LoadTopology(file string) (*Topology, error) {
+	v := viper.New()
+	v.SetConfigFile(file)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	topology := &Topology{}
+	if err := v.Unmarshal(topology); err != nil {
+		return nil, err
+	}
+	return topology, nil
+}
+
+// parse checks the topology and fills the defaults
+func (t *Topology) parse() (err error) {
+	if len(t.Validators) == 0 {
+		return fmt.Errorf("there must be at least one validator")
+	}
+	names := make(map[string]bool)
+	checkName := func(name string) error {
+		if name == "" {
+			return fmt.Errorf("a node or an account has no name")
+		}
+		if names[name] {
+			return fmt.Errorf("duplicated name: %s", name)
+		}
+		names[name] = true
+		return nil
+	}
+
+	for i := range t.Validators {
+		v := &t.Validators[i]
+		if err = checkName(v.Moniker); err != nil {
+			return err
+		}
+		if v.stake, err = parseTopologyInt(v.Stake, sdk.NewInt(MinSelfDelegation)); err != nil {
+			return fmt.Errorf("stake of %s: %s", v.Moniker, err.Error())
+		}
+		if v.balance, err = parseTopologyInt(v.Balance, v.stake.MulRaw(10)); err != nil {
+			return fmt.Errorf("balance of %s: %s", v.Moniker, err.Error())
+		}
+		if v.balance.LT(v.stake) {
+			return fmt.Errorf("balance of %s is less than its stake", v.Moniker)
+		}
+		rate, err1 := parseTopologyDec(v.CommissionRate, sdk.NewDecWithPrec(3, 2))
+		maxRate, err2 := parseTopologyDec(v.CommissionMaxRate, sdk.OneDec())
+		maxChangeRate, err3 := parseTopologyDec(v.CommissionMaxChangeRate, sdk.NewDecWithPrec(1, 2))
+		for _, err = range []error{err1, err2, err3} {
+			if err != nil {
+				return fmt.Errorf("commission of %s: %s", v.Moniker, err.Error())
+			}
+		}
+		v.commission = staking.NewCommissionRates(rate, maxRate, maxChangeRate)
+	}
+	for _, n := range t.FullNodes {
+		if err = checkName(n.Moniker); err != nil {
+			return err
+		}
+	}
+	for i := range t.Accounts {
+		acc := &t.Accounts[i]
+		if err = checkName(acc.Name); err != nil {
+			return err
+		}
+		if acc.Address != "" {
+			if _, err = sdk.AccAddressFromBech32(acc.Address); err != nil {
+				return fmt.Errorf("address of %s: %s", acc.Name, err.Error())
+			}
+		}
+		if acc.coins, err = sdk.ParseCoins(acc.Coins); err != nil {
+			return fmt.Errorf("coins of %s: %s", acc.Name, err.Error())
+		}
+	}
+	for i := range t.Tokens {
+		token := &t.Tokens[i]
+		if token.Symbol == dex.CET {
+			return fmt.Errorf("%s is issued by the testnet", dex.CET)
+		}
+		if token.totalSupply, err = parseTopologyInt(token.TotalSupply, sdk.ZeroInt()); err != nil {
+			return fmt.Errorf("total supply of %s: %s", token.Symbol, err.Error())
+		}
+		if !names[token.Owner] || t.isFullNode(token.Owner) {
+			return fmt.Errorf("owner of %s is not a validator or an account: %s", token.Symbol, token.Owner)
+		}
+	}
+	return nil
+}
+
+func (t *Topology) isFullNode(name string) bool {
+	for _, n := range t.FullNodes {
+		if n.Moniker == name {
+			return true
+		}
+	}
+	return false
+}
+
+func parseTopologyInt(s string, defaultValue sdk.Int) (sdk.Int, error) {
+	if s == "" {
+		return defaultValue, nil
+	}
+	i, ok := sdk.NewIntFromString(s)
+	if !ok || i.IsNegative() {
+		return i, fmt.Errorf("invalid amount: %s", s)
+	}
+	return i, nil
+}
+
+func parseTopologyDec(s string, defaultValue sdk.Dec) (sdk.Dec, error) {
+	if s == "" {
+		return defaultValue, nil
+	}
+	return sdk.NewDecFromStr(s)
+}
+
+// applyTopology adds the tokens, the markets and the param overrides of the topology
+// to appGenState, owners are the addresses of the validators and the accounts by name
+func applyTopology(cdc *codec.Codec, appGenState map[string]json.RawMessage,
+	topology *Topology, owners map[string]sdk.AccAddress) error {
+
+	var assetData asset.GenesisState
+	cdc.MustUnmarshalJSON(appGenState[asset.ModuleName], &assetData)
+	for _, t := range topology.Tokens {
+		token, err := asset.NewToken(t.Name, t.Symbol, t.totalSupply, owners[t.Owner],
+			t.Mintable, t.Burnable, t.AddrForbiddable, t.TokenForbiddable, t.URL, t.Description, t.Identity)
+		if err != nil {
+			return fmt.Errorf("token %s: %s", t.Symbol, err.Error())
+		}
+		assetData.Tokens = append(assetData.Tokens, token)
+	}
+	appGenState[asset.ModuleName] = cdc.MustMarshalJSON(assetData)
+
+	var marketData market.GenesisState
+	cdc.MustUnmarshalJSON(appGenState[market.ModuleName], &marketData)
+	for _, m := range topology.Markets {
+		marketData.MarketInfos = append(marketData.MarketInfos, market.MarketInfo{
+			Stock:             m.Stock,
+			Money:             m.Money,
+			PricePrecision:    m.PricePrecision,
+			LastExecutedPrice: sdk.ZeroDec(),
+			OrderPrecision:    m.OrderPrecision,
+		})
+	}
+	appGenState[market.ModuleName] = cdc.MustMarshalJSON(marketData)
+
+	for module, params := range topology.Params {
+		section, ok := appGenState[module]
+		if !ok {
+			return fmt.Errorf("unknown module: %s", module)
+		}
+		var fields map[string]interface{}
+		d := json.NewDecoder(bytes.NewReader(section))
+		d.UseNumber()
+		if err := d.Decode(&fields); err != nil {
+			return err
+		}
+		target := fields
+		if p, ok := fields["params"].(map[string]interface{}); ok {
+			target = p
+		}
+		if err := overwriteFields(target, params, module); err != nil {
+			return err
+		}
+		bz, err := json.Marshal(fields)
+		if err != nil {
+			return err
+		}
+		appGenState[module] = bz
+	}
+	return nil
+}
+
+// overwriteFields overwrites the existing fields with the new values, the values in a
+// topology file are converted to strings for the fields of strings, e.g. the amounts
+func overwriteFields(fields map[string]interface{}, values map[string]interface{}, path string) error {
+	for k, v := range values {
+		old, ok := fields[k]
+		if !ok {
+			return fmt.Errorf("unknown field: %s.%s", path, k)
+		}
+		switch old := old.(type) {
+		case map[string]interface{}:
+			m, ok := toStringMap(v)
+			if !ok {
+				return fmt.Errorf("%s.%s must be a map", path, k)
+			}
+			if err := overwriteFields(old, m, path+"."+k); err != nil {
+				return err
+			}
+		case string:
+			fields[k] = fmt.Sprint(v)
+		default:
+			fields[k] = v
+		}
+	}
+	return nil
+}
+
+func toStringMap(v interface{}) (map[string]interface{}, bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return v, true
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[fmt.Sprint(k)] = val
+		}
+		return m, true
+	}
+	return nil, false
+}
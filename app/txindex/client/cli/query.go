@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/client/context"
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/coinexchain/dex/app/txindex"
+)
+
+const (
+	flagMsgType = "msg-type"
+	flagPage    = "page"
+	flagLimit   = "limit"
+)
+
+func GetAccountTxsCmd(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "account-txs [address]",
+		Short: "Query the transactions of an address from the node's local tx index",
+		Long: `Query the transactions signed or received by an address, newest first.
+The node must run with app-tx-index enabled.
+
+Example:
+	cetcli query account-txs coinex1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4 --msg-type MsgSend --page 2 --limit 20`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			addr, err := sdk.AccAddressFromBech32(args[0])
+			if err != nil {
+				return err
+			}
+			route := fmt.Sprintf("custom/%s/%s", txindex.QuerierRoute, txindex.QueryAccountTxs)
+			param := txindex.NewQueryAccountTxsParam(addr,
+				viper.GetString(flagMsgType), viper.GetInt(flagPage), viper.GetInt(flagLimit))
+			bz, err := cdc.MarshalJSON(param)
+			if err != nil {
+				return err
+			}
+
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+			res, _, err := cliCtx.QueryWithData(route, bz)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
+
+	cmd.Flags().String(flagMsgType, "", "Only show the transactions containing this message type, e.g. MsgSend")
+	cmd.Flags().Int(flagPage, 1, "Query a specific page of paginated results")
+	cmd.Flags().Int(flagLimit, txindex.DefaultLimit, "Number of transactions per page")
+	return client.GetCommands(cmd)[0]
+}
@@ -0,0 +1,253 @@
+package txindex
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	dbm "github.com/tendermint/tm-db"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+var (
+	txKeyPrefix      = []byte{0x01} // hash -> IndexedTx
+	addrKeyPrefix    = []byte{0x02} // address, height, index -> hash
+	msgTypeKeyPrefix = []byte{0x03} // msg type, height, index -> hash
+)
+
+// Indexer keeps a secondary index of the delivered transactions in a local DB,
+// which is not a part of the consensus state. The records of a block are written
+// in a batch when the block is committed.
+type Indexer struct {
+	mtx     sync.RWMutex
+	db      dbm.DB
+	batch   dbm.Batch
+	pending map[string]IndexedTx // the records in batch, by hash
+	closed  bool
+}
+
+func NewIndexer(db dbm.DB) *Indexer {
+	return &Indexer{db: db, batch: db.NewBatch(), pending: make(map[string]IndexedTx)}
+}
+
+// IndexTx adds the record of tx to the batch of the block, a record with the same hash is
+// replaced and its address and msg type keys are deleted
+func (ix *Indexer) IndexTx(tx IndexedTx) {
+	bz, err := json.Marshal(tx)
+	if err != nil {
+		panic(err)
+	}
+	ix.mtx.Lock()
+	defer ix.mtx.Unlock()
+	if ix.closed {
+		return
+	}
+	old, found := ix.pending[string(tx.Hash)]
+	if !found {
+		old, found = getTx(ix.db, tx.Hash)
+	}
+	if found {
+		deleteTxKeys(ix.batch, old)
+	}
+	ix.batch.Set(txKey(tx.Hash), bz)
+	for _, addr := range tx.Addresses() {
+		ix.batch.Set(addrKey(addr, tx.Height, tx.Index), tx.Hash)
+	}
+	for _, msgType := range distinct(tx.MsgTypes) {
+		ix.batch.Set(msgTypeKey(msgType, tx.Height, tx.Index), tx.Hash)
+	}
+	ix.pending[string(tx.Hash)] = tx
+}
+
+// Commit writes the records of the block, it must be called before the block is committed
+// by the app, so the block is executed and indexed again after a restart. It does nothing
+// after the index is closed.
+func (ix *Indexer) Commit() {
+	ix.mtx.Lock()
+	defer ix.mtx.Unlock()
+	if ix.closed {
+		return
+	}
+	ix.batch.WriteSync()
+	ix.batch.Close()
+	ix.batch = ix.db.NewBatch()
+	ix.pending = make(map[string]IndexedTx)
+}
+
+// Close closes the db, the records of the block not committed yet are dropped
+func (ix *Indexer) Close() {
+	ix.mtx.Lock()
+	defer ix.mtx.Unlock()
+	if ix.closed {
+		return
+	}
+	ix.closed = true
+	ix.batch.Close()
+	ix.db.Close()
+}
+
+func (ix *Indexer) GetTx(hash []byte) (tx IndexedTx, found bool) {
+	ix.mtx.RLock()
+	defer ix.mtx.RUnlock()
+	if ix.closed {
+		return
+	}
+	return getTx(ix.db, hash)
+}
+
+func getTx(db dbm.DB, hash []byte) (tx IndexedTx, found bool) {
+	bz := db.Get(txKey(hash))
+	if bz == nil {
+		return
+	}
+	if err := json.Unmarshal(bz, &tx); err != nil {
+		panic(err)
+	}
+	return tx, true
+}
+
+// QueryTxs returns a page of the transactions selected by param, newest first
+func (ix *Indexer) QueryTxs(param QueryAccountTxsParam) ([]IndexedTx, error) {
+	var prefix []byte
+	filterByMsgType := false
+	switch {
+	case len(param.Address) != 0:
+		prefix = addrPrefix(param.Address)
+		filterByMsgType = param.MsgType != ""
+	case param.MsgType != "":
+		prefix = msgTypePrefix(param.MsgType)
+	default:
+		return nil, errors.New("address or msg type is required")
+	}
+
+	page, limit := param.Page, param.Limit
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	} else if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	skip := (page - 1) * limit
+
+	ix.mtx.RLock()
+	defer ix.mtx.RUnlock()
+	if ix.closed {
+		return nil, errors.New("the tx index is closed")
+	}
+	txs := make([]IndexedTx, 0, limit)
+	iter := ix.db.ReverseIterator(prefix, prefixEnd(prefix))
+	defer iter.Close()
+	for ; iter.Valid() && len(txs) < limit; iter.Next() {
+		tx, found := getTx(ix.db, iter.Value())
+		if !found || (filterByMsgType && !containsString(tx.MsgTypes, param.MsgType)) {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		txs = append(txs, tx)
+	}
+	return txs, nil
+}
+
+// Rollback deletes the transactions after height from the index in db, as if the later blocks
+// were never executed, and returns how many are deleted. Nothing is written when dryRun is true.
+func Rollback(db dbm.DB, height int64, dryRun bool) (int, error) {
+	batch := db.NewBatch()
+	defer batch.Close()
+	count := 0
+	iter := dbm.IteratePrefix(db, txKeyPrefix)
+	for ; iter.Valid(); iter.Next() {
+		var tx IndexedTx
+		if err := json.Unmarshal(iter.Value(), &tx); err != nil {
+			iter.Close()
+			return 0, err
+		}
+		if tx.Height <= height {
+			continue
+		}
+		count++
+		deleteTxKeys(batch, tx)
+	}
+	iter.Close()
+	if !dryRun {
+		batch.WriteSync()
+	}
+	return count, nil
+}
+
+// deleteTxKeys deletes the record of tx and its address and msg type keys
+func deleteTxKeys(batch dbm.Batch, tx IndexedTx) {
+	batch.Delete(txKey(tx.Hash))
+	for _, addr := range tx.Addresses() {
+		batch.Delete(addrKey(addr, tx.Height, tx.Index))
+	}
+	for _, msgType := range distinct(tx.MsgTypes) {
+		batch.Delete(msgTypeKey(msgType, tx.Height, tx.Index))
+	}
+}
+
+func txKey(hash []byte) []byte {
+	return append(append([]byte{}, txKeyPrefix...), hash...)
+}
+
+func addrPrefix(addr sdk.AccAddress) []byte {
+	key := append(append([]byte{}, addrKeyPrefix...), byte(len(addr)))
+	return append(key, addr...)
+}
+
+func addrKey(addr sdk.AccAddress, height int64, index int) []byte {
+	return appendHeightIndex(addrPrefix(addr), height, index)
+}
+
+func msgTypePrefix(msgType string) []byte {
+	key := append(append([]byte{}, msgTypeKeyPrefix...), msgType...)
+	return append(key, 0x00)
+}
+
+func msgTypeKey(msgType string, height int64, index int) []byte {
+	return appendHeightIndex(msgTypePrefix(msgType), height, index)
+}
+
+func appendHeightIndex(key []byte, height int64, index int) []byte {
+	var buf [12]byte
+	binary.BigEndian.PutUint64(buf[:8], uint64(height))
+	binary.BigEndian.PutUint32(buf[8:], uint32(index))
+	return append(key, buf[:]...)
+}
+
+// prefixEnd returns the smallest key which is larger than all the keys with the prefix
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte{}, prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		end[i]++
+		if end[i] != 0 {
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+func distinct(list []string) []string {
+	res := make([]string, 0, len(list))
+	for _, s := range list {
+		if !containsString(res, s) {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
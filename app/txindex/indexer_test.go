@@ -0,0 +1,188 @@
+package txindex
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+func newTestTx(height int64, index int, signer, recipient sdk.AccAddress, msgTypes ...string) IndexedTx {
+	tx := IndexedTx{
+		Hash:     []byte{byte(height), byte(index)},
+		Height:   height,
+		Index:    index,
+		Signers:  []sdk.AccAddress{signer},
+		MsgTypes: msgTypes,
+	}
+	if recipient != nil {
+		tx.Recipients = []sdk.AccAddress{recipient}
+	}
+	return tx
+}
+
+func TestIndexer(t *testing.T) {
+	addr1 := sdk.AccAddress(bytes.Repeat([]byte{1}, sdk.AddrLen))
+	addr2 := sdk.AccAddress(bytes.Repeat([]byte{2}, sdk.AddrLen))
+	addr3 := sdk.AccAddress(bytes.Repeat([]byte{3}, sdk.AddrLen))
+
+	ix := NewIndexer(dbm.NewMemDB())
+	ix.IndexTx(newTestTx(1, 0, addr1, addr2, "send"))
+	ix.IndexTx(newTestTx(1, 1, addr2, nil, "create_order", "create_order"))
+	_, found := ix.GetTx([]byte{1, 0})
+	require.False(t, found)
+	ix.Commit()
+
+	ix.IndexTx(newTestTx(2, 0, addr1, addr1, "send"))
+	ix.IndexTx(newTestTx(2, 1, addr1, nil, "create_order"))
+	ix.Commit()
+
+	tx, found := ix.GetTx([]byte{1, 0})
+	require.True(t, found)
+	require.Equal(t, []sdk.AccAddress{addr2}, tx.Recipients)
+
+	txs, err := ix.QueryTxs(NewQueryAccountTxsParam(addr1, "", 1, 0))
+	require.NoError(t, err)
+	require.Equal(t, 3, len(txs))
+	require.Equal(t, int64(2), txs[0].Height)
+	require.Equal(t, 1, txs[0].Index)
+	require.Equal(t, int64(1), txs[2].Height)
+
+	txs, err = ix.QueryTxs(NewQueryAccountTxsParam(addr1, "", 2, 2))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(txs))
+	require.Equal(t, int64(1), txs[0].Height)
+
+	txs, err = ix.QueryTxs(NewQueryAccountTxsParam(addr2, "", 1, 10))
+	require.NoError(t, err)
+	require.Equal(t, 2, len(txs))
+
+	txs, err = ix.QueryTxs(NewQueryAccountTxsParam(addr1, "send", 1, 10))
+	require.NoError(t, err)
+	require.Equal(t, 2, len(txs))
+
+	txs, err = ix.QueryTxs(NewQueryAccountTxsParam(nil, "create_order", 1, 10))
+	require.NoError(t, err)
+	require.Equal(t, 2, len(txs))
+	require.Equal(t, addr1, txs[0].Signers[0])
+
+	txs, err = ix.QueryTxs(NewQueryAccountTxsParam(addr3, "", 1, 10))
+	require.NoError(t, err)
+	require.Equal(t, 0, len(txs))
+
+	_, err = ix.QueryTxs(NewQueryAccountTxsParam(nil, "", 1, 10))
+	require.Error(t, err)
+}
+
+func TestRollbackAndClose(t *testing.T) {
+	addr1 := sdk.AccAddress(bytes.Repeat([]byte{1}, sdk.AddrLen))
+	addr2 := sdk.AccAddress(bytes.Repeat([]byte{2}, sdk.AddrLen))
+
+	db := dbm.NewMemDB()
+	ix := NewIndexer(db)
+	ix.IndexTx(newTestTx(1, 0, addr1, addr2, "send"))
+	ix.Commit()
+	ix.IndexTx(newTestTx(2, 0, addr2, addr1, "send", "create_order"))
+	ix.IndexTx(newTestTx(3, 0, addr1, nil, "create_order"))
+	ix.Commit()
+
+	count, err := Rollback(db, 1, true)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+	txs, _ := ix.QueryTxs(NewQueryAccountTxsParam(addr1, "", 1, 10))
+	require.Equal(t, 3, len(txs))
+
+	count, err = Rollback(db, 1, false)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+	for _, param := range []QueryAccountTxsParam{
+		NewQueryAccountTxsParam(addr1, "", 1, 10),
+		NewQueryAccountTxsParam(addr2, "", 1, 10),
+		NewQueryAccountTxsParam(nil, "send", 1, 10),
+	} {
+		txs, err = ix.QueryTxs(param)
+		require.NoError(t, err)
+		require.Equal(t, 1, len(txs))
+		require.Equal(t, int64(1), txs[0].Height)
+	}
+	txs, _ = ix.QueryTxs(NewQueryAccountTxsParam(nil, "create_order", 1, 10))
+	require.Equal(t, 0, len(txs))
+
+	ix.IndexTx(newTestTx(2, 0, addr1, nil, "send"))
+	ix.Close()
+	ix.Close()
+	_, found := ix.GetTx([]byte{1, 0})
+	require.False(t, found)
+	_, err = ix.QueryTxs(NewQueryAccountTxsParam(addr1, "", 1, 10))
+	require.Error(t, err)
+	require.NotPanics(t, ix.Commit)
+}
+
+func TestIndexRepeatedHash(t *testing.T) {
+	addr1 := sdk.AccAddress(bytes.Repeat([]byte{1}, sdk.AddrLen))
+	addr2 := sdk.AccAddress(bytes.Repeat([]byte{2}, sdk.AddrLen))
+	addr3 := sdk.AccAddress(bytes.Repeat([]byte{3}, sdk.AddrLen))
+
+	ix := NewIndexer(dbm.NewMemDB())
+	tx := newTestTx(1, 0, addr1, nil, "send")
+	ix.IndexTx(tx)
+	ix.Commit()
+
+	// the same tx in a later block, twice
+	tx.Height, tx.Signers = 2, []sdk.AccAddress{addr2}
+	ix.IndexTx(tx)
+	tx.Index, tx.Signers, tx.MsgTypes = 1, []sdk.AccAddress{addr3}, []string{"create_order"}
+	ix.IndexTx(tx)
+	ix.Commit()
+
+	for _, param := range []QueryAccountTxsParam{
+		NewQueryAccountTxsParam(addr1, "", 1, 10),
+		NewQueryAccountTxsParam(addr2, "", 1, 10),
+		NewQueryAccountTxsParam(nil, "send", 1, 10),
+	} {
+		txs, err := ix.QueryTxs(param)
+		require.NoError(t, err)
+		require.Equal(t, 0, len(txs))
+	}
+	txs, err := ix.QueryTxs(NewQueryAccountTxsParam(addr3, "create_order", 1, 10))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(txs))
+	require.Equal(t, int64(2), txs[0].Height)
+	require.Equal(t, 1, txs[0].Index)
+}
+
+func TestQuerier(t *testing.T) {
+	cdc := codec.New()
+	addr := sdk.AccAddress(bytes.Repeat([]byte{9}, sdk.AddrLen))
+	param := cdc.MustMarshalJSON(NewQueryAccountTxsParam(addr, "", 1, 10))
+	req := abci.RequestQuery{Data: param}
+
+	_, err := NewQuerier(cdc, nil)(sdk.Context{}, []string{QueryAccountTxs}, req)
+	require.Error(t, err)
+
+	ix := NewIndexer(dbm.NewMemDB())
+	ix.IndexTx(newTestTx(1, 0, addr, nil, "send"))
+	ix.Commit()
+	querier := NewQuerier(cdc, ix)
+
+	bz, err := querier(sdk.Context{}, []string{QueryAccountTxs}, req)
+	require.Nil(t, err)
+	var txs []IndexedTx
+	cdc.MustUnmarshalJSON(bz, &txs)
+	require.Equal(t, 1, len(txs))
+
+	_, err = querier(sdk.Context{}, []string{"unknown"}, req)
+	require.Error(t, err)
+}
+
+func TestPrefixEnd(t *testing.T) {
+	require.Equal(t, []byte{0x02}, prefixEnd([]byte{0x01}))
+	require.Equal(t, []byte{0x02}, prefixEnd([]byte{0x01, 0xff}))
+	require.Nil(t, prefixEnd([]byte{0xff}))
+}
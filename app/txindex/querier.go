@@ -0,0 +1,42 @@
+package txindex
+
+import (
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// NewQuerier creates a querier for the local transaction index, ix is nil when the
+// index is not enabled on this node
+func NewQuerier(cdc *codec.Codec, ix *Indexer) sdk.Querier {
+	return func(ctx sdk.Context, path []string, req abci.RequestQuery) ([]byte, sdk.Error) {
+		if ix == nil {
+			return nil, sdk.ErrUnknownRequest("tx index is not enabled on this node")
+		}
+		switch path[0] {
+		case QueryAccountTxs:
+			return queryAccountTxs(cdc, ix, req)
+		default:
+			return nil, sdk.ErrUnknownRequest("query symbol : " + path[0])
+		}
+	}
+}
+
+func queryAccountTxs(cdc *codec.Codec, ix *Indexer, req abci.RequestQuery) ([]byte, sdk.Error) {
+	var param QueryAccountTxsParam
+	if err := cdc.UnmarshalJSON(req.Data, &param); err != nil {
+		return nil, sdk.ErrUnknownRequest(sdk.AppendMsgToErr("failed to parse param", err.Error()))
+	}
+
+	txs, err := ix.QueryTxs(param)
+	if err != nil {
+		return nil, sdk.ErrUnknownRequest(err.Error())
+	}
+
+	bz, err := codec.MarshalJSONIndent(cdc, txs)
+	if err != nil {
+		return nil, sdk.ErrInternal(sdk.AppendMsgToErr("could not marshal result to JSON", err.Error()))
+	}
+	return bz, nil
+}
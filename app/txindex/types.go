@@ -0,0 +1,61 @@
+package txindex
+
+import (
+	cmn "github.com/tendermint/tendermint/libs/common"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+const (
+	// QuerierRoute is the querier route of the local transaction index
+	QuerierRoute = "txindex"
+
+	QueryAccountTxs = "account-txs"
+
+	DefaultLimit = 30
+	MaxLimit     = 100
+)
+
+// IndexedTx is the record kept for each delivered transaction
+type IndexedTx struct {
+	Hash       cmn.HexBytes     `json:"hash"`
+	Height     int64            `json:"height"`
+	Index      int              `json:"index"`
+	Code       uint32           `json:"code"`
+	Signers    []sdk.AccAddress `json:"signers"`
+	Recipients []sdk.AccAddress `json:"recipients"`
+	MsgTypes   []string         `json:"msg_types"`
+}
+
+// Addresses returns the distinct signers and recipients of the transaction
+func (tx IndexedTx) Addresses() []sdk.AccAddress {
+	seen := make(map[string]struct{})
+	addrs := make([]sdk.AccAddress, 0, len(tx.Signers)+len(tx.Recipients))
+	for _, list := range [][]sdk.AccAddress{tx.Signers, tx.Recipients} {
+		for _, addr := range list {
+			if _, ok := seen[string(addr)]; !ok {
+				seen[string(addr)] = struct{}{}
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+	return addrs
+}
+
+// QueryAccountTxsParam selects the transactions of an address and/or of a message type,
+// newest first; Page starts from 1
+type QueryAccountTxsParam struct {
+	Address sdk.AccAddress `json:"address"`
+	MsgType string         `json:"msg_type"`
+	Page    int            `json:"page"`
+	Limit   int            `json:"limit"`
+}
+
+func NewQueryAccountTxsParam(addr sdk.AccAddress, msgType string, page, limit int) QueryAccountTxsParam {
+	return QueryAccountTxsParam{
+		Address: addr,
+		MsgType: msgType,
+		Page:    page,
+		Limit:   limit,
+	}
+}
@@ -0,0 +1,23 @@
+package upgrade
+
+import (
+	"fmt"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// BeginBlocker applies the scheduled upgrade at its height. It panics to halt the chain
+// if this binary has no handler for the upgrade, so only a newer binary can go on.
+func BeginBlocker(ctx sdk.Context, k Keeper) {
+	plan, found := k.GetUpgradePlan(ctx)
+	if !found || ctx.BlockHeight() < plan.Height {
+		return
+	}
+	if !k.HasHandler(plan.Name) {
+		msg := fmt.Sprintf("UPGRADE \"%s\" NEEDED at height %d: %s", plan.Name, plan.Height, plan.Info)
+		ctx.Logger().Error(msg)
+		panic(msg)
+	}
+	ctx.Logger().Info(fmt.Sprintf("applying upgrade \"%s\" at height %d", plan.Name, ctx.BlockHeight()))
+	k.ApplyUpgrade(ctx, plan)
+}
@@ -0,0 +1,33 @@
+package upgrade
+
+import (
+	"github.com/coinexchain/dex/app/upgrade/internal/types"
+)
+
+const (
+	ModuleName   = types.ModuleName
+	StoreKey     = types.StoreKey
+	RouterKey    = types.RouterKey
+	QuerierRoute = types.QuerierRoute
+	QueryPlan    = types.QueryPlan
+	QueryApplied = types.QueryApplied
+
+	ProposalTypeSoftwareUpgrade       = types.ProposalTypeSoftwareUpgrade
+	ProposalTypeCancelSoftwareUpgrade = types.ProposalTypeCancelSoftwareUpgrade
+)
+
+var (
+	ModuleCdc                        = types.ModuleCdc
+	RegisterCodec                    = types.RegisterCodec
+	ErrInvalidPlan                   = types.ErrInvalidPlan
+	NewSoftwareUpgradeProposal       = types.NewSoftwareUpgradeProposal
+	NewCancelSoftwareUpgradeProposal = types.NewCancelSoftwareUpgradeProposal
+)
+
+type (
+	Plan                          = types.Plan
+	AppliedUpgrade                = types.AppliedUpgrade
+	QueryAppliedParams            = types.QueryAppliedParams
+	SoftwareUpgradeProposal       = types.SoftwareUpgradeProposal
+	CancelSoftwareUpgradeProposal = types.CancelSoftwareUpgradeProposal
+)
@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/client/context"
+	"github.com/cosmos/cosmos-sdk/codec"
+
+	"github.com/coinexchain/dex/app/upgrade/internal/types"
+)
+
+func GetQueryCmd(cdc *codec.Codec) *cobra.Command {
+	upgradeQueryCmd := &cobra.Command{
+		Use:                        types.ModuleName,
+		Short:                      "Querying commands for the upgrade module",
+		DisableFlagParsing:         true,
+		SuggestionsMinimumDistance: 2,
+		RunE:                       client.ValidateCmd,
+	}
+	upgradeQueryCmd.AddCommand(client.GetCommands(
+		GetCmdQueryPlan(cdc),
+		GetCmdQueryApplied(cdc),
+	)...)
+	return upgradeQueryCmd
+}
+
+func GetCmdQueryPlan(cdc *codec.Codec) *cobra.Command {
+	return &cobra.Command{
+		Use:   "plan",
+		Short: "Query the scheduled upgrade plan",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+			route := fmt.Sprintf("custom/%s/%s", types.QuerierRoute, types.QueryPlan)
+			res, _, err := cliCtx.QueryWithData(route, nil)
+			if err != nil {
+				return err
+			}
+			if len(res) == 0 {
+				return fmt.Errorf("no upgrade is scheduled")
+			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
+}
+
+func GetCmdQueryApplied(cdc *codec.Codec) *cobra.Command {
+	return &cobra.Command{
+		Use:   "applied [upgrade-name]",
+		Short: "Query the height at which an upgrade was applied, 0 if it was not",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+			bz, err := cdc.MarshalJSON(types.QueryAppliedParams{Name: args[0]})
+			if err != nil {
+				return err
+			}
+			route := fmt.Sprintf("custom/%s/%s", types.QuerierRoute, types.QueryApplied)
+			res, _, err := cliCtx.QueryWithData(route, bz)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(res))
+			return nil
+		},
+	}
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/cosmos/cosmos-sdk/client/context"
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	"github.com/cosmos/cosmos-sdk/x/auth/client/utils"
+	"github.com/cosmos/cosmos-sdk/x/gov"
+	govcli "github.com/cosmos/cosmos-sdk/x/gov/client/cli"
+
+	"github.com/coinexchain/dex/app/upgrade/internal/types"
+)
+
+const (
+	FlagUpgradeName   = "upgrade-name"
+	FlagUpgradeHeight = "upgrade-height"
+	FlagUpgradeInfo   = "upgrade-info"
+)
+
+// GetCmdSubmitUpgradeProposal implements the command to submit a software-upgrade proposal
+func GetCmdSubmitUpgradeProposal(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "software-upgrade",
+		Args:  cobra.NoArgs,
+		Short: "Submit a software upgrade proposal",
+		Long: `Submit a software upgrade proposal along with an initial deposit.
+When it passes, the chain halts at the upgrade height until the binary which has the handler
+of the upgrade is started, and the handler migrates the stores at that height.
+
+Example:
+	cetcli tx gov submit-proposal software-upgrade --upgrade-name=v0.3 --upgrade-height=5000000 \
+		--upgrade-info="https://github.com/coinexchain/dex/releases" --title="Upgrade to v0.3" \
+		--description="..." --deposit=10000000000cet --from=<key_or_address>`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			plan := types.Plan{
+				Name:   viper.GetString(FlagUpgradeName),
+				Height: viper.GetInt64(FlagUpgradeHeight),
+				Info:   viper.GetString(FlagUpgradeInfo),
+			}
+			content := types.NewSoftwareUpgradeProposal(
+				viper.GetString(govcli.FlagTitle), viper.GetString(govcli.FlagDescription), plan)
+			return submitProposal(cdc, content)
+		},
+	}
+	cmd.Flags().String(FlagUpgradeName, "", "name of the upgrade, which the new binary has a handler for")
+	cmd.Flags().Int64(FlagUpgradeHeight, 0, "height at which the upgrade is applied")
+	cmd.Flags().String(FlagUpgradeInfo, "", "information about the upgrade, such as where to download the new binary")
+	addProposalFlags(cmd)
+	return cmd
+}
+
+// GetCmdSubmitCancelUpgradeProposal implements the command to submit a cancel-software-upgrade proposal
+func GetCmdSubmitCancelUpgradeProposal(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cancel-software-upgrade",
+		Args:  cobra.NoArgs,
+		Short: "Submit a proposal to cancel the scheduled software upgrade",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			content := types.NewCancelSoftwareUpgradeProposal(
+				viper.GetString(govcli.FlagTitle), viper.GetString(govcli.FlagDescription))
+			return submitProposal(cdc, content)
+		},
+	}
+	addProposalFlags(cmd)
+	return cmd
+}
+
+func addProposalFlags(cmd *cobra.Command) {
+	cmd.Flags().String(govcli.FlagTitle, "", "title of proposal")
+	cmd.Flags().String(govcli.FlagDescription, "", "description of proposal")
+	cmd.Flags().String(govcli.FlagDeposit, "", "deposit of proposal")
+}
+
+func submitProposal(cdc *codec.Codec, content gov.Content) error {
+	txBldr := auth.NewTxBuilderFromCLI().WithTxEncoder(utils.GetTxEncoder(cdc))
+	cliCtx := context.NewCLIContext().WithCodec(cdc)
+
+	deposit, err := sdk.ParseCoins(viper.GetString(govcli.FlagDeposit))
+	if err != nil {
+		return err
+	}
+	msg := gov.NewMsgSubmitProposal(content, deposit, cliCtx.GetFromAddress())
+	if err := msg.ValidateBasic(); err != nil {
+		return err
+	}
+	return utils.GenerateOrBroadcastMsgs(cliCtx, txBldr, []sdk.Msg{msg})
+}
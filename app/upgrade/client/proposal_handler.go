@@ -0,0 +1,14 @@
+package client
+
+import (
+	govclient "github.com/cosmos/cosmos-sdk/x/gov/client"
+
+	"github.com/coinexchain/dex/app/upgrade/client/cli"
+	"github.com/coinexchain/dex/app/upgrade/client/rest"
+)
+
+// software upgrade proposal handlers
+var (
+	ProposalHandler       = govclient.NewProposalHandler(cli.GetCmdSubmitUpgradeProposal, rest.ProposalRESTHandler)
+	CancelProposalHandler = govclient.NewProposalHandler(cli.GetCmdSubmitCancelUpgradeProposal, rest.CancelProposalRESTHandler)
+)
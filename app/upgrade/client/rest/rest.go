@@ -0,0 +1,120 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/cosmos/cosmos-sdk/client/context"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/rest"
+	"github.com/cosmos/cosmos-sdk/x/auth/client/utils"
+	"github.com/cosmos/cosmos-sdk/x/gov"
+	govrest "github.com/cosmos/cosmos-sdk/x/gov/client/rest"
+
+	"github.com/coinexchain/dex/app/upgrade/internal/types"
+)
+
+// SoftwareUpgradeProposalReq defines a software upgrade proposal request body.
+type SoftwareUpgradeProposalReq struct {
+	BaseReq rest.BaseReq `json:"base_req"`
+
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	Plan        types.Plan     `json:"plan"`
+	Proposer    sdk.AccAddress `json:"proposer"`
+	Deposit     sdk.Coins      `json:"deposit"`
+}
+
+// register REST routes
+func RegisterRoutes(cliCtx context.CLIContext, r *mux.Router) {
+	r.HandleFunc("/upgrade/plan", queryPlanHandlerFn(cliCtx)).Methods("GET")
+	r.HandleFunc("/upgrade/applied/{name}", queryAppliedHandlerFn(cliCtx)).Methods("GET")
+}
+
+// ProposalRESTHandler returns a ProposalRESTHandler that exposes the software upgrade REST handler with a given sub-route.
+func ProposalRESTHandler(cliCtx context.CLIContext) govrest.ProposalRESTHandler {
+	return govrest.ProposalRESTHandler{
+		SubRoute: "software_upgrade",
+		Handler:  postProposalHandlerFn(cliCtx, false),
+	}
+}
+
+// CancelProposalRESTHandler returns a ProposalRESTHandler that exposes the cancel software upgrade REST handler with a given sub-route.
+func CancelProposalRESTHandler(cliCtx context.CLIContext) govrest.ProposalRESTHandler {
+	return govrest.ProposalRESTHandler{
+		SubRoute: "cancel_software_upgrade",
+		Handler:  postProposalHandlerFn(cliCtx, true),
+	}
+}
+
+func postProposalHandlerFn(cliCtx context.CLIContext, cancel bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req SoftwareUpgradeProposalReq
+		if !rest.ReadRESTReq(w, r, cliCtx.Codec, &req) {
+			return
+		}
+
+		req.BaseReq = req.BaseReq.Sanitize()
+		if !req.BaseReq.ValidateBasic(w) {
+			return
+		}
+
+		var content gov.Content = types.NewSoftwareUpgradeProposal(req.Title, req.Description, req.Plan)
+		if cancel {
+			content = types.NewCancelSoftwareUpgradeProposal(req.Title, req.Description)
+		}
+		msg := gov.NewMsgSubmitProposal(content, req.Deposit, req.Proposer)
+		if err := msg.ValidateBasic(); err != nil {
+			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		utils.WriteGenerateStdTxResponse(w, cliCtx, req.BaseReq, []sdk.Msg{msg})
+	}
+}
+
+func queryPlanHandlerFn(cliCtx context.CLIContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cliCtx, ok := rest.ParseQueryHeightOrReturnBadRequest(w, cliCtx, r)
+		if !ok {
+			return
+		}
+
+		route := fmt.Sprintf("custom/%s/%s", types.QuerierRoute, types.QueryPlan)
+		res, height, err := cliCtx.QueryWithData(route, nil)
+		if err != nil {
+			rest.WriteErrorResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		cliCtx = cliCtx.WithHeight(height)
+		rest.PostProcessResponse(w, cliCtx, res)
+	}
+}
+
+func queryAppliedHandlerFn(cliCtx context.CLIContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cliCtx, ok := rest.ParseQueryHeightOrReturnBadRequest(w, cliCtx, r)
+		if !ok {
+			return
+		}
+
+		bz, err := cliCtx.Codec.MarshalJSON(types.QueryAppliedParams{Name: mux.Vars(r)["name"]})
+		if err != nil {
+			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		route := fmt.Sprintf("custom/%s/%s", types.QuerierRoute, types.QueryApplied)
+		res, height, err := cliCtx.QueryWithData(route, bz)
+		if err != nil {
+			rest.WriteErrorResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		cliCtx = cliCtx.WithHeight(height)
+		rest.PostProcessResponse(w, cliCtx, res)
+	}
+}
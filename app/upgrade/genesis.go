@@ -0,0 +1,62 @@
+package upgrade
+
+import (
+	"errors"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/coinexchain/dex/app/upgrade/internal/types"
+)
+
+type GenesisState struct {
+	Plan    *Plan            `json:"plan"`
+	Applied []AppliedUpgrade `json:"applied"`
+}
+
+func NewGenesisState(plan *Plan, applied []AppliedUpgrade) GenesisState {
+	return GenesisState{
+		Plan:    plan,
+		Applied: applied,
+	}
+}
+
+func DefaultGenesisState() GenesisState {
+	return NewGenesisState(nil, []AppliedUpgrade{})
+}
+
+func InitGenesis(ctx sdk.Context, k Keeper, data GenesisState) {
+	if !k.IsActive() {
+		if data.Plan != nil || len(data.Applied) != 0 {
+			panic("upgrade module is not active, but its genesis state is not empty")
+		}
+		return
+	}
+	if data.Plan != nil {
+		ctx.KVStore(k.storeKey).Set(types.PlanKey, k.cdc.MustMarshalBinaryBare(*data.Plan))
+	}
+	for _, applied := range data.Applied {
+		k.setApplied(ctx, applied.Name, applied.Height)
+	}
+}
+
+func ExportGenesis(ctx sdk.Context, k Keeper) GenesisState {
+	var plan *Plan
+	if p, found := k.GetUpgradePlan(ctx); found {
+		plan = &p
+	}
+	return NewGenesisState(plan, k.GetAllApplied(ctx))
+}
+
+func (data GenesisState) Validate() error {
+	if data.Plan != nil {
+		if err := data.Plan.ValidateBasic(); err != nil {
+			return err
+		}
+	}
+	for _, applied := range data.Applied {
+		if len(applied.Name) == 0 {
+			return errors.New("empty name of applied upgrade")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package upgrade
+
+import (
+	"fmt"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/gov"
+)
+
+// NewSoftwareUpgradeProposalHandler handles the upgrade proposals which have passed
+func NewSoftwareUpgradeProposalHandler(k Keeper) gov.Handler {
+	return func(ctx sdk.Context, content gov.Content) sdk.Error {
+		switch c := content.(type) {
+		case SoftwareUpgradeProposal:
+			return k.ScheduleUpgrade(ctx, c.Plan)
+		case CancelSoftwareUpgradeProposal:
+			k.ClearUpgradePlan(ctx)
+			return nil
+		default:
+			errMsg := fmt.Sprintf("unrecognized upgrade proposal content type: %T", c)
+			return sdk.ErrUnknownRequest(errMsg)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/gov"
+)
+
+const (
+	ModuleName   = "upgrade"
+	StoreKey     = ModuleName
+	RouterKey    = ModuleName
+	QuerierRoute = ModuleName
+
+	QueryPlan    = "plan"
+	QueryApplied = "applied"
+
+	// gov rejects its own SoftwareUpgrade proposals, which have no plan, so we use other types
+	ProposalTypeSoftwareUpgrade       = "ScheduledSoftwareUpgrade"
+	ProposalTypeCancelSoftwareUpgrade = "CancelSoftwareUpgrade"
+
+	DefaultCodespace sdk.CodespaceType = ModuleName
+
+	CodeInvalidPlan sdk.CodeType = 1
+)
+
+var (
+	PlanKey          = []byte{0x01}
+	AppliedKeyPrefix = []byte{0x02}
+)
+
+func AppliedKey(name string) []byte {
+	return append(AppliedKeyPrefix, name...)
+}
+
+// Plan is an upgrade scheduled at Height, the binary running at Height must have a
+// handler registered for Name
+type Plan struct {
+	Name   string `json:"name"`
+	Height int64  `json:"height"`
+	Info   string `json:"info"`
+}
+
+func (p Plan) ValidateBasic() sdk.Error {
+	if len(strings.TrimSpace(p.Name)) == 0 {
+		return ErrInvalidPlan("name cannot be empty")
+	}
+	if p.Height <= 0 {
+		return ErrInvalidPlan("height must be positive")
+	}
+	return nil
+}
+
+func (p Plan) String() string {
+	return fmt.Sprintf(`Upgrade Plan
+  Name:   %s
+  Height: %d
+  Info:   %s`, p.Name, p.Height, p.Info)
+}
+
+func ErrInvalidPlan(msg string) sdk.Error {
+	return sdk.NewError(DefaultCodespace, CodeInvalidPlan, msg)
+}
+
+// SoftwareUpgradeProposal schedules Plan when it passes
+type SoftwareUpgradeProposal struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Plan        Plan   `json:"plan"`
+}
+
+var _ gov.Content = SoftwareUpgradeProposal{}
+
+func NewSoftwareUpgradeProposal(title, description string, plan Plan) SoftwareUpgradeProposal {
+	return SoftwareUpgradeProposal{Title: title, Description: description, Plan: plan}
+}
+
+// nolint
+func (sup SoftwareUpgradeProposal) GetTitle() string       { return sup.Title }
+func (sup SoftwareUpgradeProposal) GetDescription() string { return sup.Description }
+func (sup SoftwareUpgradeProposal) ProposalRoute() string  { return RouterKey }
+func (sup SoftwareUpgradeProposal) ProposalType() string   { return ProposalTypeSoftwareUpgrade }
+func (sup SoftwareUpgradeProposal) ValidateBasic() sdk.Error {
+	if err := sup.Plan.ValidateBasic(); err != nil {
+		return err
+	}
+	return gov.ValidateAbstract(DefaultCodespace, sup)
+}
+
+func (sup SoftwareUpgradeProposal) String() string {
+	return fmt.Sprintf(`Software Upgrade Proposal:
+  Title:       %s
+  Description: %s
+  Plan:        %s at height %d
+`, sup.Title, sup.Description, sup.Plan.Name, sup.Plan.Height)
+}
+
+// CancelSoftwareUpgradeProposal cancels the scheduled plan when it passes
+type CancelSoftwareUpgradeProposal struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+var _ gov.Content = CancelSoftwareUpgradeProposal{}
+
+func NewCancelSoftwareUpgradeProposal(title, description string) CancelSoftwareUpgradeProposal {
+	return CancelSoftwareUpgradeProposal{Title: title, Description: description}
+}
+
+// nolint
+func (csp CancelSoftwareUpgradeProposal) GetTitle() string       { return csp.Title }
+func (csp CancelSoftwareUpgradeProposal) GetDescription() string { return csp.Description }
+func (csp CancelSoftwareUpgradeProposal) ProposalRoute() string  { return RouterKey }
+func (csp CancelSoftwareUpgradeProposal) ProposalType() string {
+	return ProposalTypeCancelSoftwareUpgrade
+}
+func (csp CancelSoftwareUpgradeProposal) ValidateBasic() sdk.Error {
+	return gov.ValidateAbstract(DefaultCodespace, csp)
+}
+
+func (csp CancelSoftwareUpgradeProposal) String() string {
+	return fmt.Sprintf(`Cancel Software Upgrade Proposal:
+  Title:       %s
+  Description: %s
+`, csp.Title, csp.Description)
+}
+
+// AppliedUpgrade is an upgrade which has been done at Height
+type AppliedUpgrade struct {
+	Name   string `json:"name"`
+	Height int64  `json:"height"`
+}
+
+type QueryAppliedParams struct {
+	Name string `json:"name"`
+}
+
+func RegisterCodec(cdc *codec.Codec) {
+	cdc.RegisterConcrete(SoftwareUpgradeProposal{}, "upgrade/SoftwareUpgradeProposal", nil)
+	cdc.RegisterConcrete(CancelSoftwareUpgradeProposal{}, "upgrade/CancelSoftwareUpgradeProposal", nil)
+}
+
+var ModuleCdc *codec.Codec
+
+func init() {
+	gov.RegisterProposalType(ProposalTypeSoftwareUpgrade)
+	gov.RegisterProposalType(ProposalTypeCancelSoftwareUpgrade)
+	gov.RegisterProposalTypeCodec(SoftwareUpgradeProposal{}, "upgrade/SoftwareUpgradeProposal")
+	gov.RegisterProposalTypeCodec(CancelSoftwareUpgradeProposal{}, "upgrade/CancelSoftwareUpgradeProposal")
+
+	ModuleCdc = codec.New()
+	RegisterCodec(ModuleCdc)
+	ModuleCdc.Seal()
+}
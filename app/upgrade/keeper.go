@@ -0,0 +1,141 @@
+package upgrade
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/coinexchain/dex/app/upgrade/internal/types"
+)
+
+// Handler migrates the stores when the upgrade of plan is applied
+type Handler func(ctx sdk.Context, plan Plan)
+
+type Keeper struct {
+	storeKey sdk.StoreKey
+	cdc      *codec.Codec
+	handlers map[string]Handler
+	isActive func() bool
+}
+
+func NewKeeper(storeKey sdk.StoreKey, cdc *codec.Codec) Keeper {
+	return Keeper{
+		storeKey: storeKey,
+		cdc:      cdc,
+		handlers: make(map[string]Handler),
+	}
+}
+
+// SetActiveChecker makes the keeper act as if the store were empty when isActive returns false,
+// which is the case when the store is not mounted yet
+func (k *Keeper) SetActiveChecker(isActive func() bool) {
+	k.isActive = isActive
+}
+
+// IsActive returns false when the store of the keeper is not mounted
+func (k Keeper) IsActive() bool {
+	return k.isActive == nil || k.isActive()
+}
+
+// SetUpgradeHandler registers the handler of the upgrade named name, which must be
+// done before the binary is started
+func (k Keeper) SetUpgradeHandler(name string, handler Handler) {
+	k.handlers[name] = handler
+}
+
+func (k Keeper) HasHandler(name string) bool {
+	_, ok := k.handlers[name]
+	return ok
+}
+
+// ScheduleUpgrade replaces the scheduled plan if any
+func (k Keeper) ScheduleUpgrade(ctx sdk.Context, plan Plan) sdk.Error {
+	if !k.IsActive() {
+		return ErrInvalidPlan("upgrade module is not active yet")
+	}
+	if err := plan.ValidateBasic(); err != nil {
+		return err
+	}
+	if plan.Height <= ctx.BlockHeight() {
+		return ErrInvalidPlan(fmt.Sprintf("height %d has passed", plan.Height))
+	}
+	if k.GetAppliedHeight(ctx, plan.Name) != 0 {
+		return ErrInvalidPlan(fmt.Sprintf("upgrade %s has been applied", plan.Name))
+	}
+	ctx.KVStore(k.storeKey).Set(types.PlanKey, k.cdc.MustMarshalBinaryBare(plan))
+	return nil
+}
+
+func (k Keeper) GetUpgradePlan(ctx sdk.Context) (plan Plan, found bool) {
+	if !k.IsActive() {
+		return plan, false
+	}
+	bz := ctx.KVStore(k.storeKey).Get(types.PlanKey)
+	if bz == nil {
+		return plan, false
+	}
+	k.cdc.MustUnmarshalBinaryBare(bz, &plan)
+	return plan, true
+}
+
+func (k Keeper) ClearUpgradePlan(ctx sdk.Context) {
+	if !k.IsActive() {
+		return
+	}
+	ctx.KVStore(k.storeKey).Delete(types.PlanKey)
+}
+
+// GetAppliedHeight returns the height at which the upgrade named name was applied, or 0
+func (k Keeper) GetAppliedHeight(ctx sdk.Context, name string) int64 {
+	if !k.IsActive() {
+		return 0
+	}
+	bz := ctx.KVStore(k.storeKey).Get(types.AppliedKey(name))
+	if bz == nil {
+		return 0
+	}
+	var height int64
+	k.cdc.MustUnmarshalBinaryBare(bz, &height)
+	return height
+}
+
+func (k Keeper) setApplied(ctx sdk.Context, name string, height int64) {
+	ctx.KVStore(k.storeKey).Set(types.AppliedKey(name), k.cdc.MustMarshalBinaryBare(height))
+}
+
+func (k Keeper) GetAllApplied(ctx sdk.Context) []AppliedUpgrade {
+	applied := make([]AppliedUpgrade, 0)
+	if !k.IsActive() {
+		return applied
+	}
+	iter := sdk.KVStorePrefixIterator(ctx.KVStore(k.storeKey), types.AppliedKeyPrefix)
+	defer iter.Close()
+	for ; iter.Valid(); iter.Next() {
+		var height int64
+		k.cdc.MustUnmarshalBinaryBare(iter.Value(), &height)
+		applied = append(applied, AppliedUpgrade{Name: string(iter.Key()[len(types.AppliedKeyPrefix):]), Height: height})
+	}
+	sort.Slice(applied, func(i, j int) bool { return applied[i].Height < applied[j].Height })
+	return applied
+}
+
+// ApplyUpgrade runs the handler of plan and marks it as applied
+func (k Keeper) ApplyUpgrade(ctx sdk.Context, plan Plan) {
+	k.handlers[plan.Name](ctx, plan)
+	k.setApplied(ctx, plan.Name, ctx.BlockHeight())
+	k.ClearUpgradePlan(ctx)
+}
+
+// CheckBinary returns an error if this binary does not know an applied upgrade,
+// that is, it is older than the state it is going to run on
+func (k Keeper) CheckBinary(ctx sdk.Context) error {
+	for _, applied := range k.GetAllApplied(ctx) {
+		if !k.HasHandler(applied.Name) {
+			return fmt.Errorf("upgrade %s was applied at height %d, but this binary does not know it, "+
+				"please run a newer one", applied.Name, applied.Height)
+		}
+	}
+	return nil
+}
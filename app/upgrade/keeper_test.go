@@ -0,0 +1,107 @@
+package upgrade
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/store"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+func newTestKeeper(t *testing.T, height int64) (sdk.Context, Keeper) {
+	key := sdk.NewKVStoreKey(StoreKey)
+	db := dbm.NewMemDB()
+	ms := store.NewCommitMultiStore(db)
+	ms.MountStoreWithDB(key, sdk.StoreTypeIAVL, db)
+	require.NoError(t, ms.LoadLatestVersion())
+	ctx := sdk.NewContext(ms, abci.Header{Height: height}, false, log.NewNopLogger())
+	return ctx, NewKeeper(key, codec.New())
+}
+
+func TestScheduleUpgrade(t *testing.T) {
+	ctx, k := newTestKeeper(t, 10)
+	handler := NewSoftwareUpgradeProposalHandler(k)
+
+	require.NotNil(t, handler(ctx, NewSoftwareUpgradeProposal("t", "d", Plan{Name: "v2", Height: 10})))
+	require.NotNil(t, handler(ctx, NewSoftwareUpgradeProposal("t", "d", Plan{Name: "", Height: 20})))
+	_, found := k.GetUpgradePlan(ctx)
+	require.False(t, found)
+
+	plan := Plan{Name: "v2", Height: 20, Info: "info"}
+	require.Nil(t, handler(ctx, NewSoftwareUpgradeProposal("t", "d", plan)))
+	got, found := k.GetUpgradePlan(ctx)
+	require.True(t, found)
+	require.Equal(t, plan, got)
+
+	require.Nil(t, handler(ctx, NewCancelSoftwareUpgradeProposal("t", "d")))
+	_, found = k.GetUpgradePlan(ctx)
+	require.False(t, found)
+}
+
+func TestBeginBlocker(t *testing.T) {
+	ctx, k := newTestKeeper(t, 10)
+	plan := Plan{Name: "v2", Height: 12}
+	require.Nil(t, k.ScheduleUpgrade(ctx, plan))
+
+	// an old binary goes on before the upgrade height, and halts at it
+	BeginBlocker(ctx.WithBlockHeight(11), k)
+	require.Panics(t, func() { BeginBlocker(ctx.WithBlockHeight(12), k) })
+
+	var applied []int64
+	k.SetUpgradeHandler("v2", func(ctx sdk.Context, p Plan) {
+		require.Equal(t, plan, p)
+		applied = append(applied, ctx.BlockHeight())
+	})
+	BeginBlocker(ctx.WithBlockHeight(11), k)
+	require.Empty(t, applied)
+	BeginBlocker(ctx.WithBlockHeight(12), k)
+	require.Equal(t, []int64{12}, applied)
+	_, found := k.GetUpgradePlan(ctx)
+	require.False(t, found)
+	require.Equal(t, int64(12), k.GetAppliedHeight(ctx, "v2"))
+	require.Nil(t, k.CheckBinary(ctx))
+
+	// can not be scheduled again
+	require.NotNil(t, k.ScheduleUpgrade(ctx, Plan{Name: "v2", Height: 20}))
+
+	// a binary without the handler
+	k2 := NewKeeper(k.storeKey, k.cdc)
+	require.NotNil(t, k2.CheckBinary(ctx))
+}
+
+func TestGenesis(t *testing.T) {
+	ctx, k := newTestKeeper(t, 10)
+	state := NewGenesisState(&Plan{Name: "v3", Height: 30}, []AppliedUpgrade{{Name: "v2", Height: 12}})
+	require.Nil(t, state.Validate())
+	InitGenesis(ctx, k, state)
+	require.Equal(t, state, ExportGenesis(ctx, k))
+
+	require.Equal(t, DefaultGenesisState(), ExportGenesis(newTestKeeper(t, 10)))
+	require.NotNil(t, NewGenesisState(&Plan{Name: "v3"}, nil).Validate())
+	require.Nil(t, AppModuleBasic{}.ValidateGenesis(nil))
+}
+
+func TestInactiveKeeper(t *testing.T) {
+	ctx, k := newTestKeeper(t, 10)
+	require.Nil(t, k.ScheduleUpgrade(ctx, Plan{Name: "v2", Height: 20}))
+
+	active := false
+	k.SetActiveChecker(func() bool { return active })
+	_, found := k.GetUpgradePlan(ctx)
+	require.False(t, found)
+	require.NotNil(t, k.ScheduleUpgrade(ctx, Plan{Name: "v3", Height: 30}))
+	BeginBlocker(ctx.WithBlockHeight(20), k)
+	require.Equal(t, DefaultGenesisState(), ExportGenesis(ctx, k))
+	InitGenesis(ctx, k, DefaultGenesisState())
+	require.Panics(t, func() { InitGenesis(ctx, k, NewGenesisState(&Plan{Name: "v3", Height: 30}, nil)) })
+
+	active = true
+	plan, found := k.GetUpgradePlan(ctx)
+	require.True(t, found)
+	require.Equal(t, "v2", plan.Name)
+}
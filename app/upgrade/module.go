@@ -0,0 +1,115 @@
+package upgrade
+
+import (
+	"encoding/json"
+
+	"github.com/gorilla/mux"
+	"github.com/spf13/cobra"
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/client/context"
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/coinexchain/dex/app/upgrade/client/cli"
+	"github.com/coinexchain/dex/app/upgrade/client/rest"
+)
+
+// app module basics object
+type AppModuleBasic struct {
+}
+
+func (AppModuleBasic) Name() string {
+	return ModuleName
+}
+
+func (AppModuleBasic) RegisterCodec(cdc *codec.Codec) {
+	RegisterCodec(cdc)
+}
+
+// genesis
+func (AppModuleBasic) DefaultGenesis() json.RawMessage {
+	return ModuleCdc.MustMarshalJSON(DefaultGenesisState())
+}
+
+// the genesis files made before this module have no section for it
+func (AppModuleBasic) ValidateGenesis(data json.RawMessage) error {
+	if data == nil {
+		return nil
+	}
+	var state GenesisState
+	if err := ModuleCdc.UnmarshalJSON(data, &state); err != nil {
+		return err
+	}
+	return state.Validate()
+}
+
+// client functionality
+func (AppModuleBasic) RegisterRESTRoutes(ctx context.CLIContext, rtr *mux.Router) {
+	rest.RegisterRoutes(ctx, rtr)
+}
+
+func (AppModuleBasic) GetTxCmd(cdc *codec.Codec) *cobra.Command {
+	return nil
+}
+
+func (AppModuleBasic) GetQueryCmd(cdc *codec.Codec) *cobra.Command {
+	return cli.GetQueryCmd(cdc)
+}
+
+// ___________________________
+// app module object
+type AppModule struct {
+	AppModuleBasic
+	keeper Keeper
+}
+
+// NewAppModule creates a new AppModule object
+func NewAppModule(keeper Keeper) AppModule {
+	return AppModule{
+		AppModuleBasic: AppModuleBasic{},
+		keeper:         keeper,
+	}
+}
+
+// registers
+func (AppModule) RegisterInvariants(_ sdk.InvariantRegistry) {}
+
+// routes
+func (AppModule) Route() string {
+	return ""
+}
+
+func (AppModule) NewHandler() sdk.Handler {
+	return nil
+}
+
+func (AppModule) QuerierRoute() string {
+	return QuerierRoute
+}
+
+func (am AppModule) NewQuerierHandler() sdk.Querier {
+	return NewQuerier(am.keeper)
+}
+
+func (am AppModule) BeginBlock(ctx sdk.Context, _ abci.RequestBeginBlock) {
+	BeginBlocker(ctx, am.keeper)
+}
+
+func (AppModule) EndBlock(_ sdk.Context, _ abci.RequestEndBlock) []abci.ValidatorUpdate {
+	return nil
+}
+
+func (am AppModule) InitGenesis(ctx sdk.Context, data json.RawMessage) []abci.ValidatorUpdate {
+	genesisState := DefaultGenesisState()
+	if data != nil {
+		ModuleCdc.MustUnmarshalJSON(data, &genesisState)
+	}
+	InitGenesis(ctx, am.keeper, genesisState)
+	return nil
+}
+
+func (am AppModule) ExportGenesis(ctx sdk.Context) json.RawMessage {
+	gs := ExportGenesis(ctx, am.keeper)
+	return ModuleCdc.MustMarshalJSON(gs)
+}
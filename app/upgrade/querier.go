@@ -0,0 +1,46 @@
+package upgrade
+
+import (
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+func NewQuerier(k Keeper) sdk.Querier {
+	return func(ctx sdk.Context, path []string, req abci.RequestQuery) ([]byte, sdk.Error) {
+		switch path[0] {
+		case QueryPlan:
+			return queryPlan(ctx, k)
+		case QueryApplied:
+			return queryApplied(ctx, req, k)
+		default:
+			return nil, sdk.ErrUnknownRequest("query symbol : " + path[0])
+		}
+	}
+}
+
+func queryPlan(ctx sdk.Context, k Keeper) ([]byte, sdk.Error) {
+	plan, found := k.GetUpgradePlan(ctx)
+	if !found {
+		return nil, nil
+	}
+	bz, err := codec.MarshalJSONIndent(k.cdc, plan)
+	if err != nil {
+		return nil, sdk.ErrInternal(sdk.AppendMsgToErr("could not marshal result to JSON", err.Error()))
+	}
+	return bz, nil
+}
+
+func queryApplied(ctx sdk.Context, req abci.RequestQuery, k Keeper) ([]byte, sdk.Error) {
+	var params QueryAppliedParams
+	if err := k.cdc.UnmarshalJSON(req.Data, &params); err != nil {
+		return nil, sdk.ErrUnknownRequest(sdk.AppendMsgToErr("incorrectly formatted request data", err.Error()))
+	}
+	applied := AppliedUpgrade{Name: params.Name, Height: k.GetAppliedHeight(ctx, params.Name)}
+	bz, err := codec.MarshalJSONIndent(k.cdc, applied)
+	if err != nil {
+		return nil, sdk.ErrInternal(sdk.AppendMsgToErr("could not marshal result to JSON", err.Error()))
+	}
+	return bz, nil
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"fmt"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/coinexchain/dex/app/snapshot"
+	"github.com/coinexchain/dex/app/upgrade"
+)
+
+// FlagUpgradeStoreHeight is the height from which the store of the upgrade module is committed.
+// The module was added after the chain had been started, a chain started by an older binary
+// must go on with the same app hashes, so the store is not mounted before this height and the
+// module is inactive until then. When it is 0 the store is mounted only on a new chain.
+// A node replaying the old chain from genesis must have it set to the same height as the others.
+const FlagUpgradeStoreHeight = "upgrade-store-height"
+
+// upgradeHandlers are the store migrations known by this binary, keyed by the names of
+// the plans in passed software-upgrade proposals. A handler must never be removed, or
+// this binary refuses to start on a chain which has applied its upgrade.
+func (app *CetChainApp) upgradeHandlers() map[string]upgrade.Handler {
+	return map[string]upgrade.Handler{}
+}
+
+func (app *CetChainApp) registerUpgradeHandlers() {
+	for name, handler := range app.upgradeHandlers() {
+		app.upgradeKeeper.SetUpgradeHandler(name, handler)
+	}
+}
+
+// checkUpgrades refuses to run an old binary on the state which has been upgraded
+func (app *CetChainApp) checkUpgrades() error {
+	ctx := app.NewContext(true, abci.Header{Height: app.LastBlockHeight()})
+	return app.upgradeKeeper.CheckBinary(ctx)
+}
+
+// mountUpgradeStore mounts the upgrade store before version is loaded, if it is committed at
+// version or the next block is the first one to commit it
+func (app *CetChainApp) mountUpgradeStore(version int64) error {
+	if app.upgradeStoreMounted {
+		return nil
+	}
+	committed, err := app.isStoreCommitted(upgrade.StoreKey, version)
+	if err != nil {
+		return err
+	}
+	if !committed {
+		switch {
+		case version == 0 && app.upgradeStoreHeight <= 1:
+		case version > 0 && version+1 == app.upgradeStoreHeight:
+			// an empty store starting at version commits the same versions as the others
+			if err = snapshot.InitEmptyStore(app.db, upgrade.StoreKey, version); err != nil {
+				return err
+			}
+		case version > 0 && app.upgradeStoreHeight > 0 && version >= app.upgradeStoreHeight:
+			return fmt.Errorf("the upgrade store should be committed since height %d, but it is not at %d",
+				app.upgradeStoreHeight, version)
+		default:
+			return nil
+		}
+	}
+	app.cms.MountStoreWithDB(app.keyUpgrade, sdk.StoreTypeIAVL, nil)
+	app.upgradeStoreMounted = true
+	return nil
+}
+
+// loadUpgradeStore mounts the upgrade store on a running app, before the block after version
+func (app *CetChainApp) loadUpgradeStore(version int64) error {
+	if err := app.mountUpgradeStore(version); err != nil {
+		return err
+	}
+	if !app.upgradeStoreMounted {
+		return fmt.Errorf("the upgrade store can not be mounted after height %d", version)
+	}
+	app.Logger().Info(fmt.Sprintf("mounting the upgrade store at height %d", version+1))
+	return app.cms.LoadVersion(version)
+}
+
+func (app *CetChainApp) isStoreCommitted(name string, version int64) (bool, error) {
+	if version == 0 {
+		return false, nil
+	}
+	names, err := snapshot.CommittedStores(app.db, version)
+	if err != nil {
+		return false, err
+	}
+	for _, n := range names {
+		if n == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
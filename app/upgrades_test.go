@@ -0,0 +1,145 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+	dbm "github.com/tendermint/tm-db"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/coinexchain/dex/app/snapshot"
+	"github.com/coinexchain/dex/app/upgrade"
+)
+
+func TestScheduledUpgrade(t *testing.T) {
+	app := initApp(func(genState *GenesisState) {
+		genState.UpgradeData.Plan = &upgrade.Plan{Name: "v2", Height: 3}
+	})
+
+	for height := int64(1); height < 3; height++ {
+		app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: height, Time: time.Now(), ChainID: testChainID}})
+		app.EndBlock(abci.RequestEndBlock{Height: height})
+		app.Commit()
+	}
+
+	// the proposals are routed to upgrade
+	ctx := app.NewContext(true, abci.Header{Height: app.LastBlockHeight()})
+	_, err := app.govKeeper.SubmitProposal(ctx, upgrade.NewSoftwareUpgradeProposal("v2", "v2",
+		upgrade.Plan{Name: "v2", Height: 2}))
+	require.NotNil(t, err)
+	_, err = app.govKeeper.SubmitProposal(ctx, upgrade.NewCancelSoftwareUpgradeProposal("cancel", "cancel"))
+	require.Nil(t, err)
+
+	// this binary does not know the upgrade
+	header := abci.Header{Height: 3, Time: time.Now(), ChainID: testChainID}
+	require.Panics(t, func() { app.BeginBlock(abci.RequestBeginBlock{Header: header}) })
+
+	var appliedAt int64
+	app.upgradeKeeper.SetUpgradeHandler("v2", func(ctx sdk.Context, plan upgrade.Plan) {
+		appliedAt = ctx.BlockHeight()
+	})
+	app.BeginBlock(abci.RequestBeginBlock{Header: header})
+	app.EndBlock(abci.RequestEndBlock{Height: 3})
+	app.Commit()
+	require.Equal(t, int64(3), appliedAt)
+
+	ctx = app.NewContext(true, abci.Header{Height: app.LastBlockHeight()})
+	require.Equal(t, int64(3), app.upgradeKeeper.GetAppliedHeight(ctx, "v2"))
+	_, found := app.upgradeKeeper.GetUpgradePlan(ctx)
+	require.False(t, found)
+	require.Nil(t, app.checkUpgrades())
+}
+
+func newAppWithUpgradeStoreHeight(db dbm.DB, height int64) *CetChainApp {
+	viper.Set(FlagUpgradeStoreHeight, height)
+	defer viper.Set(FlagUpgradeStoreHeight, 0)
+	return NewCetChainApp(log.NewNopLogger(), db, nil, false, 10000)
+}
+
+// runBlocks runs the empty blocks from the height after the last one to toHeight
+func runBlocks(app *CetChainApp, toHeight int64) (appHashes [][]byte) {
+	blockTime := time.Unix(1577836800, 0)
+	for height := app.LastBlockHeight() + 1; height <= toHeight; height++ {
+		header := abci.Header{Height: height, Time: blockTime.Add(time.Duration(height) * time.Second), ChainID: testChainID}
+		app.BeginBlock(abci.RequestBeginBlock{Header: header})
+		app.EndBlock(abci.RequestEndBlock{Height: height})
+		appHashes = append(appHashes, app.Commit().Data)
+	}
+	return
+}
+
+func requireUpgradeStoreCommitted(t *testing.T, db dbm.DB, height int64, committed bool) {
+	names, err := snapshot.CommittedStores(db, height)
+	require.Nil(t, err)
+	found := false
+	for _, name := range names {
+		found = found || name == upgrade.StoreKey
+	}
+	require.Equal(t, committed, found)
+}
+
+func TestReplayChainWithoutUpgradeStore(t *testing.T) {
+	// the chain started by a binary without the upgrade store, as it is not mounted until height 100
+	oldDB := dbm.NewMemDB()
+	oldApp := newAppWithUpgradeStoreHeight(oldDB, 100)
+	require.Nil(t, oldApp.LoadLatestVersion(oldApp.keyMain))
+	initChain(oldApp, nil)
+	oldHashes := runBlocks(oldApp, 4)
+	requireUpgradeStoreCommitted(t, oldDB, 4, false)
+
+	// replaying it from genesis with the store added at height 3
+	db := dbm.NewMemDB()
+	app := newAppWithUpgradeStoreHeight(db, 3)
+	require.Nil(t, app.LoadLatestVersion(app.keyMain))
+	initChain(app, nil)
+	hashes := runBlocks(app, 2)
+	require.Equal(t, oldHashes[:2], hashes)
+	require.False(t, app.upgradeStoreMounted)
+	ctx := app.NewContext(true, abci.Header{Height: 2})
+	require.NotNil(t, app.upgradeKeeper.ScheduleUpgrade(ctx, upgrade.Plan{Name: "v2", Height: 10}))
+
+	hashes = append(hashes, runBlocks(app, 4)...)
+	require.True(t, app.upgradeStoreMounted)
+	require.NotEqual(t, oldHashes[2], hashes[2])
+	requireUpgradeStoreCommitted(t, db, 2, false)
+	requireUpgradeStoreCommitted(t, db, 3, true)
+
+	// the custom queries load all the stores at the latest height
+	res := app.Query(abci.RequestQuery{Path: "custom/" + upgrade.QuerierRoute + "/" + upgrade.QueryPlan})
+	require.True(t, res.IsOK(), res.Log)
+
+	// the store is mounted when the app is restarted
+	app = newAppWithUpgradeStoreHeight(db, 3)
+	require.Nil(t, app.LoadLatestVersion(app.keyMain))
+	require.True(t, app.upgradeStoreMounted)
+	require.Equal(t, hashes[3], app.LastCommitID().Hash)
+	ctx = app.NewContext(true, abci.Header{Height: 4})
+	require.Nil(t, app.upgradeKeeper.ScheduleUpgrade(ctx, upgrade.Plan{Name: "v2", Height: 10}))
+
+	// a node restarted with the new binary right before the height has the same app hashes
+	stoppedDB := dbm.NewMemDB()
+	app = newAppWithUpgradeStoreHeight(stoppedDB, 100)
+	require.Nil(t, app.LoadLatestVersion(app.keyMain))
+	initChain(app, nil)
+	runBlocks(app, 2)
+	app = newAppWithUpgradeStoreHeight(stoppedDB, 3)
+	require.Nil(t, app.LoadLatestVersion(app.keyMain))
+	require.True(t, app.upgradeStoreMounted)
+	require.Equal(t, hashes[2:], runBlocks(app, 4))
+
+	// the old chain goes on without the store if the height is not set
+	app = newAppWithUpgradeStoreHeight(oldDB, 0)
+	require.Nil(t, app.LoadLatestVersion(app.keyMain))
+	require.False(t, app.upgradeStoreMounted)
+	require.Equal(t, oldHashes[3], app.LastCommitID().Hash)
+
+	// but refuses to run with a height which has passed
+	app = newAppWithUpgradeStoreHeight(oldDB, 3)
+	require.NotNil(t, app.LoadLatestVersion(app.keyMain))
+}
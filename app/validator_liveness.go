@@ -0,0 +1,174 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/slashing"
+	sltypes "github.com/cosmos/cosmos-sdk/x/slashing/types"
+
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+// FlagLivenessWarningFractions sets the fractions of the allowed missed blocks (derived from
+// the min-signed-per-window param) at which a validator_liveness warning is reported
+const FlagLivenessWarningFractions = "liveness-warning-fractions"
+
+const DefaultLivenessWarningFractions = "0.5,0.8,0.9"
+
+type ValidatorLiveness struct {
+	Validator       string `json:"validator"`
+	Power           int64  `json:"power"`
+	Signed          bool   `json:"signed"`
+	MissedBlocks    int64  `json:"missed_blocks"`
+	MaxMissedBlocks int64  `json:"max_missed_blocks"`
+	Warning         string `json:"warning,omitempty"`
+}
+
+type NotificationValidatorLiveness struct {
+	Height     int64               `json:"height"`
+	Validators []ValidatorLiveness `json:"validators"`
+	Jailed     []string            `json:"jailed"`
+	Unjailed   []string            `json:"unjailed"`
+}
+
+// LivenessTracker gathers the votes, jailings and unjailings of a block and remembers
+// the missed blocks counters to find the warning thresholds crossed by each validator
+type LivenessTracker struct {
+	fractions     []sdk.Dec
+	missedBlocks  map[string]int64
+	votes         []abci.VoteInfo
+	jailed        []string
+	unjailedAddrs []sdk.ValAddress
+}
+
+func NewLivenessTracker(fractions string) (*LivenessTracker, error) {
+	parsed, err := parseLivenessWarningFractions(fractions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --%s: %s", FlagLivenessWarningFractions, err.Error())
+	}
+	return &LivenessTracker{fractions: parsed, missedBlocks: make(map[string]int64)}, nil
+}
+
+func parseLivenessWarningFractions(s string) ([]sdk.Dec, error) {
+	if s == "" {
+		s = DefaultLivenessWarningFractions
+	}
+	fields := strings.Split(s, ",")
+	fractions := make([]sdk.Dec, 0, len(fields))
+	for _, f := range fields {
+		d, err := sdk.NewDecFromStr(strings.TrimSpace(f))
+		if err != nil {
+			return nil, err
+		}
+		if !d.IsPositive() || d.GT(sdk.OneDec()) {
+			return nil, fmt.Errorf("fraction %s is not in (0,1]", d)
+		}
+		fractions = append(fractions, d)
+	}
+	return fractions, nil
+}
+
+func (lt *LivenessTracker) BeginBlock(req abci.RequestBeginBlock, events []abci.Event) {
+	lt.votes = req.LastCommitInfo.Votes
+	lt.jailed = lt.jailed[:0]
+	lt.unjailedAddrs = lt.unjailedAddrs[:0]
+	for _, event := range events {
+		if event.Type != sltypes.EventTypeSlash {
+			continue
+		}
+		for _, attr := range event.Attributes {
+			if string(attr.Key) == sltypes.AttributeKeyJailed {
+				lt.jailed = append(lt.jailed, string(attr.Value))
+			}
+		}
+	}
+}
+
+func (lt *LivenessTracker) AddUnjailed(msgs []sdk.Msg) {
+	for _, msg := range msgs {
+		if m, ok := msg.(slashing.MsgUnjail); ok {
+			lt.unjailedAddrs = append(lt.unjailedAddrs, m.ValidatorAddr)
+		}
+	}
+}
+
+// CrossedFraction returns the highest warning fraction in (prev, curr] of maxMissed,
+// or an empty string if none is crossed
+func (lt *LivenessTracker) CrossedFraction(prev, curr, maxMissed int64) string {
+	crossed := ""
+	for _, f := range lt.fractions {
+		threshold := f.MulInt64(maxMissed).Ceil().TruncateInt64()
+		if prev < threshold && threshold <= curr {
+			crossed = f.String()
+		}
+	}
+	return crossed
+}
+
+func (app *CetChainApp) isLivenessNotificationEnabled() bool {
+	return app.livenessTracker != nil && app.msgQueProducer.IsSubscribed(slashing.ModuleName)
+}
+
+func (app *CetChainApp) notifyValidatorLiveness(ctx sdk.Context) {
+	lt := app.livenessTracker
+	window := app.slashingKeeper.SignedBlocksWindow(ctx)
+	maxMissed := window - app.slashingKeeper.MinSignedPerWindow(ctx)
+	msg := NotificationValidatorLiveness{
+		Height:     ctx.BlockHeight(),
+		Validators: make([]ValidatorLiveness, 0, len(lt.votes)),
+		Jailed:     append(make([]string, 0, len(lt.jailed)), lt.jailed...),
+		Unjailed:   make([]string, 0, len(lt.unjailedAddrs)),
+	}
+	// only the validators of the last commit are kept, the others have left the set
+	missedBlocks := make(map[string]int64, len(lt.votes))
+	for _, vote := range lt.votes {
+		consAddr := sdk.ConsAddress(vote.Validator.Address)
+		info, ok := app.getSigningInfo(ctx, consAddr)
+		if !ok {
+			continue
+		}
+
+		key := consAddr.String()
+		prev, ok := lt.missedBlocks[key]
+		if !ok {
+			prev = info.MissedBlocksCounter
+			if !vote.SignedLastBlock {
+				prev--
+			}
+		}
+		missedBlocks[key] = info.MissedBlocksCounter
+
+		msg.Validators = append(msg.Validators, ValidatorLiveness{
+			Validator:       key,
+			Power:           vote.Validator.Power,
+			Signed:          vote.SignedLastBlock,
+			MissedBlocks:    info.MissedBlocksCounter,
+			MaxMissedBlocks: maxMissed,
+			Warning:         lt.CrossedFraction(prev, info.MissedBlocksCounter, maxMissed),
+		})
+	}
+	lt.missedBlocks = missedBlocks
+	for _, valAddr := range lt.unjailedAddrs {
+		if val, found := app.stakingKeeper.GetValidator(ctx, valAddr); found && !val.IsJailed() {
+			msg.Unjailed = append(msg.Unjailed, val.GetConsAddr().String())
+		}
+	}
+	app.appendPubMsgKV("validator_liveness", dex.SafeJSONMarshal(msg))
+}
+
+// getSigningInfo reads the signing info of a validator as the slashing keeper does, which
+// has no exported getter of a single signing info
+func (app *CetChainApp) getSigningInfo(ctx sdk.Context, consAddr sdk.ConsAddress) (
+	info slashing.ValidatorSigningInfo, found bool) {
+
+	bz := ctx.KVStore(app.keySlashing).Get(sltypes.GetValidatorSigningInfoKey(consAddr))
+	if bz == nil {
+		return info, false
+	}
+	app.cdc.MustUnmarshalBinaryLengthPrefixed(bz, &info)
+	return info, true
+}
@@ -0,0 +1,122 @@
+package app
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/common"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/slashing"
+	sltypes "github.com/cosmos/cosmos-sdk/x/slashing/types"
+
+	"github.com/coinexchain/cet-sdk/msgqueue"
+)
+
+func TestLivenessWarningFractions(t *testing.T) {
+	lt, err := NewLivenessTracker("")
+	require.Nil(t, err)
+	require.Equal(t, 3, len(lt.fractions))
+
+	lt, err = NewLivenessTracker("0.25, 1")
+	require.Nil(t, err)
+	require.Equal(t, []sdk.Dec{sdk.NewDecWithPrec(25, 2), sdk.OneDec()}, lt.fractions)
+
+	for _, invalid := range []string{"abc", "0.5,", "0", "-0.5", "1.1"} {
+		_, err = NewLivenessTracker(invalid)
+		require.NotNil(t, err, invalid)
+	}
+}
+
+func TestCrossedFraction(t *testing.T) {
+	lt, _ := NewLivenessTracker("0.5,0.8,0.9")
+	require.Equal(t, "", lt.CrossedFraction(0, 1, 9500))
+	require.Equal(t, "", lt.CrossedFraction(4750, 4751, 9500))
+	require.Equal(t, "0.500000000000000000", lt.CrossedFraction(4749, 4750, 9500))
+	require.Equal(t, "0.800000000000000000", lt.CrossedFraction(7599, 7600, 9500))
+	require.Equal(t, "0.900000000000000000", lt.CrossedFraction(4000, 9000, 9500))
+	require.Equal(t, "", lt.CrossedFraction(4750, 4749, 9500))
+}
+
+func TestLivenessTrackerBeginBlock(t *testing.T) {
+	lt, _ := NewLivenessTracker("")
+	votes := []abci.VoteInfo{{Validator: abci.Validator{Address: []byte("val"), Power: 10}, SignedLastBlock: false}}
+	events := []abci.Event{
+		{
+			Type: sltypes.EventTypeSlash,
+			Attributes: []common.KVPair{
+				{Key: []byte(sltypes.AttributeKeyAddress), Value: []byte("coinexvalcons1")},
+				{Key: []byte(sltypes.AttributeKeyJailed), Value: []byte("coinexvalcons1")},
+			},
+		},
+		{Type: "other"},
+	}
+	lt.BeginBlock(abci.RequestBeginBlock{LastCommitInfo: abci.LastCommitInfo{Votes: votes}}, events)
+	require.Equal(t, votes, lt.votes)
+	require.Equal(t, []string{"coinexvalcons1"}, lt.jailed)
+
+	valAddr := sdk.ValAddress([]byte("val"))
+	lt.AddUnjailed([]sdk.Msg{slashing.NewMsgUnjail(valAddr)})
+	require.Equal(t, []sdk.ValAddress{valAddr}, lt.unjailedAddrs)
+
+	lt.BeginBlock(abci.RequestBeginBlock{}, nil)
+	require.Equal(t, 0, len(lt.jailed))
+	require.Equal(t, 0, len(lt.unjailedAddrs))
+}
+
+func TestValidatorLivenessNotification(t *testing.T) {
+	app := initApp(nil)
+	app.msgQueProducer = msgqueue.NewProducerFromConfig([]string{"nop"}, "slashing", true, nil)
+
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: 1, Time: time.Now(), ChainID: testChainID}})
+	app.EndBlock(abci.RequestEndBlock{Height: 1})
+
+	var values [][]byte
+	for _, msg := range app.pubMsgs {
+		if string(msg.Key) == "validator_liveness" {
+			values = append(values, msg.Value)
+		}
+	}
+	require.Equal(t, 1, len(values))
+	var fields map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(values[0], &fields))
+	require.Equal(t, "[]", string(fields["validators"]))
+	require.Equal(t, "[]", string(fields["jailed"]))
+	require.Equal(t, "[]", string(fields["unjailed"]))
+}
+
+func TestValidatorLivenessOfLastCommit(t *testing.T) {
+	app := initApp(nil)
+	app.msgQueProducer = msgqueue.NewProducerFromConfig([]string{"nop"}, "slashing", true, nil)
+	header := abci.Header{Height: 1, Time: time.Now(), ChainID: testChainID}
+	app.BeginBlock(abci.RequestBeginBlock{Header: header})
+	ctx := app.NewContext(false, header)
+
+	consAddr1 := sdk.ConsAddress([]byte("validator-address-01"))
+	consAddr2 := sdk.ConsAddress([]byte("validator-address-02"))
+	app.slashingKeeper.SetValidatorSigningInfo(ctx, consAddr1,
+		slashing.NewValidatorSigningInfo(consAddr1, 0, 0, time.Unix(0, 0), false, 3))
+	app.slashingKeeper.SetValidatorSigningInfo(ctx, consAddr2,
+		slashing.NewValidatorSigningInfo(consAddr2, 0, 0, time.Unix(0, 0), false, 0))
+	votes := []abci.VoteInfo{
+		{Validator: abci.Validator{Address: consAddr1, Power: 10}, SignedLastBlock: false},
+		{Validator: abci.Validator{Address: consAddr2, Power: 20}, SignedLastBlock: true},
+	}
+
+	app.livenessTracker.votes = votes
+	app.notifyValidatorLiveness(ctx)
+	require.Equal(t, map[string]int64{consAddr1.String(): 3, consAddr2.String(): 0}, app.livenessTracker.missedBlocks)
+	var msg NotificationValidatorLiveness
+	require.NoError(t, json.Unmarshal(app.pubMsgs[len(app.pubMsgs)-1].Value, &msg))
+	require.Equal(t, 2, len(msg.Validators))
+	require.Equal(t, int64(3), msg.Validators[0].MissedBlocks)
+
+	// the second validator has left the set
+	app.livenessTracker.votes = votes[:1]
+	app.notifyValidatorLiveness(ctx)
+	require.Equal(t, map[string]int64{consAddr1.String(): 3}, app.livenessTracker.missedBlocks)
+}
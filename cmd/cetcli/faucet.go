@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/tendermint/tendermint/libs/log"
+	rpcserver "github.com/tendermint/tendermint/rpc/lib/server"
+
+	"github.com/cosmos/cosmos-sdk/client/context"
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/cosmos/cosmos-sdk/client/keys"
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	"github.com/cosmos/cosmos-sdk/x/auth/client/utils"
+
+	"github.com/coinexchain/dex/app/faucet"
+)
+
+const (
+	flagFaucetAmount   = "amount"
+	flagAddressMax     = "address-max"
+	flagAddressWindow  = "address-window"
+	flagIPMax          = "ip-max"
+	flagIPWindow       = "ip-window"
+	flagTrustedProxies = "trusted-proxies"
+	flagGas            = "gas"
+)
+
+func faucetCmd(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "faucet",
+		Short: "Start a faucet which sends the coins of the --from key to whoever claims them",
+		Long: `Start an HTTP server which sends --amount of a denom from the --from key for every claim,
+limited per address and per IP. Each denom in --amount can be claimed, such as a token issued
+by the key besides cet. Only for testnets, the passphrase of the key is kept in memory.
+
+	POST /claim   {"address": "coinex1...", "denom": "cet"}  => {"tx_hash": "...", "amount": [...]}
+	GET  /status  => {"address": "coinex1...", "balance": [...], "amounts": [...], "claims": 0}
+
+A rate limited claim gets 429 with the seconds to wait in "retry_after".
+
+Example:
+	cetcli faucet --from=faucet --chain-id=coinexdex-test --node=tcp://localhost:26657 \
+		--amount=10000000000cet,100000000000abc --address-max=1 --address-window=24h`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			amounts, err := sdk.ParseCoins(viper.GetString(flagFaucetAmount))
+			if err != nil {
+				return err
+			}
+			if amounts.Empty() {
+				return fmt.Errorf("--%s is empty", flagFaucetAmount)
+			}
+			name := viper.GetString(flags.FlagFrom)
+			passphrase, err := keys.GetPassphrase(name)
+			if err != nil {
+				return err
+			}
+			kb, err := keys.NewKeyBaseFromHomeFlag()
+			if err != nil {
+				return err
+			}
+			fees, err := sdk.ParseCoins(viper.GetString(flags.FlagFees))
+			if err != nil {
+				return err
+			}
+			gasPrices, err := sdk.ParseDecCoins(viper.GetString(flags.FlagGasPrices))
+			if err != nil {
+				return err
+			}
+			txBldr := auth.NewTxBuilder(utils.GetTxEncoder(cdc), 0, 0, viper.GetUint64(flagGas), 0,
+				false, viper.GetString(flags.FlagChainID), viper.GetString(flags.FlagMemo), fees, gasPrices).
+				WithKeybase(kb)
+			sender, err := faucet.NewTxSender(context.NewCLIContext().WithCodec(cdc), txBldr, name, passphrase)
+			if err != nil {
+				return err
+			}
+			f := faucet.New(faucet.Config{
+				Amounts:        amounts,
+				AddressLimit:   faucet.Limit{Max: viper.GetInt(flagAddressMax), Window: viper.GetDuration(flagAddressWindow)},
+				IPLimit:        faucet.Limit{Max: viper.GetInt(flagIPMax), Window: viper.GetDuration(flagIPWindow)},
+				TrustedProxies: viper.GetInt(flagTrustedProxies),
+			}, sender)
+
+			logger := log.NewTMLogger(log.NewSyncWriter(os.Stdout)).With("module", "faucet")
+			cfg := rpcserver.DefaultConfig()
+			listener, err := rpcserver.Listen(viper.GetString(flags.FlagListenAddr), cfg)
+			if err != nil {
+				return err
+			}
+			logger.Info("Starting faucet", "address", sender.Address(), "amounts", amounts.String(),
+				"laddr", listener.Addr().String())
+			mux := http.NewServeMux()
+			mux.Handle("/", f.Handler())
+			return rpcserver.StartHTTPServer(listener, mux, logger, cfg)
+		},
+	}
+
+	cmd.Flags().String(flags.FlagFrom, "", "Name of the key which sends the coins")
+	cmd.Flags().String(flags.FlagNode, "tcp://localhost:26657", "<host>:<port> to tendermint rpc interface for this chain")
+	cmd.Flags().Bool(flags.FlagTrustNode, true, "Trust connected full node (don't verify proofs for responses)")
+	cmd.Flags().StringP(flags.FlagBroadcastMode, "b", flags.BroadcastSync, "Transaction broadcasting mode (sync|async|block)")
+	cmd.Flags().Uint64(flagGas, flags.DefaultGasLimit, "The gas limit of every tx")
+	cmd.Flags().String(flags.FlagFees, "", "Fees to pay along with every tx; eg: 100cet")
+	cmd.Flags().String(flags.FlagGasPrices, "", "Gas prices to determine the fee of every tx")
+	cmd.Flags().String(flags.FlagMemo, "", "Memo to send along with every tx")
+	cmd.Flags().String(flags.FlagListenAddr, "tcp://localhost:8000", "The address for the faucet to listen on")
+	cmd.Flags().String(flagFaucetAmount, "10000000000cet", "The coins of every denom sent for a claim, one denom per claim")
+	cmd.Flags().Int(flagAddressMax, 1, "The max claims of an address in --address-window, 0 for no limit")
+	cmd.Flags().Duration(flagAddressWindow, 24*time.Hour, "The window of --address-max")
+	cmd.Flags().Int(flagIPMax, 10, "The max claims from an IP in --ip-window, 0 for no limit")
+	cmd.Flags().Duration(flagIPWindow, 24*time.Hour, "The window of --ip-max")
+	cmd.Flags().Int(flagTrustedProxies, 0, "The number of proxies in front of the faucet, the IP of a claim is the one appended to X-Forwarded-For by the outermost of them")
+	_ = cmd.MarkFlagRequired(flags.FlagFrom)
+	return cmd
+}
@@ -28,6 +28,9 @@ import (
 	distrxcmd "github.com/coinexchain/cet-sdk/modules/distributionx/client/cli"
 	dex "github.com/coinexchain/cet-sdk/types"
 	"github.com/coinexchain/dex/app"
+	overviewcmd "github.com/coinexchain/dex/app/overview/client/cli"
+	overviewrest "github.com/coinexchain/dex/app/overview/client/rest"
+	txindexcmd "github.com/coinexchain/dex/app/txindex/client/cli"
 	_ "github.com/coinexchain/dex/cmd/cetcli/statik"
 )
 
@@ -73,6 +76,7 @@ func createRootCmd(cdc *codec.Codec) *cobra.Command {
 		txCmd(cdc),
 		client.LineBreak,
 		lcd.ServeCommand(cdc, registerRoutes),
+		faucetCmd(cdc),
 		client.LineBreak,
 		keys.Commands(),
 		client.LineBreak,
@@ -132,11 +136,13 @@ func queryCmd(cdc *codec.Codec) *cobra.Command {
 
 	queryCmd.AddCommand(
 		authxcmd.GetAccountXCmd(cdc),
+		overviewcmd.GetAccountOverviewCmd(cdc),
 		client.LineBreak,
 		rpc.ValidatorCommand(cdc),
 		rpc.BlockCommand(),
 		authcmd.QueryTxsByEventsCmd(cdc),
 		authcmd.QueryTxCmd(cdc),
+		txindexcmd.GetAccountTxsCmd(cdc),
 		client.LineBreak,
 	)
 
@@ -187,6 +193,7 @@ func registerRoutes(rs *lcd.RestServer) {
 	registerSwaggerUI(rs)
 	client.RegisterRoutes(rs.CliCtx, rs.Mux)
 	authrest.RegisterTxRoutes(rs.CliCtx, rs.Mux)
+	overviewrest.RegisterRoutes(rs.CliCtx, rs.Mux)
 	app.ModuleBasics.RegisterRESTRoutes(rs.CliCtx, rs.Mux)
 }
 
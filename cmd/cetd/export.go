@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/tendermint/tendermint/libs/cli"
+	"github.com/tendermint/tendermint/libs/log"
+	tmtypes "github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/server"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/coinexchain/dex/app"
+)
+
+// the same as the flags of server.ExportCmd
+const (
+	flagHeight        = "height"
+	flagForZeroHeight = "for-zero-height"
+	flagJailWhitelist = "jail-whitelist"
+	flagModules       = "modules"
+)
+
+const (
+	flagJailWhitelistFile = "jail-whitelist-file"
+	flagZeroHeightReport  = "zero-height-report"
+)
+
+// addModuleExport lets the export command write the genesis file with --output, in which
+// case the state is exported one module at a time instead of all in memory, and export only
+// some modules with --modules. An export for zero height can also read the jail whitelist
+// from a file, and write a report of what it changes.
+func addModuleExport(ctx *server.Context, cdc *codec.Codec, rootCmd *cobra.Command) {
+	for _, cmd := range rootCmd.Commands() {
+		if cmd.Name() != "export" {
+			continue
+		}
+		exportToStdout := cmd.RunE
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			outputFile := viper.GetString(flagOutput)
+			modules := viper.GetStringSlice(flagModules)
+			whitelistFile := viper.GetString(flagJailWhitelistFile)
+			reportFile := viper.GetString(flagZeroHeightReport)
+			if outputFile == "" && len(modules) == 0 && whitelistFile == "" && reportFile == "" {
+				return exportToStdout(cmd, args)
+			}
+			if (whitelistFile != "" || reportFile != "") && !viper.GetBool(flagForZeroHeight) {
+				return fmt.Errorf("--%s and --%s are only for --%s",
+					flagJailWhitelistFile, flagZeroHeightReport, flagForZeroHeight)
+			}
+			return exportByModule(ctx, cdc, outputFile, modules)
+		}
+		cmd.Flags().String(flagOutput, "", "Write the exported genesis file to this path, one module at a time to save memory")
+		cmd.Flags().StringSlice(flagModules, nil, "Export only these modules, e.g. market,asset,bancorlite")
+		cmd.Flags().String(flagJailWhitelistFile, "",
+			"A file of validator addresses not to be jailed, one on each line, added to --jail-whitelist")
+		cmd.Flags().String(flagZeroHeightReport, "",
+			"Write a JSON report of the rewards withdrawn, the validators jailed and the heights reset to this file")
+	}
+}
+
+// exportByModule writes the genesis file to outputFile, or stdout if it is empty
+func exportByModule(ctx *server.Context, cdc *codec.Codec, outputFile string, modules []string) error {
+	config := ctx.Config
+	config.SetRoot(viper.GetString(cli.HomeFlag))
+
+	db, err := dbm.NewGoLevelDB("application", filepath.Join(config.RootDir, "data"))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	out := os.Stdout
+	if outputFile != "" {
+		if out, err = os.Create(outputFile); err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+
+	// the same as server.ExportCmd
+	if isEmptyState(db) {
+		fmt.Fprintln(os.Stderr, "WARNING: State is not initialized. Returning genesis file.")
+		genesis, err := ioutil.ReadFile(config.GenesisFile())
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(genesis)
+		return err
+	}
+
+	gApp, err := loadAppForExport(ctx.Logger, db, nil, viper.GetInt64(flagHeight))
+	if err != nil {
+		return err
+	}
+	defer gApp.Close()
+	genDoc, err := tmtypes.GenesisDocFromFile(config.GenesisFile())
+	if err != nil {
+		return err
+	}
+	forZeroHeight := viper.GetBool(flagForZeroHeight)
+	jailWhiteList := viper.GetStringSlice(flagJailWhitelist)
+	if file := viper.GetString(flagJailWhitelistFile); file != "" {
+		addrs, err := readJailWhitelist(file)
+		if err != nil {
+			return err
+		}
+		jailWhiteList = append(jailWhiteList, addrs...)
+	}
+	var report *app.ZeroHeightReport
+	err = app.WriteGenesisDoc(cdc, out, genDoc, func(w io.Writer) (validators []tmtypes.GenesisValidator, err error) {
+		validators, report, err = gApp.WriteAppState(w, forZeroHeight, jailWhiteList, modules...)
+		return
+	})
+	if err != nil {
+		return err
+	}
+	if file := viper.GetString(flagZeroHeightReport); file != "" {
+		bz, err := codec.MarshalJSONIndent(cdc, report)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(file, bz, 0644)
+	}
+	return nil
+}
+
+// isEmptyState is the same as the one in the server package
+func isEmptyState(db dbm.DB) bool {
+	return db.Stats()["leveldb.sstables"] == ""
+}
+
+// readJailWhitelist reads the validator addresses in file, one on each line,
+// the empty lines and the ones starting with '#' are skipped
+func readJailWhitelist(file string) ([]string, error) {
+	bz, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var addrs []string
+	for i, line := range strings.Split(string(bz), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, err = sdk.ValAddressFromBech32(line); err != nil {
+			return nil, fmt.Errorf("line %d of %s: %s", i+1, file, err.Error())
+		}
+		addrs = append(addrs, line)
+	}
+	return addrs, nil
+}
+
+func loadAppForExport(logger log.Logger, db dbm.DB, traceStore io.Writer, height int64) (*app.CetChainApp, error) {
+	if height != -1 {
+		gApp := app.NewCetChainApp(logger, db, traceStore, false, uint(1))
+		if err := gApp.LoadHeight(height); err != nil {
+			return nil, err
+		}
+		return gApp, nil
+	}
+	return app.NewCetChainApp(logger, db, traceStore, true, uint(1)), nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/libs/cli"
+
+	"github.com/cosmos/cosmos-sdk/server"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/coinexchain/dex/app"
+)
+
+func TestReadJailWhitelist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "whitelist")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	val1 := sdk.ValAddress([]byte("val1________________")).String()
+	val2 := sdk.ValAddress([]byte("val2________________")).String()
+	file := filepath.Join(dir, "whitelist.txt")
+	require.Nil(t, ioutil.WriteFile(file, []byte("# validators to keep\n"+val1+"\n\n  "+val2+"  \n"), 0644))
+	addrs, err := readJailWhitelist(file)
+	require.Nil(t, err)
+	require.Equal(t, []string{val1, val2}, addrs)
+
+	require.Nil(t, ioutil.WriteFile(file, []byte(val1+"\nnot-an-address\n"), 0644))
+	_, err = readJailWhitelist(file)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "line 2 of")
+}
+
+func TestExportByModuleOfEmptyState(t *testing.T) {
+	home, err := ioutil.TempDir("", "export")
+	require.Nil(t, err)
+	defer os.RemoveAll(home)
+	require.Nil(t, os.MkdirAll(filepath.Join(home, "config"), 0755))
+	genesis := []byte(`{"chain_id":"c1"}`)
+	require.Nil(t, ioutil.WriteFile(filepath.Join(home, "config", "genesis.json"), genesis, 0644))
+
+	oldHome := viper.GetString(cli.HomeFlag)
+	viper.Set(cli.HomeFlag, home)
+	defer viper.Set(cli.HomeFlag, oldHome)
+	output := filepath.Join(home, "exported.json")
+	require.Nil(t, exportByModule(server.NewDefaultContext(), app.MakeCodec(), output, nil))
+	exported, err := ioutil.ReadFile(output)
+	require.Nil(t, err)
+	require.Equal(t, genesis, exported)
+}
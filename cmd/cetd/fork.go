@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/tendermint/tendermint/libs/cli"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/privval"
+	tmtypes "github.com/tendermint/tendermint/types"
+	tmtime "github.com/tendermint/tendermint/types/time"
+
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/server"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/coinexchain/dex/app"
+)
+
+const (
+	flagOperator      = "operator"
+	flagStake         = "stake"
+	flagOperatorCoins = "operator-coins"
+	flagMoniker       = "moniker"
+	flagForkReport    = "fork-report"
+)
+
+func forkCmd(ctx *server.Context, cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fork [exported-genesis-file]",
+		Short: "Write the genesis.json of a local single-validator chain forked from an exported state",
+		Long: `Load a genesis file written by 'cetd export', replace its validators by a single validator
+with the key in priv_validator_key.json of the node, which is created if it does not exist,
+and write the result to the genesis.json of the node. The validators of the exported state are
+jailed and begin unbonding, the stake of the new validator and the --operator-coins are minted
+to --operator, whose key signs the txs of the validator. Export with --for-zero-height, so the
+heights in the state start from the genesis of the fork.
+
+The home must not have any blocks, start the node with 'cetd start' afterwards to rehearse
+upgrades, param changes or large market operations against the exported data offline.
+
+Example:
+	cetd export --for-zero-height --output=mainnet.json
+	cetd fork mainnet.json --home=fork --chain-id=coinexdex-fork --operator=coinex1...`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			config := ctx.Config
+			config.SetRoot(viper.GetString(cli.HomeFlag))
+			if _, err := os.Stat(filepath.Join(config.DBDir(), "blockstore.db")); err == nil {
+				return fmt.Errorf("%s already has blocks, fork to a new home", config.RootDir)
+			}
+			operator, err := sdk.AccAddressFromBech32(viper.GetString(flagOperator))
+			if err != nil {
+				return fmt.Errorf("--%s: %s", flagOperator, err.Error())
+			}
+			genDoc, err := tmtypes.GenesisDocFromFile(args[0])
+			if err != nil {
+				return err
+			}
+			chainID := viper.GetString(flags.FlagChainID)
+			if chainID == "" {
+				chainID = genDoc.ChainID + "-fork"
+			}
+
+			if err = cmn.EnsureDir(filepath.Dir(config.PrivValidatorKeyFile()), 0700); err != nil {
+				return err
+			}
+			if err = cmn.EnsureDir(filepath.Dir(config.PrivValidatorStateFile()), 0700); err != nil {
+				return err
+			}
+			pv := privval.LoadOrGenFilePV(config.PrivValidatorKeyFile(), config.PrivValidatorStateFile())
+
+			params := app.ForkParams{
+				ChainID:     chainID,
+				GenesisTime: tmtime.Now(),
+				ConsPubKey:  pv.GetPubKey(),
+				Moniker:     viper.GetString(flagMoniker),
+				Operator:    operator,
+				Stake:       viper.GetInt64(flagStake),
+				Coins:       viper.GetInt64(flagOperatorCoins),
+			}
+			appState, validators, report, err := app.ForkAppState(genDoc.AppState, params)
+			if err != nil {
+				return err
+			}
+			genDoc.ChainID = chainID
+			genDoc.GenesisTime = params.GenesisTime
+			genDoc.AppState = appState
+			genDoc.Validators = validators
+			if err = genDoc.SaveAs(config.GenesisFile()); err != nil {
+				return err
+			}
+			if file := viper.GetString(flagForkReport); file != "" {
+				bz, err := codec.MarshalJSONIndent(cdc, report)
+				if err != nil {
+					return err
+				}
+				if err = ioutil.WriteFile(file, bz, 0644); err != nil {
+					return err
+				}
+			}
+
+			fmt.Printf("Forked %s to %s in %s\n", args[0], chainID, config.GenesisFile())
+			fmt.Printf("Validator %s with power %d, %s minted to %s, %d validators jailed\n",
+				report.Validator, report.Power, report.Minted, operator, len(report.Jailed))
+			return nil
+		},
+	}
+
+	cmd.Flags().String(cli.HomeFlag, app.DefaultNodeHome, "node's home directory")
+	cmd.Flags().String(flags.FlagChainID, "", "The chain ID of the fork, the exported one with a '-fork' suffix if empty")
+	cmd.Flags().String(flagOperator, "", "The address which operates the validator of the fork")
+	cmd.Flags().Int64(flagStake, 0, "The self delegation of the validator in sato.CET, the min self delegation if zero")
+	cmd.Flags().Int64(flagOperatorCoins, 1e12, "The sato.CET minted to the operator besides the stake, to pay the fees")
+	cmd.Flags().String(flagMoniker, "fork", "The moniker of the validator")
+	cmd.Flags().String(flagForkReport, "", "Write a JSON report of the validators jailed and the coins minted to this file")
+	return cmd
+}
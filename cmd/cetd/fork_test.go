@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/libs/cli"
+	tmtypes "github.com/tendermint/tendermint/types"
+
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+	"github.com/coinexchain/dex/app"
+	"github.com/coinexchain/dex/app/testkit"
+)
+
+func TestFork(t *testing.T) {
+	testHome := "./testhome"
+	testDataDir := "./forkdata"
+	defer os.RemoveAll(testHome)
+	defer os.RemoveAll(testDataDir)
+	require.Nil(t, os.MkdirAll(testDataDir, 0755))
+
+	tk := testkit.NewGenesisBuilder().
+		Validator("val", 1e13).
+		Account("alice", dex.NewCetCoins(1e10)).
+		Build(t)
+	defer tk.Close()
+	tk.NextBlocks(2)
+	var exported bytes.Buffer
+	validators, _, err := tk.WriteAppState(&exported, true, nil)
+	require.Nil(t, err)
+	exportFile := filepath.Join(testDataDir, "exported.json")
+	genDoc := &tmtypes.GenesisDoc{ChainID: tk.ChainID, GenesisTime: tk.Time(), AppState: exported.Bytes(),
+		Validators: validators}
+	require.Nil(t, genDoc.SaveAs(exportFile))
+
+	_, _, operator := testutil.KeyPubAddr()
+	reportFile := filepath.Join(testDataDir, "report.json")
+	os.Args = []string{"cetd", "fork", exportFile, "--home", testHome, "--operator", operator.String(),
+		"--stake", "20000000000", "--fork-report", reportFile}
+	executor := cli.PrepareBaseCmd(createCetdCmd(), "GA", testHome)
+	require.NoError(t, executor.Execute())
+
+	genFile := filepath.Join(testHome, "config", "genesis.json")
+	require.Nil(t, validateGenesisDeep(app.MakeCodec(), genFile))
+	forkDoc, err := tmtypes.GenesisDocFromFile(genFile)
+	require.Nil(t, err)
+	require.Equal(t, tk.ChainID+"-fork", forkDoc.ChainID)
+	require.Equal(t, 1, len(forkDoc.Validators))
+	require.NotEqual(t, validators[0].PubKey, forkDoc.Validators[0].PubKey)
+
+	bz, err := ioutil.ReadFile(reportFile)
+	require.Nil(t, err)
+	var report struct {
+		Jailed []string `json:"jailed"`
+	}
+	require.Nil(t, json.Unmarshal(bz, &report))
+	require.Equal(t, []string{tk.Account("val").ValAddress().String()}, report.Jailed)
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/tendermint/tendermint/libs/cli"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/server"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/genaccounts"
+	"github.com/cosmos/cosmos-sdk/x/genutil"
+
+	"github.com/coinexchain/cet-sdk/modules/asset"
+	"github.com/coinexchain/cet-sdk/modules/authx"
+	"github.com/coinexchain/dex/app"
+)
+
+const (
+	flagTargetSupply  = "target-supply"
+	flagRemainderAddr = "remainder-addr"
+)
+
+// the columns of the CSV file, which must have a header line
+var accountRecordColumns = []string{"address", "coins", "vesting_coins", "vesting_start", "vesting_end", "memo_required"}
+
+// accountRecord is an account to import. The vesting is continuous if VestingStart is not
+// zero, and delayed otherwise.
+type accountRecord struct {
+	Address      string `json:"address"`
+	Coins        string `json:"coins"`
+	VestingCoins string `json:"vesting_coins"`
+	VestingStart int64  `json:"vesting_start"`
+	VestingEnd   int64  `json:"vesting_end"`
+	MemoRequired bool   `json:"memo_required"`
+}
+
+func importGenesisAccountsCmd(ctx *server.Context, cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import-genesis-accounts [file]",
+		Short: "Import genesis accounts from a CSV or JSON file to genesis.json",
+		Long: fmt.Sprintf(`Import genesis accounts from a CSV or JSON file to genesis.json.
+The CSV file must have a header line with the columns: %s.
+The JSON file is an array of objects with the same fields.
+The vesting is continuous if vesting_start is not zero, and delayed otherwise.
+
+Example:
+	cetd import-genesis-accounts accounts.csv --target-supply=587767527061317189cet`, strings.Join(accountRecordColumns, ",")),
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			config := ctx.Config
+			config.SetRoot(viper.GetString(cli.HomeFlag))
+
+			records, err := readAccountRecords(args[0])
+			if err != nil {
+				return err
+			}
+
+			genFile := config.GenesisFile()
+			appState, genDoc, err := genutil.GenesisStateFromGenFile(cdc, genFile)
+			if err != nil {
+				return err
+			}
+			if err = importGenesisAccounts(cdc, appState, records); err != nil {
+				return err
+			}
+
+			appStateJSON, err := cdc.MarshalJSON(appState)
+			if err != nil {
+				return err
+			}
+			genDoc.AppState = appStateJSON
+			if err = genutil.ExportGenesisFile(genDoc, genFile); err != nil {
+				return err
+			}
+			fmt.Printf("Imported %d accounts\n", len(records))
+			return nil
+		},
+	}
+
+	cmd.Flags().String(cli.HomeFlag, app.DefaultNodeHome, "node's home directory")
+	cmd.Flags().String(flagTargetSupply, "", "The expected total coins of all the genesis accounts after importing")
+	cmd.Flags().String(flagRemainderAddr, "", "The address to receive the coins left to reach --target-supply")
+	return cmd
+}
+
+func readAccountRecords(file string) ([]accountRecord, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".csv":
+		return readAccountRecordsCSV(f)
+	case ".json":
+		var records []accountRecord
+		if err := json.NewDecoder(f).Decode(&records); err != nil {
+			return nil, err
+		}
+		return records, nil
+	default:
+		return nil, fmt.Errorf("unsupported file type: %s", file)
+	}
+}
+
+func readAccountRecordsCSV(r io.Reader) ([]accountRecord, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	if _, ok := columns["address"]; !ok {
+		return nil, fmt.Errorf("missing column: address")
+	}
+	for name := range columns {
+		if !containsString(accountRecordColumns, name) {
+			return nil, fmt.Errorf("unknown column: %s", name)
+		}
+	}
+
+	// a quoted field may span lines, so the errors report the number of the record after the header
+	var records []accountRecord
+	for n := 1; ; n++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+
+		record := accountRecord{
+			Address:      field("address"),
+			Coins:        field("coins"),
+			VestingCoins: field("vesting_coins"),
+		}
+		if record.VestingStart, err = parseOptionalInt(field("vesting_start")); err != nil {
+			return nil, fmt.Errorf("record %d: invalid vesting_start: %s", n, err.Error())
+		}
+		if record.VestingEnd, err = parseOptionalInt(field("vesting_end")); err != nil {
+			return nil, fmt.Errorf("record %d: invalid vesting_end: %s", n, err.Error())
+		}
+		if s := field("memo_required"); s != "" {
+			if record.MemoRequired, err = strconv.ParseBool(s); err != nil {
+				return nil, fmt.Errorf("record %d: invalid memo_required: %s", n, err.Error())
+			}
+		}
+		records = append(records, record)
+	}
+}
+
+func parseOptionalInt(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+func (r accountRecord) toGenesisAccount() (genaccounts.GenesisAccount, error) {
+	addr, err := sdk.AccAddressFromBech32(r.Address)
+	if err != nil {
+		return genaccounts.GenesisAccount{}, err
+	}
+	coins, err := sdk.ParseCoins(r.Coins)
+	if err != nil {
+		return genaccounts.GenesisAccount{}, err
+	}
+	vestingCoins, err := sdk.ParseCoins(r.VestingCoins)
+	if err != nil {
+		return genaccounts.GenesisAccount{}, err
+	}
+	genAcc := genaccounts.NewGenesisAccountRaw(addr, coins, vestingCoins, r.VestingStart, r.VestingEnd, "", "")
+	return genAcc, genAcc.Validate()
+}
+
+// importGenesisAccounts adds the accounts to appState, and checks the denoms are issued
+// tokens and the total coins of the genesis accounts reach --target-supply
+func importGenesisAccounts(cdc *codec.Codec, appState map[string]json.RawMessage, records []accountRecord) error {
+	var genAccs genaccounts.GenesisAccounts
+	cdc.MustUnmarshalJSON(appState[genaccounts.ModuleName], &genAccs)
+	var authxData authx.GenesisState
+	cdc.MustUnmarshalJSON(appState[authx.ModuleName], &authxData)
+	var assetData asset.GenesisState
+	cdc.MustUnmarshalJSON(appState[asset.ModuleName], &assetData)
+
+	tokens := make(map[string]bool, len(assetData.Tokens))
+	for _, token := range assetData.Tokens {
+		tokens[token.GetSymbol()] = true
+	}
+
+	for i, record := range records {
+		genAcc, err := record.toGenesisAccount()
+		if err != nil {
+			return fmt.Errorf("account #%d %s: %s", i+1, record.Address, err.Error())
+		}
+		if genAccs.Contains(genAcc.Address) {
+			return fmt.Errorf("account #%d: cannot add account at existing address %s", i+1, genAcc.Address)
+		}
+		for _, coin := range genAcc.Coins {
+			if !tokens[coin.Denom] {
+				return fmt.Errorf("account #%d %s: %s is not an issued token", i+1, record.Address, coin.Denom)
+			}
+		}
+		genAccs = append(genAccs, genAcc)
+		if record.MemoRequired {
+			setMemoRequired(&authxData, genAcc.Address)
+		}
+	}
+
+	if s := viper.GetString(flagTargetSupply); s != "" {
+		targetSupply, err := sdk.ParseCoins(s)
+		if err != nil {
+			return err
+		}
+		if genAccs, err = assureTargetSupply(genAccs, targetSupply, viper.GetString(flagRemainderAddr)); err != nil {
+			return err
+		}
+	}
+
+	appState[genaccounts.ModuleName] = cdc.MustMarshalJSON(genaccounts.GenesisState(genAccs))
+	appState[authx.ModuleName] = cdc.MustMarshalJSON(authxData)
+	return nil
+}
+
+// setMemoRequired sets the flag in the AccountX of addr, which is added if there is none,
+// as an AccountX may have been added for an address without a genesis account
+func setMemoRequired(authxData *authx.GenesisState, addr sdk.AccAddress) {
+	for i := range authxData.AccountXs {
+		if authxData.AccountXs[i].Address.Equals(addr) {
+			authxData.AccountXs[i].MemoRequired = true
+			return
+		}
+	}
+	authxData.AccountXs = append(authxData.AccountXs, authx.NewAccountX(addr, true, nil, nil, nil, 0))
+}
+
+// assureTargetSupply gives the coins left to reach targetSupply to remainderAddr, like
+// assureTokenDistributionInGenesis does for testnets
+func assureTargetSupply(genAccs genaccounts.GenesisAccounts, targetSupply sdk.Coins,
+	remainderAddr string) (genaccounts.GenesisAccounts, error) {
+
+	total := sdk.Coins{}
+	for _, acc := range genAccs {
+		total = total.Add(acc.Coins)
+	}
+	if total.IsEqual(targetSupply) {
+		return genAccs, nil
+	}
+	left, neg := targetSupply.SafeSub(total)
+	if neg {
+		return nil, fmt.Errorf("total coins of genesis accounts %s exceeds the target supply %s", total, targetSupply)
+	}
+	if remainderAddr == "" {
+		return nil, fmt.Errorf("total coins of genesis accounts %s is less than the target supply %s", total, targetSupply)
+	}
+	addr, err := sdk.AccAddressFromBech32(remainderAddr)
+	if err != nil {
+		return nil, err
+	}
+	if genAccs.Contains(addr) {
+		return nil, fmt.Errorf("cannot add remainder account at existing address %s", addr)
+	}
+	return append(genAccs, genaccounts.NewGenesisAccountRaw(addr, left, sdk.Coins{}, 0, 0, "", "")), nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/genaccounts"
+
+	"github.com/coinexchain/cet-sdk/modules/asset"
+	"github.com/coinexchain/cet-sdk/modules/authx"
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+	"github.com/coinexchain/dex/app"
+)
+
+func TestReadAccountRecords(t *testing.T) {
+	_, _, addr1 := testutil.KeyPubAddr()
+	_, _, addr2 := testutil.KeyPubAddr()
+
+	records, err := readAccountRecordsCSV(strings.NewReader(fmt.Sprintf(
+		"address,coins,vesting_coins,vesting_end,memo_required\n%s,100cet,50cet,1600000000,true\n%s, 200cet,,,\n",
+		addr1, addr2)))
+	require.NoError(t, err)
+	require.Equal(t, []accountRecord{
+		{Address: addr1.String(), Coins: "100cet", VestingCoins: "50cet", VestingEnd: 1600000000, MemoRequired: true},
+		{Address: addr2.String(), Coins: "200cet"},
+	}, records)
+
+	_, err = readAccountRecordsCSV(strings.NewReader("address,balance\n"))
+	require.EqualError(t, err, "unknown column: balance")
+	_, err = readAccountRecordsCSV(strings.NewReader("coins\n"))
+	require.EqualError(t, err, "missing column: address")
+	_, err = readAccountRecordsCSV(strings.NewReader("address,vesting_end\nx,abc\n"))
+	require.Error(t, err)
+	_, err = readAccountRecordsCSV(strings.NewReader("address,coins,memo_required\n\"x\ny\",1cet,\nx,1cet,abc\n"))
+	require.EqualError(t, err, `record 2: invalid memo_required: strconv.ParseBool: parsing "abc": invalid syntax`)
+
+	dir, err := ioutil.TempDir("", "accounts")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	jsonFile := filepath.Join(dir, "accounts.json")
+	bz, _ := json.Marshal(records)
+	require.NoError(t, ioutil.WriteFile(jsonFile, bz, 0644))
+	jsonRecords, err := readAccountRecords(jsonFile)
+	require.NoError(t, err)
+	require.Equal(t, records, jsonRecords)
+
+	_, err = readAccountRecords(filepath.Join(dir, "accounts.txt"))
+	require.Error(t, err)
+}
+
+func TestImportGenesisAccounts(t *testing.T) {
+	_, _, addr1 := testutil.KeyPubAddr()
+	_, _, addr2 := testutil.KeyPubAddr()
+	_, _, addr3 := testutil.KeyPubAddr()
+
+	cdc := app.MakeCodec()
+	newAppState := func() map[string]json.RawMessage {
+		state := app.NewDefaultGenesisState()
+		state.AssetData.Tokens = append(state.AssetData.Tokens, &asset.BaseToken{
+			Name:        "CoinEx Chain Native Token",
+			Symbol:      dex.CET,
+			TotalSupply: sdk.NewInt(1000),
+			SendLock:    sdk.ZeroInt(),
+			Owner:       addr3,
+			TotalBurn:   sdk.ZeroInt(),
+			TotalMint:   sdk.ZeroInt(),
+		})
+		var appState map[string]json.RawMessage
+		cdc.MustUnmarshalJSON(cdc.MustMarshalJSON(state), &appState)
+		return appState
+	}
+	records := []accountRecord{
+		{Address: addr1.String(), Coins: "100cet", VestingCoins: "50cet", VestingEnd: 1600000000, MemoRequired: true},
+		{Address: addr2.String(), Coins: "200cet", VestingCoins: "200cet", VestingStart: 1500000000, VestingEnd: 1600000000},
+	}
+	defer viper.Reset()
+
+	appState := newAppState()
+	require.NoError(t, importGenesisAccounts(cdc, appState, records))
+	var genAccs genaccounts.GenesisState
+	cdc.MustUnmarshalJSON(appState[genaccounts.ModuleName], &genAccs)
+	require.Equal(t, 2, len(genAccs))
+	require.Equal(t, int64(0), genAccs[0].StartTime)
+	require.Equal(t, dex.NewCetCoins(50), genAccs[0].OriginalVesting)
+	require.Equal(t, int64(1500000000), genAccs[1].StartTime)
+	var authxData authx.GenesisState
+	cdc.MustUnmarshalJSON(appState[authx.ModuleName], &authxData)
+	require.Equal(t, 1, len(authxData.AccountXs))
+	require.True(t, authxData.AccountXs[0].MemoRequired)
+
+	// duplicated
+	require.Error(t, importGenesisAccounts(cdc, appState, records[:1]))
+
+	// the AccountX added for an address without a genesis account
+	appState = newAppState()
+	cdc.MustUnmarshalJSON(appState[authx.ModuleName], &authxData)
+	authxData.AccountXs = append(authxData.AccountXs, authx.NewAccountX(addr1, false, nil, dex.NewCetCoins(10), nil, 0))
+	appState[authx.ModuleName] = cdc.MustMarshalJSON(authxData)
+	require.NoError(t, importGenesisAccounts(cdc, appState, records))
+	cdc.MustUnmarshalJSON(appState[authx.ModuleName], &authxData)
+	require.Equal(t, 1, len(authxData.AccountXs))
+	require.True(t, authxData.AccountXs[0].MemoRequired)
+	require.Equal(t, dex.NewCetCoins(10), authxData.AccountXs[0].FrozenCoins)
+
+	// unknown denom
+	require.Error(t, importGenesisAccounts(cdc, newAppState(),
+		[]accountRecord{{Address: addr1.String(), Coins: "100abc"}}))
+
+	// target supply
+	viper.Set(flagTargetSupply, "300cet")
+	require.NoError(t, importGenesisAccounts(cdc, newAppState(), records))
+	viper.Set(flagTargetSupply, "200cet")
+	require.Error(t, importGenesisAccounts(cdc, newAppState(), records))
+	viper.Set(flagTargetSupply, "1000cet")
+	require.Error(t, importGenesisAccounts(cdc, newAppState(), records))
+
+	viper.Set(flagRemainderAddr, addr3.String())
+	appState = newAppState()
+	require.NoError(t, importGenesisAccounts(cdc, appState, records))
+	cdc.MustUnmarshalJSON(appState[genaccounts.ModuleName], &genAccs)
+	require.Equal(t, 3, len(genAccs))
+	require.Equal(t, addr3, genAccs[2].Address)
+	require.Equal(t, dex.NewCetCoins(700), genAccs[2].Coins)
+}
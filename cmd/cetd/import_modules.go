@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/tendermint/tendermint/libs/cli"
+	tmtypes "github.com/tendermint/tendermint/types"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/server"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/genaccounts"
+	"github.com/cosmos/cosmos-sdk/x/genutil"
+	"github.com/cosmos/cosmos-sdk/x/supply"
+
+	"github.com/coinexchain/dex/app"
+)
+
+func importGenesisModulesCmd(ctx *server.Context, cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import-genesis-modules [exported-genesis-file]",
+		Short: "Overlay the state of some modules from an exported genesis file onto genesis.json",
+		Long: `Overlay the state of some modules from an exported genesis file onto genesis.json,
+which is usually a default or synthetic one made by 'cetd init' or 'cetd testnet'.
+The supply is re-derived from the genesis accounts. The state of a module may depend on
+other modules, e.g. the frozen coins of orders are kept in accountx and the accounts, so
+run 'cetd validate-genesis --deep' to check the result.
+
+Example:
+	cetd export --modules=market,asset,bancorlite --output=state.json
+	cetd import-genesis-modules state.json --modules=market,asset,bancorlite`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			config := ctx.Config
+			config.SetRoot(viper.GetString(cli.HomeFlag))
+
+			exported, err := tmtypes.GenesisDocFromFile(args[0])
+			if err != nil {
+				return err
+			}
+			var sections map[string]json.RawMessage
+			if err = cdc.UnmarshalJSON(exported.AppState, &sections); err != nil {
+				return err
+			}
+
+			genFile := config.GenesisFile()
+			appState, genDoc, err := genutil.GenesisStateFromGenFile(cdc, genFile)
+			if err != nil {
+				return err
+			}
+			modules, err := importGenesisModules(cdc, appState, sections, viper.GetStringSlice(flagModules))
+			if err != nil {
+				return err
+			}
+
+			appStateJSON, err := cdc.MarshalJSON(appState)
+			if err != nil {
+				return err
+			}
+			genDoc.AppState = appStateJSON
+			if err = genutil.ExportGenesisFile(genDoc, genFile); err != nil {
+				return err
+			}
+			fmt.Printf("Imported modules: %s\n", strings.Join(modules, ","))
+			return nil
+		},
+	}
+
+	cmd.Flags().String(cli.HomeFlag, app.DefaultNodeHome, "node's home directory")
+	cmd.Flags().StringSlice(flagModules, nil, "The modules to import, all the modules in the exported file if empty")
+	return cmd
+}
+
+// importGenesisModules replaces the sections of modules in appState with the exported ones,
+// and re-derives the supply. It returns the names of the imported modules.
+func importGenesisModules(cdc *codec.Codec, appState, exported map[string]json.RawMessage,
+	modules []string) ([]string, error) {
+
+	if len(modules) == 0 {
+		for name := range exported {
+			modules = append(modules, name)
+		}
+		sort.Strings(modules)
+	}
+	for _, name := range modules {
+		if _, ok := appState[name]; !ok {
+			return nil, fmt.Errorf("unknown module: %s", name)
+		}
+		section, ok := exported[name]
+		if !ok {
+			return nil, fmt.Errorf("module %s is not in the exported file", name)
+		}
+		appState[name] = section
+	}
+
+	var genAccs genaccounts.GenesisState
+	if err := cdc.UnmarshalJSON(appState[genaccounts.ModuleName], &genAccs); err != nil {
+		return nil, err
+	}
+	var supplyData supply.GenesisState
+	if err := cdc.UnmarshalJSON(appState[supply.ModuleName], &supplyData); err != nil {
+		return nil, err
+	}
+	total := sdk.Coins{}
+	for _, acc := range genAccs {
+		total = total.Add(acc.Coins)
+	}
+	supplyData.Supply = total
+	appState[supply.ModuleName] = cdc.MustMarshalJSON(supplyData)
+	return modules, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/genaccounts"
+	"github.com/cosmos/cosmos-sdk/x/supply"
+
+	"github.com/coinexchain/cet-sdk/modules/asset"
+	"github.com/coinexchain/cet-sdk/modules/market"
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+	"github.com/coinexchain/dex/app"
+)
+
+func TestImportGenesisModules(t *testing.T) {
+	_, _, addr1 := testutil.KeyPubAddr()
+	_, _, addr2 := testutil.KeyPubAddr()
+
+	cdc := app.MakeCodec()
+	toMap := func(state app.GenesisState) map[string]json.RawMessage {
+		var appState map[string]json.RawMessage
+		cdc.MustUnmarshalJSON(cdc.MustMarshalJSON(state), &appState)
+		return appState
+	}
+
+	exportedState := app.NewDefaultGenesisState()
+	exportedState.Accounts = genaccounts.GenesisState{
+		genaccounts.NewGenesisAccountRaw(addr1, dex.NewCetCoins(500), sdk.Coins{}, 0, 0, "", ""),
+	}
+	exportedState.Supply.Supply = dex.NewCetCoins(500)
+	exportedState.AssetData.Tokens = append(exportedState.AssetData.Tokens, &asset.BaseToken{
+		Name:        "CoinEx Chain Native Token",
+		Symbol:      dex.CET,
+		TotalSupply: sdk.NewInt(500),
+		SendLock:    sdk.ZeroInt(),
+		Owner:       addr1,
+		TotalBurn:   sdk.ZeroInt(),
+		TotalMint:   sdk.ZeroInt(),
+	})
+	exported := toMap(exportedState)
+
+	targetState := app.NewDefaultGenesisState()
+	targetState.Accounts = genaccounts.GenesisState{
+		genaccounts.NewGenesisAccountRaw(addr2, dex.NewCetCoins(100), sdk.Coins{}, 0, 0, "", ""),
+	}
+	targetState.Supply.Supply = dex.NewCetCoins(100)
+
+	// only asset, the supply is still from the target accounts
+	appState := toMap(targetState)
+	modules, err := importGenesisModules(cdc, appState, exported, []string{asset.ModuleName})
+	require.NoError(t, err)
+	require.Equal(t, []string{asset.ModuleName}, modules)
+	require.Equal(t, string(exported[asset.ModuleName]), string(appState[asset.ModuleName]))
+	var supplyData supply.GenesisState
+	cdc.MustUnmarshalJSON(appState[supply.ModuleName], &supplyData)
+	require.Equal(t, dex.NewCetCoins(100), supplyData.Supply)
+
+	// the accounts too, so the supply is re-derived
+	_, err = importGenesisModules(cdc, appState, exported, []string{genaccounts.ModuleName})
+	require.NoError(t, err)
+	cdc.MustUnmarshalJSON(appState[supply.ModuleName], &supplyData)
+	require.Equal(t, dex.NewCetCoins(500), supplyData.Supply)
+	require.Empty(t, app.FromMap(cdc, appState).CheckConsistency())
+
+	// all the modules in the exported file
+	appState = toMap(targetState)
+	delete(exported, market.ModuleName)
+	modules, err = importGenesisModules(cdc, appState, exported, nil)
+	require.NoError(t, err)
+	require.Equal(t, len(exported), len(modules))
+	require.NotContains(t, modules, market.ModuleName)
+
+	_, err = importGenesisModules(cdc, toMap(targetState), exported, []string{"unknown"})
+	require.EqualError(t, err, "unknown module: unknown")
+	_, err = importGenesisModules(cdc, toMap(targetState), exported, []string{market.ModuleName})
+	require.EqualError(t, err, "module market is not in the exported file")
+}
@@ -68,6 +68,8 @@ func createCetdCmd() *cobra.Command {
 	addInitCommands(ctx, cdc, rootCmd)
 	rootCmd.AddCommand(client.NewCompletionCmd(rootCmd, true))
 	server.AddCommands(ctx, cdc, rootCmd, newApp, exportAppStateAndTMValidators)
+	addStartFlags(rootCmd)
+	addModuleExport(ctx, cdc, rootCmd)
 
 	rootCmd.PersistentFlags().UintVar(&invCheckPeriod, flagInvCheckPeriod,
 		0, "Assert registered invariants every N blocks")
@@ -75,6 +77,22 @@ func createCetdCmd() *cobra.Command {
 	return rootCmd
 }
 
+// addStartFlags registers the flags of CetChainApp, which are read by newApp from viper
+func addStartFlags(rootCmd *cobra.Command) {
+	for _, cmd := range rootCmd.Commands() {
+		if cmd.Name() != "start" {
+			continue
+		}
+		cmd.Flags().StringSlice(app.FlagBalanceChangeAddrs, nil,
+			"Only report the balance_change of these addresses, all the changed accounts are reported if it is empty")
+		cmd.Flags().String(app.FlagLivenessWarningFractions, app.DefaultLivenessWarningFractions,
+			"The fractions of the allowed missed blocks at which a validator_liveness warning is reported, each in (0,1]")
+		cmd.Flags().Bool(app.FlagTxIndex, false, "Keep a local index of the delivered transactions in data/txindex.db")
+		cmd.Flags().Int64(app.FlagUpgradeStoreHeight, 0,
+			"The height from which the upgrade store is committed, on a chain started by a binary without it")
+	}
+}
+
 func addInitCommands(ctx *server.Context, cdc *codec.Codec, rootCmd *cobra.Command) {
 	rawBasicManager := app.ModuleBasics.BasicManager
 
@@ -86,11 +104,16 @@ func addInitCommands(ctx *server.Context, cdc *codec.Codec, rootCmd *cobra.Comma
 	rootCmd.AddCommand(genutilcli.CollectGenTxsCmd(ctx, cdc, genaccounts.AppModuleBasic{}, app.DefaultNodeHome))
 	rootCmd.AddCommand(genutilcli.GenTxCmd(ctx, cdc, rawBasicManager, staking.AppModuleBasic{},
 		genaccounts.AppModuleBasic{}, app.DefaultNodeHome, app.DefaultCLIHome))
-	rootCmd.AddCommand(genutilcli.ValidateGenesisCmd(ctx, cdc, rawBasicManager))
+	rootCmd.AddCommand(validateGenesisCmd(ctx, cdc, rawBasicManager))
 	rootCmd.AddCommand(genaccscli.AddGenesisAccountCmd(ctx, cdc, app.DefaultNodeHome, app.DefaultCLIHome))
+	rootCmd.AddCommand(importGenesisAccountsCmd(ctx, cdc))
+	rootCmd.AddCommand(importGenesisModulesCmd(ctx, cdc))
 	rootCmd.AddCommand(assetcli.AddGenesisTokenCmd(ctx, cdc, app.DefaultNodeHome, app.DefaultCLIHome))
 	rootCmd.AddCommand(testnetCmd(ctx, cdc, app.ModuleBasics, genaccounts.AppModuleBasic{}))
 	rootCmd.AddCommand(migrateCmd(cdc))
+	rootCmd.AddCommand(snapshotCmd(ctx))
+	rootCmd.AddCommand(rollbackCmd(ctx))
+	rootCmd.AddCommand(forkCmd(ctx, cdc))
 }
 
 func adjustBlockCommitSpeed(config *tmconfig.Config) {
@@ -123,14 +146,9 @@ func exportAppStateAndTMValidators(
 	logger log.Logger, db dbm.DB, traceStore io.Writer, height int64, forZeroHeight bool, jailWhiteList []string,
 ) (json.RawMessage, []tmtypes.GenesisValidator, error) {
 
-	if height != -1 {
-		gApp := app.NewCetChainApp(logger, db, traceStore, false, uint(1))
-		err := gApp.LoadHeight(height)
-		if err != nil {
-			return nil, nil, err
-		}
-		return gApp.ExportAppStateAndValidators(forZeroHeight, jailWhiteList)
+	gApp, err := loadAppForExport(logger, db, traceStore, height)
+	if err != nil {
+		return nil, nil, err
 	}
-	gApp := app.NewCetChainApp(logger, db, traceStore, true, uint(1))
 	return gApp.ExportAppStateAndValidators(forZeroHeight, jailWhiteList)
 }
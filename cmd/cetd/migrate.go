@@ -9,13 +9,8 @@ import (
 	"github.com/spf13/viper"
 	tm "github.com/tendermint/tendermint/types"
 
+	"github.com/cosmos/cosmos-sdk/client"
 	"github.com/cosmos/cosmos-sdk/codec"
-	sdk "github.com/cosmos/cosmos-sdk/types"
-
-	"github.com/coinexchain/cet-sdk/modules/asset"
-	"github.com/coinexchain/cet-sdk/modules/authx"
-	"github.com/coinexchain/cet-sdk/modules/market"
-	"github.com/coinexchain/dex/app"
 )
 
 const (
@@ -23,12 +18,14 @@ const (
 	flagListValidators = "list-validators"
 	GenesisBlockHeight = "genesis-block-height"
 	flagGenesisTime    = "genesis-time"
+	flagFromVersion    = "from-version"
+	flagToVersion      = "to-version"
 )
 
 func migrateCmd(cdc *codec.Codec) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "migrate [from]",
-		Short: "Migrate genesis.json (coinexdex -> coinexdex2)",
+		Short: "Migrate genesis.json from one version to another (e.g. coinexdex -> coinexdex2)",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			inputFile := args[0]
@@ -41,6 +38,10 @@ func migrateCmd(cdc *codec.Codec) *cobra.Command {
 	cmd.Flags().Int64(flagGenesisTime, 0, "The unix timestamp for genesis time, in seconds")
 	cmd.Flags().String(flagOutput, "", "New genesis.json file")
 	cmd.Flags().Bool(flagListValidators, false, "List validators in genesis.json file")
+	cmd.Flags().String(flagFromVersion, migrations[0].from, "The version of the input genesis.json")
+	cmd.Flags().String(flagToVersion, latestVersion(), "The version to migrate to")
+	cmd.Flags().Bool(flagDryRun, false, "Print a per-module report of the changes instead of the new genesis.json")
+	cmd.Flags().String(client.FlagChainID, "", "The chain-id of the new genesis.json, the same as --to-version if empty")
 
 	cmd.MarkFlagRequired(flagGenesisTime)
 	return cmd
@@ -60,15 +61,31 @@ func migrateGenesisFile(cdc *codec.Codec, inputFile, outputFile string) error {
 	}
 	genesisTime := viper.GetInt64(flagGenesisTime)
 
-	genState := &app.GenesisState{}
-	cdc.MustUnmarshalJSON(genDoc.AppState, genState)
-
-	upgradeGenesisState(genState)
+	toVersion := viper.GetString(flagToVersion)
+	path, err := migrationPath(viper.GetString(flagFromVersion), toVersion)
+	if err != nil {
+		return err
+	}
+	appState, err := runMigrations(cdc, genDoc.AppState, path)
+	if err != nil {
+		return err
+	}
+	if viper.GetBool(flagDryRun) {
+		report, err := newMigrationReport(cdc, path, genDoc.AppState, appState)
+		if err != nil {
+			return err
+		}
+		fmt.Print(report.String())
+		return nil
+	}
 
-	genDoc.ChainID = "coinexdex2"
+	genDoc.ChainID = viper.GetString(client.FlagChainID)
+	if genDoc.ChainID == "" {
+		genDoc.ChainID = toVersion
+	}
 	genDoc.GenesisBlockHeight = viper.GetInt64(GenesisBlockHeight)
 	genDoc.GenesisTime = time.Unix(genesisTime, 0)
-	genDoc.AppState = cdc.MustMarshalJSON(genState)
+	genDoc.AppState = appState
 	data = cdc.MustMarshalJSON(genDoc)
 
 	if outputFile == "" {
@@ -77,25 +94,3 @@ func migrateGenesisFile(cdc *codec.Codec, inputFile, outputFile string) error {
 	}
 	return ioutil.WriteFile(outputFile, data, 0644)
 }
-
-func upgradeGenesisState(genState *app.GenesisState) {
-	genState.GovData.VotingParams.VotingPeriod = app.VotingPeriod
-	genState.StakingXData.Params.MinSelfDelegation = app.MinSelfDelegation
-	genState.AuthXData.Params = authx.DefaultParams()
-	genState.AssetData.Params = asset.DefaultParams()
-	genState.MarketData.Params = market.DefaultParams()
-	for _, v := range genState.MarketData.Orders {
-		if v.FrozenFee != 0 {
-			v.FrozenCommission = v.FrozenFee
-			v.FrozenFee = 0
-		}
-	}
-	for k, v := range genState.BancorData.BancorInfoMap {
-		if v.AR == 0 {
-			v.MaxMoney = sdk.ZeroInt()
-			genState.BancorData.BancorInfoMap[k] = v
-		}
-	}
-	genState.Incentive.State.HeightAdjustment = 0
-	// TODO: more upgrades
-}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/coinexchain/dex/app"
+)
+
+const flagDryRun = "dry-run"
+
+// moduleDiff lists the fields changed by a migration in one module of app.GenesisState.
+// The indexes of slices and the keys of maps are not a part of the field paths, so the
+// changes of all the orders are counted under 'orders[].xxx'.
+type moduleDiff struct {
+	Module        string
+	ChangedFields map[string]int
+	Rewritten     map[string]rewrittenEntries
+}
+
+type rewrittenEntries struct {
+	Changed int
+	Total   int
+}
+
+type coinTotals struct {
+	AccountCoins sdk.Coins
+	Supply       sdk.Coins
+}
+
+type migrationReport struct {
+	From    string
+	To      string
+	Modules []moduleDiff
+	Before  *coinTotals // nil if the old state can not be decoded by the current code
+	After   coinTotals
+}
+
+func newMigrationReport(cdc *codec.Codec, path []migration, oldAppState, newAppState json.RawMessage) (*migrationReport, error) {
+	report := &migrationReport{From: path[0].from, To: path[len(path)-1].to}
+
+	var newState app.GenesisState
+	if err := cdc.UnmarshalJSON(newAppState, &newState); err != nil {
+		return nil, err
+	}
+	report.After = getCoinTotals(newState)
+	// both states are re-encoded, so the fields omitted in the input are not reported
+	newAppState = cdc.MustMarshalJSON(newState)
+	var oldState app.GenesisState
+	if err := cdc.UnmarshalJSON(oldAppState, &oldState); err == nil {
+		before := getCoinTotals(oldState)
+		report.Before = &before
+		oldAppState = cdc.MustMarshalJSON(oldState)
+	}
+
+	oldModules, err := decodeModules(oldAppState)
+	if err != nil {
+		return nil, err
+	}
+	newModules, err := decodeModules(newAppState)
+	if err != nil {
+		return nil, err
+	}
+
+	collections := genesisCollections()
+	for _, name := range sortedKeys(oldModules, newModules) {
+		diff := moduleDiff{
+			Module:        name,
+			ChangedFields: make(map[string]int),
+			Rewritten:     make(map[string]rewrittenEntries),
+		}
+		diffModule(oldModules[name], newModules[name], collections[name], &diff)
+		if len(diff.ChangedFields) != 0 {
+			report.Modules = append(report.Modules, diff)
+		}
+	}
+	return report, nil
+}
+
+// SupplyUnchanged is true if the totals of coins are the same before and after the migration
+func (r *migrationReport) SupplyUnchanged() bool {
+	return r.Before != nil &&
+		r.Before.AccountCoins.IsEqual(r.After.AccountCoins) &&
+		r.Before.Supply.IsEqual(r.After.Supply)
+}
+
+func (r *migrationReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Dry-run migration from %s to %s\n", r.From, r.To)
+	if len(r.Modules) == 0 {
+		sb.WriteString("No module is changed\n")
+	}
+	for _, diff := range r.Modules {
+		fmt.Fprintf(&sb, "[%s]\n", diff.Module)
+		fields := make([]string, 0, len(diff.ChangedFields))
+		for field := range diff.ChangedFields {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			fmt.Fprintf(&sb, "  changed: %s (%d)\n", field, diff.ChangedFields[field])
+		}
+		colls := make([]string, 0, len(diff.Rewritten))
+		for coll := range diff.Rewritten {
+			colls = append(colls, coll)
+		}
+		sort.Strings(colls)
+		for _, coll := range colls {
+			n := diff.Rewritten[coll]
+			fmt.Fprintf(&sb, "  rewritten: %s %d of %d\n", coll, n.Changed, n.Total)
+		}
+	}
+	if r.Before != nil {
+		fmt.Fprintf(&sb, "Account coins before: %s\n", r.Before.AccountCoins)
+	}
+	fmt.Fprintf(&sb, "Account coins after:  %s\n", r.After.AccountCoins)
+	if r.Before != nil {
+		fmt.Fprintf(&sb, "Supply before: %s\n", r.Before.Supply)
+	}
+	fmt.Fprintf(&sb, "Supply after:  %s\n", r.After.Supply)
+	if r.Before == nil {
+		sb.WriteString("Supply unchanged: unknown, the old state can not be decoded\n")
+	} else {
+		fmt.Fprintf(&sb, "Supply unchanged: %v\n", r.SupplyUnchanged())
+	}
+	return sb.String()
+}
+
+func getCoinTotals(genState app.GenesisState) coinTotals {
+	totals := coinTotals{AccountCoins: sdk.Coins{}, Supply: genState.Supply.Supply}
+	for _, acc := range genState.Accounts {
+		totals.AccountCoins = totals.AccountCoins.Add(acc.Coins)
+	}
+	return totals
+}
+
+func decodeModules(appState json.RawMessage) (map[string]interface{}, error) {
+	modules := make(map[string]interface{})
+	dec := json.NewDecoder(bytes.NewReader(appState))
+	dec.UseNumber()
+	if err := dec.Decode(&modules); err != nil {
+		return nil, err
+	}
+	return modules, nil
+}
+
+// genesisCollections finds the slice and map fields in the genesis state of each
+// module, which are reported as rewritten entries instead of one by one. An empty
+// field name means the genesis state of the module is a collection itself.
+func genesisCollections() map[string]map[string]bool {
+	res := make(map[string]map[string]bool)
+	t := reflect.TypeOf(app.GenesisState{})
+	for i := 0; i < t.NumField(); i++ {
+		module := jsonName(t.Field(i))
+		res[module] = make(map[string]bool)
+		mt := t.Field(i).Type
+		if isCollection(mt) {
+			res[module][""] = true
+			continue
+		}
+		if mt.Kind() != reflect.Struct {
+			continue
+		}
+		for j := 0; j < mt.NumField(); j++ {
+			if isCollection(mt.Field(j).Type) {
+				res[module][jsonName(mt.Field(j))] = true
+			}
+		}
+	}
+	return res
+}
+
+func isCollection(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice || t.Kind() == reflect.Map
+}
+
+func jsonName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+func diffModule(oldState, newState interface{}, collections map[string]bool, diff *moduleDiff) {
+	if collections[""] {
+		diffCollection("", oldState, newState, diff)
+		return
+	}
+	oldFields, ok1 := oldState.(map[string]interface{})
+	newFields, ok2 := newState.(map[string]interface{})
+	if !ok1 || !ok2 {
+		diffValue("", oldState, newState, diff.ChangedFields)
+		return
+	}
+	for _, field := range sortedKeys(oldFields, newFields) {
+		if collections[field] {
+			diffCollection(field, oldFields[field], newFields[field], diff)
+		} else {
+			diffValue(field, oldFields[field], newFields[field], diff.ChangedFields)
+		}
+	}
+}
+
+func diffCollection(name string, oldColl, newColl interface{}, diff *moduleDiff) {
+	oldEntries, newEntries := collectionEntries(oldColl), collectionEntries(newColl)
+	if oldEntries == nil || newEntries == nil || len(oldEntries) != len(newEntries) {
+		diffValue(name, oldColl, newColl, diff.ChangedFields)
+		return
+	}
+	changed := 0
+	for key, oldEntry := range oldEntries {
+		newEntry, ok := newEntries[key]
+		if !ok {
+			diffValue(name, oldColl, newColl, diff.ChangedFields)
+			return
+		}
+		if !reflect.DeepEqual(oldEntry, newEntry) {
+			changed++
+			diffValue(name+"[]", oldEntry, newEntry, diff.ChangedFields)
+		}
+	}
+	if changed != 0 {
+		if name == "" {
+			name = diff.Module
+		}
+		diff.Rewritten[name] = rewrittenEntries{Changed: changed, Total: len(oldEntries)}
+	}
+}
+
+// collectionEntries indexes the entries of a JSON array or object
+func collectionEntries(coll interface{}) map[string]interface{} {
+	switch v := coll.(type) {
+	case []interface{}:
+		entries := make(map[string]interface{}, len(v))
+		for i, e := range v {
+			entries[fmt.Sprint(i)] = e
+		}
+		return entries
+	case map[string]interface{}:
+		return v
+	case nil:
+		return map[string]interface{}{}
+	}
+	return nil
+}
+
+func diffValue(path string, oldVal, newVal interface{}, changes map[string]int) {
+	oldObj, ok1 := oldVal.(map[string]interface{})
+	newObj, ok2 := newVal.(map[string]interface{})
+	if ok1 && ok2 {
+		for _, k := range sortedKeys(oldObj, newObj) {
+			diffValue(joinPath(path, k), oldObj[k], newObj[k], changes)
+		}
+		return
+	}
+	oldArr, ok1 := oldVal.([]interface{})
+	newArr, ok2 := newVal.([]interface{})
+	if ok1 && ok2 && len(oldArr) == len(newArr) {
+		for i := range oldArr {
+			diffValue(path+"[]", oldArr[i], newArr[i], changes)
+		}
+		return
+	}
+	if !reflect.DeepEqual(oldVal, newVal) {
+		if path == "" {
+			path = "(all)"
+		}
+		changes[path]++
+	}
+}
+
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+func sortedKeys(a, b map[string]interface{}) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
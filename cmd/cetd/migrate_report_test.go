@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	"github.com/cosmos/cosmos-sdk/x/genaccounts"
+
+	"github.com/coinexchain/cet-sdk/modules/bancorlite"
+	"github.com/coinexchain/cet-sdk/modules/market"
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+	"github.com/coinexchain/dex/app"
+)
+
+func TestMigrationReport(t *testing.T) {
+	cdc := app.MakeCodec()
+	state := app.NewDefaultGenesisState()
+	state.AssetData.Params.Issue4CharTokenFee = 0
+	state.MarketData.Orders = append(state.MarketData.Orders,
+		&market.Order{FrozenFee: 100}, &market.Order{}, &market.Order{FrozenFee: 200})
+	state.BancorData.BancorInfoMap["x"] = bancorlite.BancorInfo{MaxMoney: sdk.NewInt(5)}
+	_, _, addr := testutil.KeyPubAddr()
+	acc := auth.BaseAccount{Address: addr, Coins: dex.NewCetCoins(100)}
+	state.Accounts = append(state.Accounts, genaccounts.NewGenesisAccount(&acc))
+	state.Supply.Supply = dex.NewCetCoins(100)
+	oldAppState := cdc.MustMarshalJSON(state)
+
+	path, err := migrationPath("coinexdex", "coinexdex2")
+	require.NoError(t, err)
+	newAppState, err := runMigrations(cdc, oldAppState, path)
+	require.NoError(t, err)
+
+	report, err := newMigrationReport(cdc, path, oldAppState, newAppState)
+	require.NoError(t, err)
+	require.Equal(t, "coinexdex", report.From)
+	require.Equal(t, "coinexdex2", report.To)
+	require.True(t, report.SupplyUnchanged())
+	require.Equal(t, "100cet", report.After.AccountCoins.String())
+
+	diffs := make(map[string]moduleDiff)
+	for _, diff := range report.Modules {
+		diffs[diff.Module] = diff
+	}
+	require.NotContains(t, diffs, "accounts")
+	require.NotContains(t, diffs, "supply")
+	require.Equal(t, 1, diffs["asset"].ChangedFields["params.issue_4char_token_fee"])
+	require.Equal(t, 2, diffs["market"].ChangedFields["orders[].frozen_commission"])
+	require.Equal(t, rewrittenEntries{Changed: 2, Total: 3}, diffs["market"].Rewritten["orders"])
+	require.Equal(t, rewrittenEntries{Changed: 1, Total: 1}, diffs["bancorlite"].Rewritten["bancor_info_map"])
+	require.Contains(t, report.String(), "rewritten: orders 2 of 3")
+	require.Contains(t, report.String(), "Supply unchanged: true")
+
+	report, err = newMigrationReport(cdc, path, oldAppState, oldAppState)
+	require.NoError(t, err)
+	require.Equal(t, 0, len(report.Modules))
+	require.Contains(t, report.String(), "No module is changed")
+}
@@ -24,7 +24,7 @@ func TestMigrate(t *testing.T) {
 	state.BancorData.BancorInfoMap["x"] = bancorlite.BancorInfo{}
 
 	// upgrade to DEX2
-	upgradeGenesisState(&state)
+	upgradeDex1ToDex2(&state)
 
 	// check state
 	require.Equal(t, time.Hour*24*7, state.GovData.VotingParams.VotingPeriod)
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/coinexchain/cet-sdk/modules/asset"
+	"github.com/coinexchain/cet-sdk/modules/authx"
+	"github.com/coinexchain/cet-sdk/modules/market"
+	"github.com/coinexchain/dex/app"
+)
+
+// migration upgrades the app state of genesis.json from one version to the next one.
+// A migration works either on app.GenesisState, or on the raw JSON of the modules when
+// the old state can not be decoded by the current code.
+type migration struct {
+	from       string
+	to         string
+	migrate    func(genState *app.GenesisState)
+	migrateRaw func(appState map[string]json.RawMessage) error
+}
+
+// migrations are registered in order, the version names are the chain-ids
+var migrations = []migration{
+	{from: "coinexdex", to: "coinexdex2", migrate: upgradeDex1ToDex2},
+}
+
+func latestVersion() string {
+	return migrations[len(migrations)-1].to
+}
+
+// migrationPath returns the chain of migrations which upgrades fromVer to toVer
+func migrationPath(fromVer, toVer string) ([]migration, error) {
+	var path []migration
+	for curr := fromVer; curr != toVer; {
+		m, found := findMigration(curr)
+		if !found {
+			return nil, fmt.Errorf("no migration from %s to %s", curr, toVer)
+		}
+		path = append(path, m)
+		curr = m.to
+	}
+	if len(path) == 0 {
+		return nil, fmt.Errorf("nothing to migrate from %s to %s", fromVer, toVer)
+	}
+	return path, nil
+}
+
+func findMigration(fromVer string) (migration, bool) {
+	for _, m := range migrations {
+		if m.from == fromVer {
+			return m, true
+		}
+	}
+	return migration{}, false
+}
+
+// runMigrations applies the migrations one by one on the app state of genesis.json
+func runMigrations(cdc *codec.Codec, appState json.RawMessage, path []migration) (json.RawMessage, error) {
+	for _, m := range path {
+		var err error
+		if m.migrateRaw != nil {
+			appState, err = migrateRawAppState(appState, m.migrateRaw)
+		} else {
+			appState, err = migrateAppState(cdc, appState, m.migrate)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to migrate from %s to %s: %s", m.from, m.to, err.Error())
+		}
+	}
+	return appState, nil
+}
+
+func migrateAppState(cdc *codec.Codec, appState json.RawMessage,
+	migrate func(genState *app.GenesisState)) (json.RawMessage, error) {
+
+	genState := &app.GenesisState{}
+	if err := cdc.UnmarshalJSON(appState, genState); err != nil {
+		return nil, err
+	}
+	migrate(genState)
+	return cdc.MarshalJSON(genState)
+}
+
+func migrateRawAppState(appState json.RawMessage,
+	migrate func(appState map[string]json.RawMessage) error) (json.RawMessage, error) {
+
+	modules := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(appState, &modules); err != nil {
+		return nil, err
+	}
+	if err := migrate(modules); err != nil {
+		return nil, err
+	}
+	return json.Marshal(modules)
+}
+
+func upgradeDex1ToDex2(genState *app.GenesisState) {
+	genState.GovData.VotingParams.VotingPeriod = app.VotingPeriod
+	genState.StakingXData.Params.MinSelfDelegation = app.MinSelfDelegation
+	genState.AuthXData.Params = authx.DefaultParams()
+	genState.AssetData.Params = asset.DefaultParams()
+	genState.MarketData.Params = market.DefaultParams()
+	for _, v := range genState.MarketData.Orders {
+		if v.FrozenFee != 0 {
+			v.FrozenCommission = v.FrozenFee
+			v.FrozenFee = 0
+		}
+	}
+	for k, v := range genState.BancorData.BancorInfoMap {
+		if v.AR == 0 {
+			v.MaxMoney = sdk.ZeroInt()
+			genState.BancorData.BancorInfoMap[k] = v
+		}
+	}
+	genState.Incentive.State.HeightAdjustment = 0
+}
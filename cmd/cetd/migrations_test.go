@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	tm "github.com/tendermint/tendermint/types"
+
+	"github.com/cosmos/cosmos-sdk/client"
+
+	"github.com/coinexchain/cet-sdk/modules/market"
+	"github.com/coinexchain/dex/app"
+)
+
+func withMigrations(ms []migration, fn func()) {
+	saved := migrations
+	migrations = ms
+	defer func() { migrations = saved }()
+	fn()
+}
+
+func TestMigrationPath(t *testing.T) {
+	noop := func(genState *app.GenesisState) {}
+	withMigrations([]migration{
+		{from: "v1", to: "v2", migrate: noop},
+		{from: "v2", to: "v3", migrate: noop},
+		{from: "v3", to: "v4", migrate: noop},
+	}, func() {
+		require.Equal(t, "v4", latestVersion())
+
+		path, err := migrationPath("v1", "v4")
+		require.NoError(t, err)
+		require.Equal(t, 3, len(path))
+		require.Equal(t, "v3", path[2].from)
+
+		path, err = migrationPath("v2", "v3")
+		require.NoError(t, err)
+		require.Equal(t, 1, len(path))
+
+		_, err = migrationPath("v2", "v2")
+		require.Error(t, err)
+		_, err = migrationPath("v3", "v2")
+		require.Error(t, err)
+		_, err = migrationPath("v0", "v2")
+		require.Error(t, err)
+	})
+}
+
+func TestRunMigrations(t *testing.T) {
+	cdc := app.MakeCodec()
+	state := app.NewDefaultGenesisState()
+	appState := cdc.MustMarshalJSON(state)
+
+	path := []migration{
+		{from: "v1", to: "v2", migrateRaw: func(appState map[string]json.RawMessage) error {
+			delete(appState, "comment")
+			return nil
+		}},
+		{from: "v2", to: "v3", migrate: func(genState *app.GenesisState) {
+			genState.MarketData.Orders = append(genState.MarketData.Orders, &market.Order{FrozenFee: 100})
+		}},
+	}
+	newAppState, err := runMigrations(cdc, appState, path)
+	require.NoError(t, err)
+
+	var newState app.GenesisState
+	cdc.MustUnmarshalJSON(newAppState, &newState)
+	require.Equal(t, 1, len(newState.MarketData.Orders))
+	require.EqualValues(t, 100, newState.MarketData.Orders[0].FrozenFee)
+
+	path = []migration{{from: "v1", to: "v2", migrateRaw: func(appState map[string]json.RawMessage) error {
+		return errors.New("bad state")
+	}}}
+	_, err = runMigrations(cdc, appState, path)
+	require.EqualError(t, err, "failed to migrate from v1 to v2: bad state")
+}
+
+func TestMigrateGenesisFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migrate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cdc := app.MakeCodec()
+	state := app.NewDefaultGenesisState()
+	state.MarketData.Orders = append(state.MarketData.Orders, &market.Order{FrozenFee: 100})
+	genDoc := tm.GenesisDoc{ChainID: "coinexdex", AppState: cdc.MustMarshalJSON(state)}
+	inputFile := filepath.Join(dir, "genesis.json")
+	outputFile := filepath.Join(dir, "genesis2.json")
+	require.NoError(t, ioutil.WriteFile(inputFile, cdc.MustMarshalJSON(genDoc), 0644))
+
+	viper.Set(flagGenesisTime, 1577836800)
+	viper.Set(flagFromVersion, "coinexdex")
+	viper.Set(flagToVersion, "coinexdex2")
+	viper.Set(client.FlagChainID, "")
+	defer viper.Reset()
+	require.NoError(t, migrateGenesisFile(cdc, inputFile, outputFile))
+
+	data, err := ioutil.ReadFile(outputFile)
+	require.NoError(t, err)
+	var newGenDoc tm.GenesisDoc
+	cdc.MustUnmarshalJSON(data, &newGenDoc)
+	require.Equal(t, "coinexdex2", newGenDoc.ChainID)
+	var newState app.GenesisState
+	cdc.MustUnmarshalJSON(newGenDoc.AppState, &newState)
+	require.EqualValues(t, 100, newState.MarketData.Orders[0].FrozenCommission)
+
+	viper.Set(client.FlagChainID, "coinexdex-test")
+	require.NoError(t, migrateGenesisFile(cdc, inputFile, outputFile))
+	data, err = ioutil.ReadFile(outputFile)
+	require.NoError(t, err)
+	cdc.MustUnmarshalJSON(data, &newGenDoc)
+	require.Equal(t, "coinexdex-test", newGenDoc.ChainID)
+
+	viper.Set(flagFromVersion, "coinexdex2")
+	require.Error(t, migrateGenesisFile(cdc, inputFile, outputFile))
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/tendermint/tendermint/libs/cli"
+	sm "github.com/tendermint/tendermint/state"
+	tmstore "github.com/tendermint/tendermint/store"
+	tmtypes "github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/server"
+
+	"github.com/coinexchain/dex/app"
+	"github.com/coinexchain/dex/app/snapshot"
+	"github.com/coinexchain/dex/app/txindex"
+)
+
+func rollbackCmd(ctx *server.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Roll the application state and the Tendermint state back to a previous height",
+		Long: `Make --height the latest committed height of the application, as if the later blocks
+were never executed, and roll the Tendermint state back to it too. The block of height+1
+is kept, so Tendermint executes it again when the node starts, the later blocks are deleted
+and synced again. The local tx index, if any, is rolled back as well. The node must be
+stopped, and the state of --height must not have been pruned.
+Use --dry-run to check and print what would be deleted without writing anything.
+
+The validator does not sign again at the heights it has signed, as the priv_validator_state.json
+is kept.
+
+Example:
+	cetd rollback --height=3000000 --dry-run`,
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			config := ctx.Config
+			config.SetRoot(viper.GetString(cli.HomeFlag))
+			height := viper.GetInt64(flagHeight)
+			if height <= 0 {
+				return fmt.Errorf("--%s is required", flagHeight)
+			}
+			dryRun := viper.GetBool(flagDryRun)
+
+			db, err := dbm.NewGoLevelDB("application", filepath.Join(config.RootDir, "data"))
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			stateDB, err := dbm.NewGoLevelDB("state", config.DBDir())
+			if err != nil {
+				return err
+			}
+			defer stateDB.Close()
+			blockDB, err := dbm.NewGoLevelDB("blockstore", config.DBDir())
+			if err != nil {
+				return err
+			}
+			defer blockDB.Close()
+
+			// the checks of both sides are done before anything is written
+			r, err := snapshot.RollbackStores(db, height, true)
+			if err != nil {
+				return err
+			}
+			gApp, err := loadAppForExport(ctx.Logger, db, nil, height)
+			if err != nil {
+				return err
+			}
+			gApp.Close()
+			tr, err := rollbackTendermint(stateDB, blockDB, height, r.AppHash, true)
+			if err != nil {
+				return err
+			}
+			txs, hasTxIndex, err := rollbackTxIndex(config.RootDir, height, true)
+			if err != nil {
+				return err
+			}
+			if !dryRun {
+				// the app is rolled back first, Tendermint can replay the blocks to it if interrupted
+				if r, err = snapshot.RollbackStores(db, height, false); err != nil {
+					return err
+				}
+				if tr, err = rollbackTendermint(stateDB, blockDB, height, r.AppHash, false); err != nil {
+					return err
+				}
+				if txs, _, err = rollbackTxIndex(config.RootDir, height, false); err != nil {
+					return err
+				}
+				gApp := app.NewCetChainApp(ctx.Logger, db, nil, true, uint(1))
+				gApp.Close()
+				if commitID := gApp.LastCommitID(); commitID.Version != height || !bytes.Equal(commitID.Hash, r.AppHash) {
+					return fmt.Errorf("rolled back state is at height %d with app hash %X, expected %d and %s",
+						commitID.Version, commitID.Hash, height, r.AppHash)
+				}
+			}
+
+			fmt.Printf("Application: height %d -> %d, app hash: %s\n", r.LatestHeight, r.Height, r.AppHash)
+			for _, s := range r.Stores {
+				fmt.Printf("  %s: %d versions, %d nodes, %d orphans deleted\n", s.Name, s.Roots, s.Nodes, s.Orphans)
+			}
+			if hasTxIndex {
+				fmt.Printf("Tx index: %d transactions deleted\n", txs)
+			}
+			fmt.Printf("Tendermint: state height %d -> %d, block store height %d -> %d\n",
+				tr.stateHeight, height, tr.blockStoreHeight, tr.newBlockStoreHeight)
+			if dryRun {
+				fmt.Println("Dry run, nothing is written")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String(cli.HomeFlag, app.DefaultNodeHome, "node's home directory")
+	cmd.Flags().Int64(flagHeight, 0, "The height to roll back to")
+	cmd.Flags().Bool(flagDryRun, false, "Check and print what would be deleted, without writing anything")
+	return cmd
+}
+
+// rollbackTxIndex deletes the transactions after height from the local tx index, if it exists.
+// The db is closed at once, as the app opens it when the index is enabled.
+func rollbackTxIndex(home string, height int64, dryRun bool) (txs int, found bool, err error) {
+	db, err := app.OpenTxIndexDB(home)
+	if err != nil || db == nil {
+		return 0, false, err
+	}
+	defer db.Close()
+	txs, err = txindex.Rollback(db, height, dryRun)
+	return txs, true, err
+}
+
+type tmRollback struct {
+	stateHeight         int64
+	blockStoreHeight    int64
+	newBlockStoreHeight int64
+}
+
+// the same as the codec of the tendermint state package
+var tmStateCdc = codec.New()
+
+func init() {
+	codec.RegisterCrypto(tmStateCdc)
+}
+
+// rollbackTendermint rebuilds the state of height from the block store and the saved validators
+// and consensus params, and deletes the blocks after height+1. appHash must be the one of the
+// application at height, which is checked against the header of height+1.
+func rollbackTendermint(stateDB, blockDB dbm.DB, height int64, appHash []byte, dryRun bool) (*tmRollback, error) {
+	state := sm.LoadState(stateDB)
+	if state.IsEmpty() {
+		return nil, fmt.Errorf("no Tendermint state is saved")
+	}
+	blockStore := tmstore.NewBlockStore(blockDB)
+	tr := &tmRollback{stateHeight: state.LastBlockHeight, blockStoreHeight: blockStore.Height()}
+	if state.LastBlockHeight < height {
+		return nil, fmt.Errorf("Tendermint state is at height %d, lower than %d", state.LastBlockHeight, height)
+	}
+	tr.newBlockStoreHeight = height + 1
+	if tr.blockStoreHeight < tr.newBlockStoreHeight {
+		return nil, fmt.Errorf("block store is at height %d, block %d is needed", tr.blockStoreHeight, height+1)
+	}
+	meta, next := blockStore.LoadBlockMeta(height), blockStore.LoadBlockMeta(height+1)
+	if meta == nil || next == nil {
+		return nil, fmt.Errorf("blocks %d and %d are needed", height, height+1)
+	}
+	if !bytes.Equal(next.Header.AppHash, appHash) {
+		return nil, fmt.Errorf("app hash %X of height %d is not the one in block %d: %s",
+			appHash, height, height+1, next.Header.AppHash)
+	}
+
+	var newState sm.State
+	if state.LastBlockHeight > height {
+		var err error
+		if newState, err = loadTendermintState(stateDB, state, meta, next); err != nil {
+			return nil, err
+		}
+	}
+	if dryRun {
+		return tr, nil
+	}
+
+	batch := blockDB.NewBatch()
+	defer batch.Close()
+	for h := tr.newBlockStoreHeight + 1; h <= tr.blockStoreHeight; h++ {
+		if m := blockStore.LoadBlockMeta(h); m != nil {
+			for i := 0; i < m.BlockID.PartsHeader.Total; i++ {
+				batch.Delete([]byte(fmt.Sprintf("P:%d:%d", h, i)))
+			}
+		}
+		batch.Delete([]byte(fmt.Sprintf("H:%d", h)))
+		batch.Delete([]byte(fmt.Sprintf("C:%d", h-1)))
+		batch.Delete([]byte(fmt.Sprintf("SC:%d", h)))
+	}
+	batch.WriteSync()
+	tmstore.BlockStoreStateJSON{Height: tr.newBlockStoreHeight}.Save(blockDB)
+
+	if state.LastBlockHeight > height {
+		batch := stateDB.NewBatch()
+		defer batch.Close()
+		for h := height + 1; h <= state.LastBlockHeight+2; h++ {
+			batch.Delete([]byte(fmt.Sprintf("abciResponsesKey:%d", h)))
+			if h > height+1 {
+				batch.Delete([]byte(fmt.Sprintf("consensusParamsKey:%d", h)))
+			}
+			if h > height+2 {
+				batch.Delete([]byte(fmt.Sprintf("validatorsKey:%d", h)))
+			}
+		}
+		batch.WriteSync()
+		sm.SaveState(stateDB, newState)
+	}
+	return tr, nil
+}
+
+// loadTendermintState returns the state after the block of meta is committed,
+// next is the meta of the block after it
+func loadTendermintState(stateDB dbm.DB, state sm.State, meta, next *tmtypes.BlockMeta) (sm.State, error) {
+	height := meta.Header.Height
+	s := sm.State{
+		Version: sm.Version{Consensus: next.Header.Version, Software: state.Version.Software},
+		ChainID: state.ChainID,
+
+		LastBlockHeight:  height,
+		LastBlockTotalTx: meta.Header.TotalTxs,
+		LastBlockID:      meta.BlockID,
+		LastBlockTime:    meta.Header.Time,
+
+		LastResultsHash: next.Header.LastResultsHash,
+		AppHash:         next.Header.AppHash,
+	}
+
+	var err error
+	if s.LastValidators, err = sm.LoadValidators(stateDB, height); err != nil {
+		return s, err
+	}
+	if s.Validators, err = sm.LoadValidators(stateDB, height+1); err != nil {
+		return s, err
+	}
+	if s.NextValidators, err = sm.LoadValidators(stateDB, height+2); err != nil {
+		return s, err
+	}
+	var valInfo sm.ValidatorsInfo
+	bz := stateDB.Get([]byte(fmt.Sprintf("validatorsKey:%d", height+2)))
+	if err = tmStateCdc.UnmarshalBinaryBare(bz, &valInfo); err != nil {
+		return s, err
+	}
+	s.LastHeightValidatorsChanged = valInfo.LastHeightChanged
+
+	if s.ConsensusParams, err = sm.LoadConsensusParams(stateDB, height+1); err != nil {
+		return s, err
+	}
+	var paramsInfo sm.ConsensusParamsInfo
+	bz = stateDB.Get([]byte(fmt.Sprintf("consensusParamsKey:%d", height+1)))
+	if err = tmStateCdc.UnmarshalBinaryBare(bz, &paramsInfo); err != nil {
+		return s, err
+	}
+	s.LastHeightConsensusParamsChanged = paramsInfo.LastHeightChanged
+
+	if !bytes.Equal(s.Validators.Hash(), next.Header.ValidatorsHash) ||
+		!bytes.Equal(s.NextValidators.Hash(), next.Header.NextValidatorsHash) ||
+		!bytes.Equal(s.ConsensusParams.Hash(), next.Header.ConsensusHash) {
+		return s, fmt.Errorf("saved validators or consensus params do not match block %d", height+1)
+	}
+	return s, nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+	sm "github.com/tendermint/tendermint/state"
+	tmstore "github.com/tendermint/tendermint/store"
+	tmtypes "github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/coinexchain/dex/app/txindex"
+)
+
+// saveBlocks saves the blocks and the states of some heights as Tendermint does,
+// a validator is added by the block of height 2, the commits are signed by the validators
+func saveBlocks(t *testing.T, stateDB, blockDB dbm.DB, heights int64) []sm.State {
+	privVals := make(map[string]tmtypes.PrivValidator)
+	newPubKey := func() crypto.PubKey {
+		pv := tmtypes.NewMockPV()
+		privVals[pv.GetPubKey().Address().String()] = pv
+		return pv.GetPubKey()
+	}
+	pubKey := newPubKey()
+	genDoc := &tmtypes.GenesisDoc{
+		ChainID:     "c1",
+		GenesisTime: time.Unix(1500000000, 0).UTC(),
+		Validators:  []tmtypes.GenesisValidator{{Address: pubKey.Address(), PubKey: pubKey, Power: 10}},
+	}
+	require.Nil(t, genDoc.ValidateAndComplete())
+	state, err := sm.MakeGenesisState(genDoc)
+	require.Nil(t, err)
+	sm.SaveState(stateDB, state)
+
+	blockStore := tmstore.NewBlockStore(blockDB)
+	states := []sm.State{state}
+	lastCommit := tmtypes.NewCommit(tmtypes.BlockID{}, nil)
+	for h := int64(1); h <= heights; h++ {
+		block, parts := state.MakeBlock(h, nil, lastCommit, nil, state.Validators.GetProposer().Address)
+		blockID := tmtypes.BlockID{Hash: block.Hash(), PartsHeader: parts.Header()}
+		lastCommit = signCommit(t, state.Validators, privVals, blockID, h)
+		blockStore.SaveBlock(block, parts, lastCommit)
+
+		next := state.Copy()
+		next.LastBlockHeight = h
+		next.LastBlockID = blockID
+		next.LastBlockTime = block.Time
+		nValSet := state.NextValidators.Copy()
+		if h == 2 {
+			require.Nil(t, nValSet.UpdateWithChangeSet([]*tmtypes.Validator{
+				tmtypes.NewValidator(newPubKey(), 5)}))
+			next.LastHeightValidatorsChanged = h + 2
+		}
+		nValSet.IncrementProposerPriority(1)
+		next.LastValidators = state.Validators.Copy()
+		next.Validators = state.NextValidators.Copy()
+		next.NextValidators = nValSet
+		next.LastResultsHash = tmhash.Sum([]byte{byte(h), 1})
+		next.AppHash = tmhash.Sum([]byte{byte(h)})
+		sm.SaveState(stateDB, next)
+		states = append(states, next)
+		state = next
+	}
+	return states
+}
+
+func signCommit(t *testing.T, vals *tmtypes.ValidatorSet, privVals map[string]tmtypes.PrivValidator,
+	blockID tmtypes.BlockID, height int64) *tmtypes.Commit {
+
+	signers := make([]tmtypes.PrivValidator, vals.Size())
+	for i, val := range vals.Validators {
+		signers[i] = privVals[val.Address.String()]
+	}
+	voteSet := tmtypes.NewVoteSet("c1", height, 0, tmtypes.PrecommitType, vals)
+	commit, err := tmtypes.MakeCommit(blockID, height, 0, voteSet, signers)
+	require.Nil(t, err)
+	return commit
+}
+
+func TestRollbackTendermint(t *testing.T) {
+	stateDB, blockDB := dbm.NewMemDB(), dbm.NewMemDB()
+	states := saveBlocks(t, stateDB, blockDB, 6)
+	blockStore := tmstore.NewBlockStore(blockDB)
+
+	_, err := rollbackTendermint(stateDB, blockDB, 3, []byte("hash"), false)
+	require.NotNil(t, err)
+	_, err = rollbackTendermint(stateDB, blockDB, 6, states[6].AppHash, false)
+	require.NotNil(t, err) // no block 7
+
+	tr, err := rollbackTendermint(stateDB, blockDB, 3, states[3].AppHash, true)
+	require.Nil(t, err)
+	require.Equal(t, tmRollback{stateHeight: 6, blockStoreHeight: 6, newBlockStoreHeight: 4}, *tr)
+	require.True(t, sm.LoadState(stateDB).Equals(states[6]))
+	require.Equal(t, int64(6), blockStore.Height())
+
+	_, err = rollbackTendermint(stateDB, blockDB, 3, states[3].AppHash, false)
+	require.Nil(t, err)
+	require.True(t, sm.LoadState(stateDB).Equals(states[3]))
+	blockStore = tmstore.NewBlockStore(blockDB)
+	require.Equal(t, int64(4), blockStore.Height())
+	require.NotNil(t, blockStore.LoadBlock(4))
+	require.NotNil(t, blockStore.LoadSeenCommit(4))
+	require.Nil(t, blockStore.LoadBlock(5))
+	require.Nil(t, blockStore.LoadBlockCommit(4))
+	require.Nil(t, blockStore.LoadSeenCommit(5))
+
+	// once more after being interrupted
+	tr, err = rollbackTendermint(stateDB, blockDB, 3, states[3].AppHash, false)
+	require.Nil(t, err)
+	require.Equal(t, tmRollback{stateHeight: 3, blockStoreHeight: 4, newBlockStoreHeight: 4}, *tr)
+	require.True(t, sm.LoadState(stateDB).Equals(states[3]))
+}
+
+func TestRollbackTxIndex(t *testing.T) {
+	home, err := ioutil.TempDir("", "rollback")
+	require.Nil(t, err)
+	defer os.RemoveAll(home)
+
+	_, found, err := rollbackTxIndex(home, 1, false)
+	require.Nil(t, err)
+	require.False(t, found)
+
+	db, err := dbm.NewGoLevelDB("txindex", filepath.Join(home, "data"))
+	require.Nil(t, err)
+	ix := txindex.NewIndexer(db)
+	addr := sdk.AccAddress(tmhash.SumTruncated([]byte("addr")))
+	for h := int64(1); h <= 3; h++ {
+		ix.IndexTx(txindex.IndexedTx{Hash: []byte{byte(h)}, Height: h, Signers: []sdk.AccAddress{addr}})
+		ix.Commit()
+	}
+	ix.Close()
+
+	txs, found, err := rollbackTxIndex(home, 1, true)
+	require.Nil(t, err)
+	require.True(t, found)
+	require.Equal(t, 2, txs)
+	txs, _, err = rollbackTxIndex(home, 1, false)
+	require.Nil(t, err)
+	require.Equal(t, 2, txs)
+	txs, _, err = rollbackTxIndex(home, 1, false)
+	require.Nil(t, err)
+	require.Equal(t, 0, txs)
+}
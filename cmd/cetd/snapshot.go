@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/tendermint/tendermint/libs/cli"
+	sm "github.com/tendermint/tendermint/state"
+	tmstore "github.com/tendermint/tendermint/store"
+	tmtypes "github.com/tendermint/tendermint/types"
+	"github.com/tendermint/tendermint/version"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/cosmos/cosmos-sdk/server"
+
+	"github.com/coinexchain/dex/app"
+	"github.com/coinexchain/dex/app/snapshot"
+)
+
+const (
+	flagChunkSize         = "chunk-size"
+	flagTrustedAppHash    = "trusted-app-hash"
+	flagTrustedHeaderHash = "trusted-header-hash"
+)
+
+func snapshotCmd(ctx *server.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Create or restore a snapshot of the application state",
+	}
+	cmd.AddCommand(
+		createSnapshotCmd(ctx),
+		restoreSnapshotCmd(ctx),
+	)
+	return cmd
+}
+
+func createSnapshotCmd(ctx *server.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "create [output-dir]",
+		Short: "Write the application state at a height to chunked, checksummed files",
+		Long: `Write the IAVL stores at a height to chunked, checksummed files, with a manifest which
+has the header of the next block to verify the app hash. The block, the commits and the
+validators of Tendermint at the height are written too, to start a fresh node from the snapshot.
+The node must be stopped, and the block of height+1 must have been committed.
+
+Example:
+	cetd snapshot create ./snapshot --height=3000000`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			config := ctx.Config
+			config.SetRoot(viper.GetString(cli.HomeFlag))
+			height := viper.GetInt64(flagHeight)
+			if height <= 0 {
+				return fmt.Errorf("--%s is required", flagHeight)
+			}
+
+			blockDB, err := dbm.NewGoLevelDB("blockstore", config.DBDir())
+			if err != nil {
+				return err
+			}
+			defer blockDB.Close()
+			meta := tmstore.NewBlockStore(blockDB).LoadBlockMeta(height + 1)
+			if meta == nil {
+				return fmt.Errorf("block %d is not committed, which has the app hash of height %d", height+1, height)
+			}
+			stateDB, err := dbm.NewGoLevelDB("state", config.DBDir())
+			if err != nil {
+				return err
+			}
+			defer stateDB.Close()
+			t, err := loadSnapshotTendermint(stateDB, blockDB, height)
+			if err != nil {
+				return err
+			}
+
+			db, err := dbm.NewGoLevelDB("application", filepath.Join(config.RootDir, "data"))
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			gApp, err := loadAppForExport(ctx.Logger, db, nil, height)
+			if err != nil {
+				return err
+			}
+			m, err := gApp.CreateSnapshot(db, height, &meta.Header, args[0], viper.GetInt64(flagChunkSize))
+			if err != nil {
+				return err
+			}
+			if err = t.Verify(m); err != nil {
+				return err
+			}
+			if err = snapshot.WriteTendermint(args[0], t); err != nil {
+				return err
+			}
+			fmt.Printf("Created snapshot of height %d in %d chunks, app hash: %s\n", m.Height, len(m.Chunks), m.AppHash)
+			return nil
+		},
+	}
+
+	cmd.Flags().String(cli.HomeFlag, app.DefaultNodeHome, "node's home directory")
+	cmd.Flags().Int64(flagHeight, 0, "The height of the snapshot")
+	cmd.Flags().Int64(flagChunkSize, snapshot.DefaultChunkSize, "The size limit of a chunk file in bytes")
+	return cmd
+}
+
+func restoreSnapshotCmd(ctx *server.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore [snapshot-dir]",
+		Short: "Rebuild the application state from a snapshot",
+		Long: `Rebuild the IAVL stores from a snapshot into an empty application db.
+
+On a fresh node, whose block store and Tendermint state are empty, the block and the state of
+Tendermint at the height of the snapshot are restored too, after they are verified against the
+header of height+1 in the manifest, so the node starts from the next block and syncs the later
+blocks from its peers. The genesis file of the chain must be in the config directory.
+On a node which has the blocks up to the height of the snapshot, e.g. a node whose application
+db is lost or corrupted, only the application state is restored, and Tendermint replays the
+blocks after the height of the snapshot when the node starts.
+
+The manifest can not be trusted by itself, so its header is verified against --trusted-header-hash,
+the hash of the block of height+1, or its app hash against --trusted-app-hash, which should be
+got from a trusted node or a light client. One of them is required, unless the block of height+1
+is in the local block store. --trusted-header-hash is preferred on a fresh node, as it verifies
+the validators of Tendermint as well.
+
+Example:
+	cetd snapshot restore ./snapshot --trusted-header-hash=9A1C...`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			config := ctx.Config
+			config.SetRoot(viper.GetString(cli.HomeFlag))
+
+			m, err := snapshot.ReadManifest(args[0])
+			if err != nil {
+				return err
+			}
+			if err = m.Verify(); err != nil {
+				return err
+			}
+			blockDB, err := dbm.NewGoLevelDB("blockstore", config.DBDir())
+			if err != nil {
+				return err
+			}
+			defer blockDB.Close()
+			stateDB, err := dbm.NewGoLevelDB("state", config.DBDir())
+			if err != nil {
+				return err
+			}
+			defer stateDB.Close()
+			fresh, err := checkSnapshotBlocks(stateDB, blockDB, m,
+				viper.GetString(flagTrustedAppHash), viper.GetString(flagTrustedHeaderHash))
+			if err != nil {
+				return err
+			}
+			var t *snapshot.Tendermint
+			if fresh {
+				if t, err = snapshot.ReadTendermint(args[0]); err != nil {
+					return err
+				}
+				if err = t.Verify(m); err != nil {
+					return err
+				}
+			}
+
+			db, err := dbm.NewGoLevelDB("application", filepath.Join(config.RootDir, "data"))
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			if m, err = snapshot.Restore(db, args[0]); err != nil {
+				return err
+			}
+			gApp := app.NewCetChainApp(ctx.Logger, db, nil, true, uint(1))
+			if commitID := gApp.LastCommitID(); commitID.Version != m.Height || !bytes.Equal(commitID.Hash, m.AppHash) {
+				return fmt.Errorf("restored state is at height %d with app hash %X, expected %d and %s",
+					commitID.Version, commitID.Hash, m.Height, m.AppHash)
+			}
+			if fresh {
+				// written after the application, a node with the blocks and no application
+				// state would be started from the genesis
+				restoreTendermint(stateDB, blockDB, m, t)
+				fmt.Printf("Restored the block and the Tendermint state of height %d\n", m.Height)
+			}
+			fmt.Printf("Restored height %d, app hash: %s\n", m.Height, m.AppHash)
+			return nil
+		},
+	}
+
+	cmd.Flags().String(cli.HomeFlag, app.DefaultNodeHome, "node's home directory")
+	cmd.Flags().String(flagTrustedAppHash, "", "The app hash in hex from a trusted node")
+	cmd.Flags().String(flagTrustedHeaderHash, "", "The hash in hex of the block of height+1 from a trusted node or a light client")
+	return cmd
+}
+
+// checkSnapshotBlocks verifies the snapshot against the trusted hashes, or the block of height+1 in
+// the local block store. fresh is true if the block store and the state db are empty, the block
+// and the state of the snapshot height are restored then, otherwise the block store must have
+// the blocks up to the snapshot height.
+func checkSnapshotBlocks(stateDB, blockDB dbm.DB, m *snapshot.Manifest,
+	trustedAppHash, trustedHeaderHash string) (fresh bool, err error) {
+
+	blockStore := tmstore.NewBlockStore(blockDB)
+	fresh = blockStore.Height() == 0 && sm.LoadState(stateDB).IsEmpty()
+	if !fresh && blockStore.Height() < m.Height {
+		return false, fmt.Errorf("the block store is at height %d, below the snapshot height %d; "+
+			"sync the blocks first, or remove the Tendermint data to restore it from the snapshot",
+			blockStore.Height(), m.Height)
+	}
+
+	verified := false
+	if meta := blockStore.LoadBlockMeta(m.Height + 1); meta != nil {
+		if !bytes.Equal(meta.Header.AppHash, m.AppHash) {
+			return false, fmt.Errorf("app hash of the snapshot %s does not match %s in block %d",
+				m.AppHash, meta.Header.AppHash, m.Height+1)
+		}
+		verified = true
+	}
+	if trustedAppHash != "" {
+		trusted, err := hex.DecodeString(trustedAppHash)
+		if err != nil {
+			return false, err
+		}
+		if !bytes.Equal(trusted, m.AppHash) {
+			return false, fmt.Errorf("app hash of the snapshot %s is not the trusted one", m.AppHash)
+		}
+		verified = true
+	}
+	if trustedHeaderHash != "" {
+		trusted, err := hex.DecodeString(trustedHeaderHash)
+		if err != nil {
+			return false, err
+		}
+		if m.Header == nil || !bytes.Equal(trusted, m.Header.Hash()) {
+			return false, fmt.Errorf("header of block %d in the snapshot is not the trusted one", m.Height+1)
+		}
+		verified = true
+	}
+	if !verified {
+		return false, fmt.Errorf("block %d is not in the local block store, --%s or --%s is required",
+			m.Height+1, flagTrustedHeaderHash, flagTrustedAppHash)
+	}
+	return fresh, nil
+}
+
+// loadSnapshotTendermint loads the block and the state of Tendermint at height, the block of
+// height+1 must have been committed
+func loadSnapshotTendermint(stateDB, blockDB dbm.DB, height int64) (*snapshot.Tendermint, error) {
+	blockStore := tmstore.NewBlockStore(blockDB)
+	t := &snapshot.Tendermint{
+		Block:      blockStore.LoadBlock(height),
+		Commit:     blockStore.LoadBlockCommit(height),
+		NextCommit: blockStore.LoadBlockCommit(height + 1),
+	}
+	if t.NextCommit == nil {
+		// height+1 is the latest block
+		t.NextCommit = blockStore.LoadSeenCommit(height + 1)
+	}
+	if t.Block == nil || t.Commit == nil || t.NextCommit == nil {
+		return nil, fmt.Errorf("blocks %d and %d are needed", height, height+1)
+	}
+	for h := height; h <= height+2; h++ {
+		vals, err := sm.LoadValidators(stateDB, h)
+		if err != nil {
+			return nil, err
+		}
+		t.Validators = append(t.Validators, vals)
+	}
+	var err error
+	t.ConsensusParams, err = sm.LoadConsensusParams(stateDB, height+1)
+	return t, err
+}
+
+// restoreTendermint saves the block of the snapshot height into the empty block store, and the
+// state after it into the empty state db, as if the node had committed the block. The validators
+// and the consensus params needed by the next blocks are saved as changed at their heights.
+func restoreTendermint(stateDB, blockDB dbm.DB, m *snapshot.Manifest, t *snapshot.Tendermint) {
+	height, header := m.Height, m.Header
+	tmstore.BlockStoreStateJSON{Height: height - 1}.Save(blockDB)
+	tmstore.NewBlockStore(blockDB).SaveBlock(t.Block, t.Block.MakePartSet(tmtypes.BlockPartSizeBytes), t.Commit)
+
+	for i, vals := range t.Validators[:2] {
+		h := height + int64(i)
+		valInfo := &sm.ValidatorsInfo{ValidatorSet: vals, LastHeightChanged: h}
+		stateDB.Set([]byte(fmt.Sprintf("validatorsKey:%d", h)), valInfo.Bytes())
+	}
+	sm.SaveState(stateDB, sm.State{
+		Version: sm.Version{Consensus: header.Version, Software: version.TMCoreSemVer},
+		ChainID: header.ChainID,
+
+		LastBlockHeight:  height,
+		LastBlockTotalTx: t.Block.TotalTxs,
+		LastBlockID:      header.LastBlockID,
+		LastBlockTime:    t.Block.Time,
+
+		NextValidators:              t.Validators[2],
+		Validators:                  t.Validators[1],
+		LastValidators:              t.Validators[0],
+		LastHeightValidatorsChanged: height + 2,
+
+		ConsensusParams:                  t.ConsensusParams,
+		LastHeightConsensusParamsChanged: height + 1,
+
+		LastResultsHash: header.LastResultsHash,
+		AppHash:         header.AppHash,
+	})
+}
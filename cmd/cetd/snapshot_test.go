@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/libs/log"
+	sm "github.com/tendermint/tendermint/state"
+	tmstore "github.com/tendermint/tendermint/store"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/coinexchain/dex/app/snapshot"
+)
+
+func TestCheckSnapshotBlocks(t *testing.T) {
+	stateDB, blockDB := dbm.NewMemDB(), dbm.NewMemDB()
+	states := saveBlocks(t, stateDB, blockDB, 4)
+	check := func(m *snapshot.Manifest, trustedAppHash string) error {
+		fresh, err := checkSnapshotBlocks(stateDB, blockDB, m, trustedAppHash, "")
+		require.False(t, fresh)
+		return err
+	}
+
+	// the app hash of height 3 is in block 4
+	m := &snapshot.Manifest{Height: 3, AppHash: states[3].AppHash}
+	require.Nil(t, check(m, ""))
+	require.NotNil(t, check(m, hex.EncodeToString(states[2].AppHash)))
+	m.AppHash = states[2].AppHash
+	require.NotNil(t, check(m, hex.EncodeToString(states[2].AppHash)))
+
+	// block 5 is not in the block store
+	m = &snapshot.Manifest{Height: 4, AppHash: states[4].AppHash}
+	require.NotNil(t, check(m, ""))
+	require.Nil(t, check(m, hex.EncodeToString(states[4].AppHash)))
+
+	// the blocks up to the snapshot are missing
+	m = &snapshot.Manifest{Height: 5, AppHash: states[4].AppHash}
+	require.NotNil(t, check(m, hex.EncodeToString(states[4].AppHash)))
+}
+
+func TestRestoreTendermint(t *testing.T) {
+	stateDB, blockDB := dbm.NewMemDB(), dbm.NewMemDB()
+	states := saveBlocks(t, stateDB, blockDB, 5)
+	blockStore := tmstore.NewBlockStore(blockDB)
+
+	// the validators are changed at height 4
+	tm, err := loadSnapshotTendermint(stateDB, blockDB, 3)
+	require.Nil(t, err)
+	m := &snapshot.Manifest{Height: 3, AppHash: states[3].AppHash, Header: &blockStore.LoadBlockMeta(4).Header}
+	require.Nil(t, tm.Verify(m))
+	_, err = loadSnapshotTendermint(stateDB, blockDB, 5)
+	require.NotNil(t, err)
+
+	dir, err := ioutil.TempDir("", "snapshot")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	require.Nil(t, snapshot.WriteTendermint(dir, tm))
+	tm, err = snapshot.ReadTendermint(dir)
+	require.Nil(t, err)
+	require.Nil(t, tm.Verify(m))
+
+	// the tampered ones
+	vals := tm.Validators
+	tm.Validators = append(vals[:2:2], vals[0])
+	require.NotNil(t, tm.Verify(m))
+	tm.Validators = vals
+	commit := tm.NextCommit
+	tm.NextCommit = tm.Commit
+	require.NotNil(t, tm.Verify(m))
+	tm.NextCommit = commit
+	require.Nil(t, tm.Verify(m))
+
+	// a fresh node
+	freshStateDB, freshBlockDB := dbm.NewMemDB(), dbm.NewMemDB()
+	_, err = checkSnapshotBlocks(freshStateDB, freshBlockDB, m, "", "")
+	require.NotNil(t, err)
+	_, err = checkSnapshotBlocks(freshStateDB, freshBlockDB, m, "", hex.EncodeToString(states[3].AppHash))
+	require.NotNil(t, err)
+	fresh, err := checkSnapshotBlocks(freshStateDB, freshBlockDB, m, "", hex.EncodeToString(m.Header.Hash()))
+	require.Nil(t, err)
+	require.True(t, fresh)
+
+	restoreTendermint(freshStateDB, freshBlockDB, m, tm)
+	fresh, err = checkSnapshotBlocks(freshStateDB, freshBlockDB, m, hex.EncodeToString(states[3].AppHash), "")
+	require.Nil(t, err)
+	require.False(t, fresh)
+
+	freshBlockStore := tmstore.NewBlockStore(freshBlockDB)
+	require.Equal(t, int64(3), freshBlockStore.Height())
+	require.Equal(t, blockStore.LoadBlock(3).Hash(), freshBlockStore.LoadBlock(3).Hash())
+	require.Equal(t, blockStore.LoadBlockCommit(3).Hash(), freshBlockStore.LoadSeenCommit(3).Hash())
+	state := sm.LoadState(freshStateDB)
+	expected := states[3].Copy()
+	expected.LastHeightValidatorsChanged = 5
+	expected.LastHeightConsensusParamsChanged = 4
+	require.True(t, state.Equals(expected))
+	for h := int64(3); h <= 5; h++ {
+		expectedVals, err := sm.LoadValidators(stateDB, h)
+		require.Nil(t, err)
+		vals, err := sm.LoadValidators(freshStateDB, h)
+		require.Nil(t, err)
+		require.Equal(t, expectedVals.Hash(), vals.Hash())
+	}
+
+	// the node goes on from block 4
+	blockExec := sm.NewBlockExecutor(freshStateDB, log.NewNopLogger(), nil, nil, sm.MockEvidencePool{})
+	require.Nil(t, blockExec.ValidateBlock(state, blockStore.LoadBlock(4)))
+}
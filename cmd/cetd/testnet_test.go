@@ -1,14 +1,18 @@
 package main
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 	cfg "github.com/tendermint/tendermint/config"
 	"github.com/tendermint/tendermint/libs/cli"
+	tmtypes "github.com/tendermint/tendermint/types"
 
 	"github.com/cosmos/cosmos-sdk/server"
+	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/cosmos/cosmos-sdk/x/genaccounts"
 
 	"github.com/coinexchain/dex/app"
@@ -55,3 +59,138 @@ func TestInitGenFiles(t *testing.T) {
 	//err := initGenFiles(cdc, "chain", []app.GenesisAccount{acc}, []string{"./genesis.json"}, 1)
 	//require.Equal(t, nil, err)
 }
+
+const testTopology = `
+validators:
+  - moniker: alice
+    stake: 2000000000000
+    commission_rate: "0.05"
+  - moniker: bob
+    ip: 10.0.0.8
+full_nodes:
+  - moniker: seed
+accounts:
+  - name: faucet
+    coins: 100000000000000cet
+  - name: carol
+    address: coinex1paehyhx9sxdfwc3rjf85vwn6kjnmzjemtedpnl
+    coins: 500000000cet
+tokens:
+  - symbol: abc
+    name: ABC Token
+    total_supply: 2100000000000000
+    owner: faucet
+    mintable: true
+    identity: 552A83BA62F9B1F8
+markets:
+  - stock: abc
+    money: cet
+    price_precision: 8
+params:
+  market:
+    create_market_fee: 100000000
+  stakingx:
+    min_mandatory_commission_rate: "0.01"
+`
+
+func TestInitTestnetWithTopology(t *testing.T) {
+	testHome := "./testhome"
+	testDataDir := "./testnetdata"
+	defer os.RemoveAll(testHome)
+	defer os.RemoveAll(testDataDir)
+	require.Nil(t, os.MkdirAll(testDataDir, 0755))
+	topologyFile := filepath.Join(testDataDir, "topology.yaml")
+	require.Nil(t, ioutil.WriteFile(topologyFile, []byte(testTopology), 0644))
+
+	os.Args = []string{"cetd", "testnet", "--topology", topologyFile, "-o", testDataDir,
+		"--starting-ip-address", "10.0.0.1"}
+	executor := cli.PrepareBaseCmd(createCetdCmd(), "GA", testHome)
+	require.NoError(t, executor.Execute())
+
+	genFile := filepath.Join(testDataDir, "node0", "cetd", "config", "genesis.json")
+	require.Nil(t, validateGenesisDeep(app.MakeCodec(), genFile))
+	genDoc, err := tmtypes.GenesisDocFromFile(genFile)
+	require.Nil(t, err)
+	var genState app.GenesisState
+	require.Nil(t, app.MakeCodec().UnmarshalJSON(genDoc.AppState, &genState))
+
+	require.Equal(t, 2, len(genState.GenUtil.GenTxs))
+	require.Equal(t, 2, len(genState.AssetData.Tokens))
+	abc := genState.AssetData.Tokens[1]
+	require.Equal(t, "abc", abc.GetSymbol())
+	require.True(t, abc.GetMintable())
+	require.Equal(t, 1, len(genState.MarketData.MarketInfos))
+	require.Equal(t, "abc/cet", genState.MarketData.MarketInfos[0].GetSymbol())
+	require.Equal(t, int64(100000000), genState.MarketData.Params.CreateMarketFee)
+	require.Equal(t, sdk.NewDecWithPrec(1, 2), genState.StakingXData.Params.MinMandatoryCommissionRate)
+	var faucet, carol genaccounts.GenesisAccount
+	for _, acc := range genState.Accounts {
+		if acc.Coins.AmountOf("abc").IsPositive() {
+			faucet = acc
+		}
+		if acc.Address.String() == "coinex1paehyhx9sxdfwc3rjf85vwn6kjnmzjemtedpnl" {
+			carol = acc
+		}
+	}
+	require.Equal(t, abc.GetOwner(), faucet.Address)
+	require.Equal(t, sdk.NewInt(100000000000000), faucet.Coins.AmountOf("cet"))
+	require.Equal(t, sdk.NewInt(500000000), carol.Coins.AmountOf("cet"))
+
+	// the full node has the same genesis file, and the validators as its peers
+	bz1, err := ioutil.ReadFile(genFile)
+	require.Nil(t, err)
+	bz2, err := ioutil.ReadFile(filepath.Join(testDataDir, "node2", "cetd", "config", "genesis.json"))
+	require.Nil(t, err)
+	require.Equal(t, bz1, bz2)
+	config, err := ioutil.ReadFile(filepath.Join(testDataDir, "node2", "cetd", "config", "config.toml"))
+	require.Nil(t, err)
+	require.Contains(t, string(config), `moniker = "seed"`)
+	require.Contains(t, string(config), "@10.0.0.1:26656,")
+	require.Contains(t, string(config), "@10.0.0.8:26656")
+	_, err = os.Stat(filepath.Join(testDataDir, "accounts", "faucet_seed.json"))
+	require.Nil(t, err)
+}
+
+func TestInitTestnetSingleHost(t *testing.T) {
+	testHome := "./testhome"
+	testDataDir := "./testnetdata"
+	defer os.RemoveAll(testHome)
+	defer os.RemoveAll(testDataDir)
+	require.Nil(t, os.MkdirAll(testDataDir, 0755))
+	topologyFile := filepath.Join(testDataDir, "topology.yaml")
+	require.Nil(t, ioutil.WriteFile(topologyFile, []byte(testTopology), 0644))
+
+	os.Args = []string{"cetd", "testnet", "--topology", topologyFile, "-o", testDataDir, "--single-host",
+		"--chain-id", "c1"}
+	executor := cli.PrepareBaseCmd(createCetdCmd(), "GA", testHome)
+	require.NoError(t, executor.Execute())
+
+	genFile := filepath.Join(testDataDir, "node0", "cetd", "config", "genesis.json")
+	require.Nil(t, validateGenesisDeep(app.MakeCodec(), genFile))
+
+	// the ip in the topology is ignored
+	config, err := ioutil.ReadFile(filepath.Join(testDataDir, "node1", "cetd", "config", "config.toml"))
+	require.Nil(t, err)
+	require.Contains(t, string(config), `laddr = "tcp://127.0.0.1:26666"`)
+	require.Contains(t, string(config), `laddr = "tcp://127.0.0.1:26667"`)
+	require.Contains(t, string(config), `proxy_app = "tcp://127.0.0.1:26668"`)
+	require.Contains(t, string(config), "addr_book_strict = false")
+	require.Contains(t, string(config), "allow_duplicate_ip = true")
+	require.Contains(t, string(config), "@127.0.0.1:26656")
+	require.NotContains(t, string(config), "10.0.0.8")
+
+	config, err = ioutil.ReadFile(filepath.Join(testDataDir, "node2", "cetd", "config", "config.toml"))
+	require.Nil(t, err)
+	require.Contains(t, string(config), `laddr = "tcp://127.0.0.1:26677"`)
+	require.Contains(t, string(config), `external_address = "127.0.0.1:26676"`)
+	require.Contains(t, string(config), "@127.0.0.1:26656,")
+	require.Contains(t, string(config), "@127.0.0.1:26666")
+
+	start, err := ioutil.ReadFile(filepath.Join(testDataDir, "start.sh"))
+	require.Nil(t, err)
+	require.Contains(t, string(start), "start --home node2/cetd --minimum-gas-prices 20.0cet")
+	require.Contains(t, string(start), "--chain-id c1 --node tcp://127.0.0.1:26677 --laddr tcp://127.0.0.1:1319")
+	stop, err := ioutil.ReadFile(filepath.Join(testDataDir, "stop.sh"))
+	require.Nil(t, err)
+	require.Contains(t, string(stop), "kill $(cat node2/cetd.pid)")
+}
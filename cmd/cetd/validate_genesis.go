@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	tmtypes "github.com/tendermint/tendermint/types"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/server"
+	"github.com/cosmos/cosmos-sdk/types/module"
+	genutilcli "github.com/cosmos/cosmos-sdk/x/genutil/client/cli"
+
+	"github.com/coinexchain/dex/app"
+)
+
+const flagDeep = "deep"
+
+// validateGenesisCmd adds the cross-module checks of app.GenesisState to genutil's validate-genesis
+func validateGenesisCmd(ctx *server.Context, cdc *codec.Codec, mbm module.BasicManager) *cobra.Command {
+	cmd := genutilcli.ValidateGenesisCmd(ctx, cdc, mbm)
+	validateModules := cmd.RunE
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := validateModules(cmd, args); err != nil {
+			return err
+		}
+		if !viper.GetBool(flagDeep) {
+			return nil
+		}
+
+		genesis := ctx.Config.GenesisFile()
+		if len(args) != 0 {
+			genesis = args[0]
+		}
+		return validateGenesisDeep(cdc, genesis)
+	}
+	cmd.Flags().Bool(flagDeep, false, "Also check the consistency across modules, e.g. supply, staking pools and frozen coins")
+	return cmd
+}
+
+func validateGenesisDeep(cdc *codec.Codec, genesis string) error {
+	genDoc, err := tmtypes.GenesisDocFromFile(genesis)
+	if err != nil {
+		return fmt.Errorf("error loading genesis doc from %s: %s", genesis, err.Error())
+	}
+	var genState app.GenesisState
+	if err = cdc.UnmarshalJSON(genDoc.AppState, &genState); err != nil {
+		return fmt.Errorf("error unmarshaling genesis doc %s: %s", genesis, err.Error())
+	}
+
+	errs := genState.CheckConsistency()
+	for _, err := range errs {
+		fmt.Fprintln(os.Stderr, err.Error())
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("genesis file %s failed %d cross-module checks", genesis, len(errs))
+	}
+	fmt.Printf("File at %s passed the cross-module checks\n", genesis)
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	tm "github.com/tendermint/tendermint/types"
+
+	"github.com/coinexchain/cet-sdk/modules/market"
+	"github.com/coinexchain/dex/app"
+)
+
+func TestValidateGenesisDeep(t *testing.T) {
+	dir, err := ioutil.TempDir("", "genesis")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cdc := app.MakeCodec()
+	genesis := filepath.Join(dir, "genesis.json")
+	state := app.NewDefaultGenesisState()
+	genDoc := tm.GenesisDoc{ChainID: "coinexdex-test", AppState: cdc.MustMarshalJSON(state)}
+	require.NoError(t, genDoc.SaveAs(genesis))
+	require.NoError(t, validateGenesisDeep(cdc, genesis))
+
+	state.MarketData.Orders = append(state.MarketData.Orders, &market.Order{TradingPair: "abc/cet"})
+	genDoc.AppState = cdc.MustMarshalJSON(state)
+	require.NoError(t, genDoc.SaveAs(genesis))
+	require.Error(t, validateGenesisDeep(cdc, genesis))
+
+	require.Error(t, validateGenesisDeep(cdc, filepath.Join(dir, "none.json")))
+}